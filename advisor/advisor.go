@@ -0,0 +1,449 @@
+package advisor
+
+import (
+	"LGM/dockerfile"
+	"LGM/filetree"
+	"LGM/i18n"
+	"LGM/image"
+	"archive/tar"
+	"github.com/dustin/go-humanize"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kind 是建议的类型
+type Kind string
+
+const (
+	// MergeRun 建议合并两个RUN：后一个删除或替换了前一个添加的文件
+	MergeRun Kind = "merge-run"
+	// NoInstallRecommends 建议apt-get install不安装推荐的软件包
+	NoInstallRecommends Kind = "no-install-recommends"
+	// CleanCache 建议在同一个RUN中清理包管理器的缓存
+	CleanCache Kind = "clean-cache"
+	// CopyChown 建议使用COPY --chown/--chmod，而不是在之后的RUN中修改所有者或权限（文件会保存两次）
+	CopyChown Kind = "copy-chown"
+	// CopyExclude 建议不复制之后的RUN删除的文件
+	CopyExclude Kind = "copy-exclude"
+	// CopyOrder 建议将复制源代码的COPY移到安装依赖之后，以便修改源代码时可以使用缓存
+	CopyOrder Kind = "copy-order"
+)
+
+// Suggestion 是对Dockerfile的一条具体修改建议
+type Suggestion struct {
+	Kind Kind
+	// Layers 是建议涉及的层（RefTrees中的索引）
+	Layers []int
+	// Message 是建议（已翻译），Rewrite 是修改后的指令（Dockerfile语法，可能有多行）
+	Message string
+	Rewrite string
+	// Saving 是估计节省的字节数，PerBuild 指示节省的是每次修改源代码后重新构建和传输的字节（而不是镜像的大小）
+	Saving   uint64
+	PerBuild bool
+}
+
+// step 是镜像的一层以及产生它的指令（没有对应的Dockerfile指令时来自历史记录）
+type step struct {
+	layer int
+	cmd   string
+	args  string
+	// script 是RUN执行的命令（包括heredoc的内容），用于识别包管理器等
+	script      string
+	instruction *dockerfile.Instruction
+	tree        *filetree.FileTree
+}
+
+// cache 是包管理器在镜像中留下的缓存
+type cache struct {
+	dirs    []string
+	command *regexp.Regexp
+	message string
+	rewrite func(args string) string
+}
+
+// recommendsShare 是估计的推荐软件包占apt-get install安装的内容的比例（无法从镜像中得知哪些软件包是推荐安装的）
+const recommendsShare = 0.3
+
+const whiteoutPrefix = ".wh."
+
+var (
+	aptInstallPattern = regexp.MustCompile(`\bapt(-get)?\s+(-\S+\s+)*install\b`)
+	chownPattern      = regexp.MustCompile(`\bchown\s+(-\S+\s+)*(\S+)`)
+	chmodPattern      = regexp.MustCompile(`\bchmod\s+(-\S+\s+)*(\S+)`)
+
+	caches = []cache{
+		{
+			dirs:    []string{"/var/lib/apt/lists", "/var/cache/apt"},
+			command: regexp.MustCompile(`\bapt(-get)?\b`),
+			message: "Clean the apt lists and package cache in the same RUN at %s",
+			rewrite: appendCommand("apt-get clean && rm -rf /var/lib/apt/lists/*"),
+		},
+		{
+			dirs:    []string{"/var/cache/apk"},
+			command: regexp.MustCompile(`\bapk\s+add\b`),
+			message: "Use apk add --no-cache at %s",
+			rewrite: replaceCommand(regexp.MustCompile(`\bapk\s+add\b`), "apk add --no-cache"),
+		},
+		{
+			dirs:    []string{"/var/cache/yum"},
+			command: regexp.MustCompile(`\byum\b`),
+			message: "Clean the yum cache in the same RUN at %s",
+			rewrite: appendCommand("yum clean all"),
+		},
+		{
+			dirs:    []string{"/var/cache/dnf"},
+			command: regexp.MustCompile(`\bdnf\b`),
+			message: "Clean the dnf cache in the same RUN at %s",
+			rewrite: appendCommand("dnf clean all"),
+		},
+		{
+			dirs:    []string{"/root/.cache/pip"},
+			command: regexp.MustCompile(`\bpip3?\s+install\b`),
+			message: "Use pip install --no-cache-dir at %s",
+			rewrite: replaceCommand(regexp.MustCompile(`\bpip3?\s+install\b`), "$0 --no-cache-dir"),
+		},
+		{
+			dirs:    []string{"/root/.npm"},
+			command: regexp.MustCompile(`\bnpm\b`),
+			message: "Clean the npm cache in the same RUN at %s",
+			rewrite: appendCommand("npm cache clean --force"),
+		},
+	}
+
+	// installers 是安装依赖的命令以及它们需要的清单文件
+	installers = []struct {
+		command   *regexp.Regexp
+		manifests string
+	}{
+		{regexp.MustCompile(`\bnpm\s+(ci|install)\b`), "package.json package-lock.json"},
+		{regexp.MustCompile(`\byarn(\s+install\b|\s*$|\s*&&|\s*;)`), "package.json yarn.lock"},
+		{regexp.MustCompile(`\bpnpm\s+install\b`), "package.json pnpm-lock.yaml"},
+		{regexp.MustCompile(`\bpip3?\s+install\b.*\s-r\s`), "requirements.txt"},
+		{regexp.MustCompile(`\bpoetry\s+install\b`), "pyproject.toml poetry.lock"},
+		{regexp.MustCompile(`\bgo\s+mod\s+download\b`), "go.mod go.sum"},
+		{regexp.MustCompile(`\bbundle\s+install\b`), "Gemfile Gemfile.lock"},
+		{regexp.MustCompile(`\bcomposer\s+install\b`), "composer.json composer.lock"},
+		{regexp.MustCompile(`\bmvn\b.*\bdependency:`), "pom.xml"},
+		{regexp.MustCompile(`\bcargo\s+fetch\b`), "Cargo.toml Cargo.lock"},
+	}
+)
+
+// Advise 根据分析结果（以及与历史记录对齐的Dockerfile，见image.MapDockerfile）给出修改Dockerfile的建议，
+// 只返回估计节省至少minSaving字节的建议，按节省的字节数从大到小排列。
+func Advise(analysis *image.AnalysisResult, minSaving uint64) []Suggestion {
+	steps := newSteps(analysis)
+	waste := newWaste(analysis.RefTrees)
+
+	var suggestions []Suggestion
+	add := func(suggestion Suggestion) {
+		if suggestion.Saving >= minSaving && suggestion.Saving > 0 {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	for _, current := range steps {
+		if current.cmd != "RUN" {
+			continue
+		}
+		var cacheBytes uint64
+		for _, cache := range caches {
+			size := filesSize(current.tree, cache.dirs)
+			cacheBytes += size
+			if size == 0 || !cache.command.MatchString(current.script) {
+				continue
+			}
+			add(Suggestion{
+				Kind:    CleanCache,
+				Layers:  []int{current.layer},
+				Message: i18n.T(cache.message, current.where()),
+				Rewrite: "RUN " + cache.rewrite(current.command()),
+				Saving:  size,
+			})
+		}
+		// the caches are not part of the installed packages
+		var installed uint64
+		if cacheBytes < current.tree.FileSize {
+			installed = current.tree.FileSize - cacheBytes
+		}
+		if aptInstallPattern.MatchString(current.script) && !strings.Contains(current.script, "--no-install-recommends") && !strings.Contains(current.script, "Install-Recommends") {
+			add(Suggestion{
+				Kind:    NoInstallRecommends,
+				Layers:  []int{current.layer},
+				Message: i18n.T("Add --no-install-recommends to apt-get install at %s", current.where()),
+				Rewrite: "RUN " + aptInstallPattern.ReplaceAllString(current.command(), "$0 --no-install-recommends"),
+				Saving:  uint64(float64(installed) * recommendsShare),
+			})
+		}
+	}
+
+	for _, pair := range waste.pairs() {
+		lower, upper := steps[pair.lower], steps[pair.upper]
+		switch {
+		case lower.cmd == "RUN" && upper.cmd == "RUN":
+			add(Suggestion{
+				Kind:    MergeRun,
+				Layers:  []int{lower.layer, upper.layer},
+				Message: i18n.T("Merge the RUN at %s and %s: %s deletes or replaces %s added by %s", lower.where(), upper.where(), upper.where(), humanize.Bytes(pair.removed+pair.replaced), lower.where()),
+				Rewrite: "RUN " + lower.command() + " \\\n    && " + upper.command(),
+				Saving:  pair.removed + pair.replaced,
+			})
+		case (lower.cmd == "COPY" || lower.cmd == "ADD") && upper.cmd == "RUN":
+			if flags := ownerFlags(upper.script); pair.replaced > 0 && flags != "" {
+				add(Suggestion{
+					Kind:    CopyChown,
+					Layers:  []int{lower.layer, upper.layer},
+					Message: i18n.T("Use %s --chown/--chmod at %s instead of changing the files at %s, which stores them twice", lower.cmd, lower.where(), upper.where()),
+					Rewrite: lower.cmd + " " + flags + " " + strings.TrimSpace(lower.args),
+					Saving:  pair.replaced,
+				})
+			}
+			if pair.removed > 0 {
+				add(Suggestion{
+					Kind:    CopyExclude,
+					Layers:  []int{lower.layer, upper.layer},
+					Message: i18n.T("Do not copy the files deleted at %s in the %s at %s (add them to .dockerignore or use RUN --mount=type=bind)", upper.where(), lower.cmd, lower.where()),
+					Rewrite: "# .dockerignore\n" + strings.Join(lower.contextPaths(pair.removedPaths), "\n"),
+					Saving:  pair.removed,
+				})
+			}
+		}
+	}
+
+	for idx, current := range steps {
+		if current.cmd != "COPY" || !current.copiesContext() {
+			continue
+		}
+	install:
+		for _, later := range steps[idx+1:] {
+			if later.cmd != "RUN" {
+				continue
+			}
+			for _, installer := range installers {
+				if !installer.command.MatchString(later.script) {
+					continue
+				}
+				add(Suggestion{
+					Kind:     CopyOrder,
+					Layers:   []int{current.layer, later.layer},
+					Message:  i18n.T("Move the COPY at %s below the dependency install at %s for better caching, copy only %s before it", current.where(), later.where(), installer.manifests),
+					Rewrite:  "COPY " + installer.manifests + " " + current.destination() + "\nRUN " + later.command() + "\nCOPY " + strings.TrimSpace(current.args),
+					Saving:   later.tree.FileSize,
+					PerBuild: true,
+				})
+				break install
+			}
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Saving > suggestions[j].Saving
+	})
+	return suggestions
+}
+
+// ForLayer 返回涉及给定层（RefTrees中的索引）的建议。
+func ForLayer(suggestions []Suggestion, layerIdx int) []Suggestion {
+	var result []Suggestion
+	for _, suggestion := range suggestions {
+		for _, idx := range suggestion.Layers {
+			if idx == layerIdx {
+				result = append(result, suggestion)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// newSteps 返回镜像的每一层及其指令：有对应的Dockerfile指令时使用该指令，否则使用历史记录中的命令。
+func newSteps(analysis *image.AnalysisResult) []step {
+	steps := make([]step, len(analysis.RefTrees))
+	for idx, tree := range analysis.RefTrees {
+		steps[idx] = step{layer: idx, tree: tree}
+	}
+	for _, entry := range analysis.History {
+		if entry.EmptyLayer || entry.Layer < 0 || entry.Layer >= len(steps) {
+			continue
+		}
+		current := &steps[entry.Layer]
+		if instruction := entry.Instruction; instruction != nil {
+			current.instruction = instruction
+			current.cmd = instruction.Cmd
+			current.args = strings.TrimSpace(strings.Join(instruction.Flags, " ") + " " + instruction.Args)
+			if instruction.Cmd == "RUN" {
+				current.args = instruction.Args
+			}
+			current.script = instruction.Args
+			for _, heredoc := range instruction.Heredocs {
+				current.script += "\n" + heredoc.Content
+			}
+			continue
+		}
+		current.cmd, current.args = image.ParseCreatedBy(entry.CreatedBy)
+		current.script = current.args
+	}
+	return steps
+}
+
+// where 返回指令的位置：Dockerfile中的行，没有对应的指令时为层。
+func (current step) where() string {
+	if current.instruction != nil {
+		return i18n.T("line %d", current.instruction.Line)
+	}
+	return i18n.T("layer %d", current.layer)
+}
+
+// command 返回RUN执行的命令，用于合并或修改RUN：heredoc的各行用 "&&" 连接，exec形式的参数用空格连接。
+func (current step) command() string {
+	instruction := current.instruction
+	if instruction == nil {
+		return strings.TrimSpace(current.args)
+	}
+	if instruction.JSON != nil {
+		return strings.Join(instruction.JSON, " ")
+	}
+	if len(instruction.Heredocs) == 0 || !strings.HasPrefix(strings.TrimSpace(instruction.Args), "<<") {
+		return strings.TrimSpace(instruction.Args)
+	}
+	var lines []string
+	for _, heredoc := range instruction.Heredocs {
+		for _, line := range strings.Split(heredoc.Content, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return strings.Join(lines, " \\\n    && ")
+}
+
+// copiesContext 返回COPY是否复制整个构建上下文（例如 "COPY . ."）。
+func (current step) copiesContext() bool {
+	if current.instruction != nil {
+		if _, ok := current.instruction.Flag("from"); ok {
+			return false
+		}
+	}
+	sources := current.sources()
+	for _, source := range sources {
+		if source == "." || source == "./" || source == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// sources 和 destination 返回COPY或ADD的源路径和目标路径（目标路径总是以 "/" 结尾）。
+func (current step) sources() []string {
+	fields := copyFields(current.args)
+	if len(fields) < 2 {
+		return nil
+	}
+	return fields[:len(fields)-1]
+}
+
+func (current step) destination() string {
+	fields := copyFields(current.args)
+	if len(fields) == 0 {
+		return "./"
+	}
+	// several sources need a directory as the destination
+	destination := fields[len(fields)-1]
+	if !strings.HasSuffix(destination, "/") {
+		destination += "/"
+	}
+	return destination
+}
+
+// contextPaths 将镜像中的路径转换为相对于COPY目标目录的路径（用于.dockerignore），最多返回10个。
+func (current step) contextPaths(paths []string) []string {
+	destination := current.destination()
+	if !path.IsAbs(destination) {
+		// a relative destination depends on the working directory
+		destination = ""
+	}
+	var result []string
+	for _, imagePath := range paths {
+		if len(result) == 10 {
+			break
+		}
+		if destination != "" && strings.HasPrefix(imagePath, strings.TrimSuffix(destination, "/")+"/") {
+			imagePath = strings.TrimPrefix(imagePath, strings.TrimSuffix(destination, "/")+"/")
+		}
+		result = append(result, strings.TrimPrefix(imagePath, "/"))
+	}
+	return result
+}
+
+// copyFields 返回COPY或ADD的参数（不包括选项）。
+func copyFields(args string) []string {
+	var fields []string
+	for _, field := range strings.Fields(args) {
+		if !strings.HasPrefix(field, "--") {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// ownerFlags 返回代替RUN中的chown和chmod的COPY选项（例如 "--chown=app:app --chmod=755"），RUN没有修改所有者或权限时返回空字符串。
+func ownerFlags(script string) string {
+	var flags []string
+	if match := chownPattern.FindStringSubmatch(script); match != nil {
+		flags = append(flags, "--chown="+match[2])
+	}
+	if match := chmodPattern.FindStringSubmatch(script); match != nil {
+		flags = append(flags, "--chmod="+match[2])
+	}
+	return strings.Join(flags, " ")
+}
+
+// appendCommand 和 replaceCommand 返回修改RUN的命令的函数：在最后添加一个命令，或者替换匹配的命令。
+func appendCommand(command string) func(string) string {
+	return func(args string) string {
+		return strings.TrimSpace(args) + " \\\n    && " + command
+	}
+}
+
+func replaceCommand(pattern *regexp.Regexp, replacement string) func(string) string {
+	return func(args string) string {
+		return pattern.ReplaceAllString(strings.TrimSpace(args), replacement)
+	}
+}
+
+// filesSize 返回树中给定目录下的文件大小之和。
+func filesSize(tree *filetree.FileTree, dirs []string) uint64 {
+	var size uint64
+	for _, dir := range dirs {
+		node, err := tree.GetNode(dir)
+		if err != nil || node == nil {
+			continue
+		}
+		node.VisitDepthChildFirst(func(node *filetree.FileNode) error {
+			if !node.IsWhiteout() && isFile(node) {
+				size += uint64(node.Data.FileInfo.Size)
+			}
+			return nil
+		}, nil)
+	}
+	return size
+}
+
+// isCachePath 返回路径是否在包管理器的缓存目录中（这些文件由清理缓存的建议处理）。
+func isCachePath(filePath string) bool {
+	for _, cache := range caches {
+		for _, dir := range cache.dirs {
+			if filePath == dir || strings.HasPrefix(filePath, dir+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isFile 返回节点是否是层中的文件（而不是目录或者没有自己的条目的父目录）。
+func isFile(node *filetree.FileNode) bool {
+	info := node.Data.FileInfo
+	return info.TypeFlag != 0 && info.TypeFlag != tar.TypeDir && !info.IsDir && len(node.Children) == 0
+}
@@ -0,0 +1,68 @@
+package advisor
+
+import (
+	"LGM/i18n"
+	"LGM/image"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"strings"
+)
+
+// Markdown 以Markdown格式返回建议：汇总、一览表以及每条建议修改后的指令。
+func Markdown(suggestions []Suggestion, analysis *image.AnalysisResult) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "# %s\n\n", i18n.T("Dockerfile suggestions"))
+	if len(analysis.RepoTags) > 0 {
+		fmt.Fprintf(&out, "- %s `%s`\n", i18n.T("Image:"), strings.Join(analysis.RepoTags, "`, `"))
+	}
+	if analysis.Dockerfile != nil {
+		fmt.Fprintf(&out, "- %s `%s`\n", i18n.T("Dockerfile:"), analysis.Dockerfile.Path)
+	}
+	fmt.Fprintf(&out, "- %s %s\n", i18n.T("Total Image size:"), humanize.Bytes(analysis.SizeBytes))
+	if len(suggestions) == 0 {
+		fmt.Fprintf(&out, "\n%s\n", i18n.T("No suggestions."))
+		return out.String()
+	}
+	saving, perBuild := TotalSaving(suggestions)
+	fmt.Fprintf(&out, "- %s %s\n", i18n.T("Estimated saving:"), humanize.Bytes(saving))
+	if perBuild > 0 {
+		fmt.Fprintf(&out, "- %s %s\n", i18n.T("Estimated saving per rebuild:"), humanize.Bytes(perBuild))
+	}
+
+	fmt.Fprintf(&out, "\n| # | %s | %s | %s |\n|---|---|---|---|\n", i18n.T("Suggestion"), i18n.T("Layers"), i18n.T("Estimated saving"))
+	for idx, suggestion := range suggestions {
+		layers := make([]string, len(suggestion.Layers))
+		for pos, layer := range suggestion.Layers {
+			layers[pos] = fmt.Sprint(layer)
+		}
+		fmt.Fprintf(&out, "| %d | %s | %s | %s |\n", idx+1, strings.Replace(suggestion.Message, "|", "\\|", -1), strings.Join(layers, ", "), FormatSaving(suggestion))
+	}
+
+	for idx, suggestion := range suggestions {
+		fmt.Fprintf(&out, "\n## %d. %s\n\n", idx+1, suggestion.Message)
+		fmt.Fprintf(&out, "%s %s\n\n", i18n.T("Estimated saving:"), FormatSaving(suggestion))
+		fmt.Fprintf(&out, "```dockerfile\n%s\n```\n", suggestion.Rewrite)
+	}
+	return out.String()
+}
+
+// TotalSaving 返回所有建议估计节省的镜像大小以及每次重新构建节省的字节数。
+// 同一层的多个建议可能节省相同的文件，所以这是一个上限。
+func TotalSaving(suggestions []Suggestion) (saving, perBuild uint64) {
+	for _, suggestion := range suggestions {
+		if suggestion.PerBuild {
+			perBuild += suggestion.Saving
+		} else {
+			saving += suggestion.Saving
+		}
+	}
+	return saving, perBuild
+}
+
+// FormatSaving 返回建议估计节省的字节数（例如 "230 MB" 或 "12 MB per rebuild"）。
+func FormatSaving(suggestion Suggestion) string {
+	if suggestion.PerBuild {
+		return i18n.T("%s per rebuild", humanize.Bytes(suggestion.Saving))
+	}
+	return humanize.Bytes(suggestion.Saving)
+}
@@ -0,0 +1,107 @@
+package advisor
+
+import (
+	"LGM/filetree"
+	"sort"
+	"strings"
+)
+
+// waste 记录每一层添加的文件中被之后的哪一层删除或替换（文件仍然占用前一层的空间）
+type waste struct {
+	layers map[[2]int]*wastedPair
+}
+
+// wastedPair 是一层（lower）添加而被之后的一层（upper）删除或替换的文件的大小，removedPaths 是被删除的路径（按大小从大到小）
+type wastedPair struct {
+	lower, upper      int
+	removed, replaced uint64
+	removedPaths      []string
+	pathSizes         map[string]uint64
+}
+
+// newWaste 查找每一层中的每个文件在之后第一个删除或替换它的层。包管理器的缓存不计算在内（见isCachePath）。
+func newWaste(trees []*filetree.FileTree) *waste {
+	result := &waste{layers: make(map[[2]int]*wastedPair)}
+	for lower, tree := range trees {
+		tree.VisitDepthChildFirst(func(node *filetree.FileNode) error {
+			if node.IsWhiteout() || !isFile(node) {
+				return nil
+			}
+			filePath := node.Path()
+			if isCachePath(filePath) {
+				return nil
+			}
+			upper, removedPath := touchedBy(trees, filePath, lower+1)
+			if upper < 0 {
+				return nil
+			}
+			key := [2]int{lower, upper}
+			pair := result.layers[key]
+			if pair == nil {
+				pair = &wastedPair{lower: lower, upper: upper, pathSizes: make(map[string]uint64)}
+				result.layers[key] = pair
+			}
+			size := uint64(node.Data.FileInfo.Size)
+			if removedPath == "" {
+				pair.replaced += size
+			} else {
+				pair.removed += size
+				pair.pathSizes[removedPath] += size
+			}
+			return nil
+		}, nil)
+	}
+	return result
+}
+
+// pairs 返回所有的层对（按层的顺序），被删除的路径按大小从大到小排列。
+func (result *waste) pairs() []*wastedPair {
+	pairs := make([]*wastedPair, 0, len(result.layers))
+	for _, pair := range result.layers {
+		pair.removedPaths = pair.removedPaths[:0]
+		for removedPath := range pair.pathSizes {
+			pair.removedPaths = append(pair.removedPaths, removedPath)
+		}
+		sort.Slice(pair.removedPaths, func(i, j int) bool {
+			a, b := pair.removedPaths[i], pair.removedPaths[j]
+			if pair.pathSizes[a] != pair.pathSizes[b] {
+				return pair.pathSizes[a] > pair.pathSizes[b]
+			}
+			return a < b
+		})
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].lower != pairs[j].lower {
+			return pairs[i].lower < pairs[j].lower
+		}
+		return pairs[i].upper < pairs[j].upper
+	})
+	return pairs
+}
+
+// touchedBy 返回从第from层开始第一个删除或替换给定文件的层（没有时返回-1）。
+// 文件被删除时还返回被删除的路径（whiteout或不透明目录删除的可能是其父目录），被替换时返回空字符串。
+func touchedBy(trees []*filetree.FileTree, filePath string, from int) (int, string) {
+	names := strings.Split(strings.Trim(filePath, "/"), "/")
+	for idx := from; idx < len(trees); idx++ {
+		node := trees[idx].Root
+		for depth, name := range names {
+			if node.Children[whiteoutPrefix+name] != nil {
+				return idx, "/" + strings.Join(names[:depth+1], "/")
+			}
+			child := node.Children[name]
+			if child == nil {
+				if node.Opaque {
+					return idx, "/" + strings.Join(names[:depth], "/")
+				}
+				break
+			}
+			if depth == len(names)-1 {
+				return idx, ""
+			}
+			node = child
+		}
+	}
+	return -1, ""
+}
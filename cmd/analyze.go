@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	"LGM/i18n"
 	"LGM/runtime"
 	"LGM/utils"
 	"fmt"
@@ -32,14 +33,14 @@ func doAnalyzeCmd(cmd *cobra.Command, args []string)  {
 			return
 		}
 
-		fmt.Println("No image argument given")
+		fmt.Println(i18n.T("No image argument given"))
 		cmd.Help()
 		utils.Exit(1)
 	}
 
 	userImage := args[0]
 	if userImage == "" {
-		fmt.Println("No image argument given")
+		fmt.Println(i18n.T("No image argument given"))
 		cmd.Help()
 		utils.Exit(1)
 	}
@@ -50,5 +51,8 @@ func doAnalyzeCmd(cmd *cobra.Command, args []string)  {
 		ImageId:      userImage,
 		ExportFile:   exportFile,
 		CiConfigFile: ciConfigFile,
+		Dockerfile:   dockerfilePath,
+		AdviceFile:   adviceFile,
+		HtmlFile:     htmlFile,
 	})
 }
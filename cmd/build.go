@@ -15,16 +15,22 @@
 package cmd
 
 import (
+	"LGM/i18n"
 	"LGM/runtime"
 	"LGM/utils"
 	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 // buildCmd 表示生成命令
 var buildCmd = &cobra.Command{
 	Use:   "build [any valid 'docker build' arguments]",
 	Short: "Builds and analyzes a docker image from a Dockerfile (this is a thin wrapper for the 'docker build' command).",
-	Run: doBuildCmd,
+	Run:   doBuildCmd,
+	// the arguments are handed to docker build as they are (except the persistent flags of LGM, see lgmFlags)
+	DisableFlagParsing: true,
 }
 
 func init() {
@@ -44,10 +50,100 @@ func init() {
 func doBuildCmd(cmd *cobra.Command, args []string) {
 	defer utils.CleanUp()
 
+	// with the flag parsing disabled, the help flag is one of the arguments
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			cmd.Help()
+			return
+		}
+	}
+
+	// the config is read again with the config file and language given in the arguments
+	args = lgmFlags(args)
+	initConfig()
 	initLogging()
 
+	dockerfile, target := buildDockerfile(args)
 	runtime.Run(runtime.Options{
 		BuildArgs:  args,
 		ExportFile: exportFile,
+		Dockerfile: dockerfile,
+		Target:     target,
 	})
-}
\ No newline at end of file
+}
+
+// lgmFlags 设置参数中LGM的全局参数（例如 --config 和 --lang，可以写为 "--config FILE" 或 "--config=FILE"），返回其余交给docker build的参数。
+func lgmFlags(args []string) []string {
+	var dockerArgs []string
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		if !strings.HasPrefix(arg, "--") {
+			dockerArgs = append(dockerArgs, arg)
+			continue
+		}
+		name, value := strings.TrimPrefix(arg, "--"), ""
+		hasValue := false
+		if pos := strings.Index(name, "="); pos >= 0 {
+			name, value, hasValue = name[:pos], name[pos+1:], true
+		}
+		flag := rootCmd.PersistentFlags().Lookup(name)
+		if flag == nil {
+			dockerArgs = append(dockerArgs, arg)
+			continue
+		}
+		if !hasValue {
+			if idx+1 >= len(args) {
+				utils.PrintAndExit(i18n.T("flag needs an argument: --%s", name))
+			}
+			idx++
+			value = args[idx]
+		}
+		if err := flag.Value.Set(value); err != nil {
+			utils.PrintAndExit(i18n.T("invalid argument '%s' for --%s: %v", value, name, err))
+		}
+	}
+	return dockerArgs
+}
+
+// buildDockerfile 返回docker build参数中的Dockerfile（-f/--file，默认为构建上下文中的Dockerfile）和构建的阶段（--target）。
+// Dockerfile从标准输入读取（"-"）、构建上下文是URL或者文件不存在时返回空的路径。
+func buildDockerfile(args []string) (dockerfile, target string) {
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		next := func() string {
+			if idx+1 < len(args) {
+				idx++
+				return args[idx]
+			}
+			return ""
+		}
+		switch {
+		case arg == "-f" || arg == "--file":
+			dockerfile = next()
+		case strings.HasPrefix(arg, "--file="):
+			dockerfile = strings.TrimPrefix(arg, "--file=")
+		case strings.HasPrefix(arg, "-f"):
+			dockerfile = strings.TrimPrefix(arg[2:], "=")
+		case arg == "--target":
+			target = next()
+		case strings.HasPrefix(arg, "--target="):
+			target = strings.TrimPrefix(arg, "--target=")
+		}
+	}
+
+	if dockerfile == "" && len(args) > 0 {
+		// the build context is the last argument
+		context := args[len(args)-1]
+		if strings.HasPrefix(context, "-") || strings.Contains(context, "://") || strings.HasPrefix(context, "git@") {
+			return "", target
+		}
+		dockerfile = filepath.Join(context, "Dockerfile")
+	}
+	if dockerfile == "-" {
+		return "", target
+	}
+	if _, err := os.Stat(dockerfile); err != nil {
+		return "", target
+	}
+	return dockerfile, target
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"LGM/keybinding"
+	"LGM/theme"
+	"LGM/utils"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var forceConfigInit bool
+
+// configCmd 表示config命令及其子命令
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Create, show or validate the LGM config file.",
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Writes a commented config file with the default values (to --config or $XDG_CONFIG_HOME/LGM/config.yaml).",
+	Args:  cobra.NoArgs,
+	Run:   doConfigInitCmd,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Prints the effective config values and where each value comes from (default, preset, file or env).",
+	Args:  cobra.NoArgs,
+	Run:   doConfigShowCmd,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Checks the config for unknown keys, invalid keybindings, keybinding conflicts and out of range values.",
+	Args:  cobra.NoArgs,
+	Run:   doConfigValidateCmd,
+}
+
+func init() {
+	configInitCmd.Flags().BoolVarP(&forceConfigInit, "force", "f", false, "Overwrite an existing config file.")
+
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// doConfigInitCmd 将带注释的默认配置写入--config指定的文件或XDG配置目录
+func doConfigInitCmd(cmd *cobra.Command, args []string) {
+	target := cfgFile
+	if target == "" {
+		target = defaultConfigPath()
+	}
+
+	if _, err := os.Stat(target); err == nil && !forceConfigInit {
+		utils.PrintAndExit(fmt.Sprintf("config file already exists: %s (use --force to overwrite it)", target))
+	}
+
+	if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+		utils.PrintAndExit(err)
+	}
+	if err := ioutil.WriteFile(target, defaultConfigYaml(), 0644); err != nil {
+		utils.PrintAndExit(err)
+	}
+	fmt.Println("Wrote the default config to", target)
+}
+
+// doConfigShowCmd 打印合并后的配置值及其来源
+func doConfigShowCmd(cmd *cobra.Command, args []string) {
+	file, err := readConfigFile()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to read config file:", err)
+	}
+
+	status := ""
+	if file == nil {
+		status = " (not found)"
+	}
+	fmt.Printf("# config file: %s%s\n", viper.ConfigFileUsed(), status)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, option := range configOptions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", option.Key, configValueString(viper.Get(option.Key)), configSource(file, option.Key))
+	}
+	w.Flush()
+}
+
+// doConfigValidateCmd 检查配置文件中的未知配置项、按键定义、按键冲突以及取值范围，发现问题时以状态码1退出
+func doConfigValidateCmd(cmd *cobra.Command, args []string) {
+	var problems []string
+
+	file, err := readConfigFile()
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	if file != nil {
+		keys := file.AllKeys()
+		sort.Strings(keys)
+		for _, key := range keys {
+			if strings.HasPrefix(key, "themes.") {
+				if err := checkThemeKey(key, file.Get(key)); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+				}
+				continue
+			}
+			if lookupConfigOption(key) == nil {
+				problems = append(problems, fmt.Sprintf("%s: unknown config key", key))
+			}
+		}
+	}
+
+	for _, option := range configOptions {
+		if option.Check == nil {
+			continue
+		}
+		if err := option.Check(viper.Get(option.Key)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v (from %s)", option.Key, err, configSource(file, option.Key)))
+		}
+	}
+
+	// invalid keys are already reported above, only the conflicts are left
+	for _, err := range keybinding.Validate() {
+		if conflict, ok := err.(keybinding.Conflict); ok {
+			problems = append(problems, conflict.Error())
+		}
+	}
+
+	if len(problems) == 0 {
+		fmt.Println("The config is valid:", viper.ConfigFileUsed())
+		return
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	fmt.Printf("%d problem(s) found in the config\n", len(problems))
+	utils.Exit(1)
+}
+
+// configDir 返回LGM的默认配置目录（$XDG_CONFIG_HOME/LGM，未设置时为 ~/.config/LGM）
+func configDir() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", err
+		}
+		configHome = path.Join(home, ".config")
+	}
+	return path.Join(configHome, "LGM"), nil
+}
+
+// defaultConfigPath 返回"LGM config init"默认写入的路径，getCfgFile 会在此处找到该文件
+func defaultConfigPath() string {
+	dir, err := configDir()
+	if err != nil {
+		utils.PrintAndExit(err)
+	}
+	return path.Join(dir, "config.yaml")
+}
+
+// defaultLayoutPath 返回保存窗格布局的默认文件（与默认配置文件在同一目录），无法确定用户目录时返回空字符串（不保存布局）
+func defaultLayoutPath() string {
+	dir, err := configDir()
+	if err != nil {
+		return ""
+	}
+	return path.Join(dir, "layout.yaml")
+}
+
+// readConfigFile 单独读取当前使用的配置文件（不包含默认值和环境变量），文件不存在时返回nil
+func readConfigFile() (*viper.Viper, error) {
+	file := viper.New()
+	file.SetConfigFile(viper.ConfigFileUsed())
+	if err := file.ReadInConfig(); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// envName 返回可以覆盖给定配置项的环境变量名称（与viper的查找规则一致）
+func envName(key string) string {
+	return envKeyReplacer.Replace(strings.ToUpper(envPrefix + "_" + key))
+}
+
+// configSource 返回配置项当前值的来源，优先级与viper一致：环境变量、配置文件、按键方案、默认值
+func configSource(file *viper.Viper, key string) string {
+	if value, ok := os.LookupEnv(envName(key)); ok && value != "" {
+		return "env " + envName(key)
+	}
+	if file != nil && file.IsSet(key) {
+		return "file"
+	}
+	preset := viper.GetString("keybinding.preset")
+	if _, exists := keybinding.Presets[preset][key]; exists {
+		return "preset " + preset
+	}
+	return "default"
+}
+
+// configValueString 返回配置值的显示文字
+func configValueString(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		if value == "" {
+			return `""`
+		}
+		return value
+	case []string, []interface{}:
+		return "[" + strings.Join(cast.ToStringSlice(value), ", ") + "]"
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// yamlValue 返回默认值的YAML表示
+func yamlValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value)
+	case []string:
+		quoted := make([]string, len(value))
+		for idx, item := range value {
+			quoted[idx] = strconv.Quote(item)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// defaultConfigYaml 生成带注释的默认配置。
+// 按键操作的配置项被注释掉：配置文件中的按键优先于按键方案（keybinding.preset），写出全部默认按键会使方案失效。
+func defaultConfigYaml() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# LGM config file, generated by \"LGM config init\".\n")
+	buf.WriteString("# Every value is the built-in default. A value can also be overridden with an environment\n")
+	buf.WriteString("# variable, e.g. " + envName("filetree.pane-width") + "=0.4 for filetree.pane-width.\n")
+	buf.WriteString("# Run \"LGM config validate\" after editing and \"LGM keys\" to list the keybindings.\n")
+
+	section := ""
+	for _, option := range configOptions {
+		parts := strings.SplitN(option.Key, ".", 2)
+		if parts[0] != section {
+			section = parts[0]
+			buf.WriteString("\n" + section + ":\n")
+		}
+
+		buf.WriteString("  # " + option.Help + "\n")
+		line := parts[1] + ": " + yamlValue(option.Default)
+		if isKeybindingAction(option.Key) {
+			buf.WriteString("  # " + line + "\n")
+		} else {
+			buf.WriteString("  " + line + "\n")
+		}
+	}
+
+	buf.WriteString("\n# Custom themes, selected with theme.name. A style is a space separated list of colors\n")
+	buf.WriteString("# (" + strings.Join(theme.ColorNames(), ", ") + "; prefix with bg: for the background)\n")
+	buf.WriteString("# and attributes (bold, underline, reverse). Elements that are not set use the default theme.\n")
+	buf.WriteString("# Elements: " + strings.Join(theme.Elements, ", ") + "\n")
+	buf.WriteString("# themes:\n")
+	buf.WriteString("#   my-theme:\n")
+	buf.WriteString("#     added: \"fg:cyan bold\"\n")
+	buf.WriteString("#     search-match: \"fg:white bg:blue\"\n")
+	return buf.Bytes()
+}
+
+// isKeybindingAction 确定配置项是否为按键操作（而非keybinding.preset等设置）
+func isKeybindingAction(key string) bool {
+	for _, binding := range keybinding.Registry {
+		if binding.Config == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"LGM/runtime"
+	"LGM/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	extractLayer     int
+	extractOnlyLayer bool
+	extractOutputDir string
+)
+
+// extractCmd 表示extract命令
+var extractCmd = &cobra.Command{
+	Use:   "extract IMAGE PATH",
+	Short: "Copies a file or directory out of a docker image without running a container.",
+	Long: `Copies a file or directory out of a docker image without running a container.
+
+By default the files are taken from the final file system of the image (all
+layers stacked, removed files are not extracted). --layer N takes them from the
+file system as it is after layer N (0 is the base layer), add --only-layer to
+extract only the files that layer N itself contains.
+
+Like docker cp, the file or directory is written into the output directory with
+its own name ("/" writes the whole file system into the output directory). File
+modes, symlinks and hard links are kept, the owners are kept when running as root.
+For example:
+
+  LGM extract ubuntu:latest /etc --layer 0 -o out/`,
+	Args: cobra.ExactArgs(2),
+	Run:  doExtractCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(extractCmd)
+
+	extractCmd.Flags().IntVar(&extractLayer, "layer", -1, "Index of the layer to extract from (0 is the base layer, -1 the last layer).")
+	extractCmd.Flags().BoolVar(&extractOnlyLayer, "only-layer", false, "Extract only the files contained in the layer itself instead of the stacked file system.")
+	extractCmd.Flags().StringVarP(&extractOutputDir, "output", "o", ".", "Directory to write the extracted files to.")
+}
+
+// doExtractCmd 从给定的镜像中提取文件
+func doExtractCmd(cmd *cobra.Command, args []string) {
+	defer utils.CleanUp()
+
+	initLogging()
+
+	runtime.Extract(runtime.Options{
+		ImageId:          args[0],
+		ExtractPath:      args[1],
+		ExtractLayer:     extractLayer,
+		ExtractOnlyLayer: extractOnlyLayer,
+		ExtractOutputDir: extractOutputDir,
+	})
+}
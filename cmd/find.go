@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"LGM/runtime"
+	"LGM/utils"
+	"github.com/spf13/cobra"
+)
+
+// findCmd 表示find命令
+var findCmd = &cobra.Command{
+	Use:   "find IMAGE QUERY",
+	Short: "Lists the files changed by each layer of a docker image that match the given query (without the TUI).",
+	Long: `Lists the files changed by each layer of a docker image that match the given query (without the TUI).
+
+The query uses the same language as the file tree filter. Terms are separated by
+whitespace and all of them must match:
+
+  size>10MB           size comparison (>, >=, <, <=, =), directories use their cumulative size
+  uid:0  gid>=1000    owner and group comparison
+  type:symlink        file, dir, symlink, hardlink, char, block or fifo
+  diff:added          added, removed, modified or unchanged
+  path:/usr/lib/**    path glob (* stays within a directory, ** crosses directories)
+  name:*.so           file name glob
+  mode:+s  mode:0755  has (+) or lacks (-) any of the rwxst bits, or an exact octal mode
+  lib.*\.so           a term without a field is a regular expression on the path
+
+Prefix a term with ! to negate it. Files a layer leaves unchanged are only listed
+when the query has a diff term, e.g. diff:unchanged. For example:
+
+  LGM find ubuntu:latest 'size>1MB type:file !path:/usr/share/**'`,
+	Args: cobra.ExactArgs(2),
+	Run:  doFindCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(findCmd)
+}
+
+// doFindCmd 在给定的镜像中查找满足查询条件的文件
+func doFindCmd(cmd *cobra.Command, args []string) {
+	defer utils.CleanUp()
+
+	initLogging()
+
+	runtime.Find(runtime.Options{
+		ImageId: args[0],
+		Query:   args[1],
+	})
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"LGM/keybinding"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// keysCmd 表示keys命令
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Prints the keybindings of every pane (including the ones overridden in the config file).",
+	Args:  cobra.NoArgs,
+	Run:   doKeysCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(keysCmd)
+}
+
+// doKeysCmd 按窗格打印与TUI中的按键帮助（?）相同的按键表
+func doKeysCmd(cmd *cobra.Command, args []string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PANE\tKEYS\tACTION\tCONFIG")
+	for _, view := range keybinding.Views() {
+		for _, binding := range keybinding.Registry {
+			if binding.View != view {
+				continue
+			}
+			config := binding.Config
+			if config == "" {
+				config = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", binding.View, binding.Describe(), binding.Help, config)
+		}
+	}
+	w.Flush()
+
+	for _, err := range keybinding.Validate() {
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"LGM/linter"
+	"LGM/runtime"
+	"LGM/utils"
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var (
+	lintFormat     string
+	lintOutputFile string
+)
+
+// lintCmd 表示lint命令
+var lintCmd = &cobra.Command{
+	Use:   "lint [DOCKERFILE]",
+	Short: "Checks a Dockerfile for common mistakes (without building or analyzing an image).",
+	Long: `Checks a Dockerfile for common mistakes (without building or analyzing an image).
+
+The rules and their default severities:
+
+` + lintRulesHelp() + `
+The severity of each rule can be changed with the lint.rules.<rule> config
+option (error, warning, info or off). The command fails when a problem has at
+least the lint.fail-on severity (error by default). Set lint.before-build to
+lint the Dockerfile before "LGM build" as well.
+
+A comment right before an instruction ignores the problems of the instruction:
+
+  # lgm-ignore: sudo, apt-cleanup
+  RUN sudo apt-get install -y curl
+
+"# lgm-ignore" without rules ignores all of them. For example:
+
+  LGM lint Dockerfile --format sarif -o lint.sarif`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  doLintCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text", "Output format: "+strings.Join(linter.Formats, ", ")+".")
+	lintCmd.Flags().StringVarP(&lintOutputFile, "output", "o", "", "File to write the result to (default the standard output).")
+}
+
+// lintRulesHelp 返回lint命令帮助中的规则列表
+func lintRulesHelp() string {
+	var help strings.Builder
+	for _, rule := range linter.Rules {
+		fmt.Fprintf(&help, "  %-18s %-8s %s\n", rule.ID, rule.Severity, rule.Description)
+	}
+	return help.String()
+}
+
+// doLintCmd 按规则检查给定的Dockerfile（默认为当前目录中的Dockerfile）
+func doLintCmd(cmd *cobra.Command, args []string) {
+	defer utils.CleanUp()
+
+	initLogging()
+
+	path := "Dockerfile"
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if !isLintFormat(lintFormat) {
+		utils.PrintAndExit(fmt.Sprintf("unknown format '%s' (available: %s)", lintFormat, strings.Join(linter.Formats, ", ")))
+	}
+	runtime.Lint(runtime.Options{
+		Dockerfile: path,
+		LintFormat: lintFormat,
+		OutputFile: lintOutputFile,
+	})
+}
+
+func isLintFormat(format string) bool {
+	for _, available := range linter.Formats {
+		if format == available {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"LGM/filetree"
+	"LGM/image"
+	"LGM/keybinding"
+	"LGM/linter"
+	"LGM/theme"
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// configOption 描述一个配置项：名称、默认值、说明（写入"LGM config init"生成的配置文件）以及取值检查（"LGM config validate"使用，可以为空）。
+type configOption struct {
+	Key     string
+	Default interface{}
+	Help    string
+	Check   func(value interface{}) error
+}
+
+// configOptions 列出所有支持的配置项，initConfig 按此设置默认值。
+var configOptions = []configOption{
+	{Key: "log.enabled", Default: true, Help: "Write a log file", Check: checkBool},
+	{Key: "log.path", Default: "./LGM.log", Help: "Path of the log file"},
+	{Key: "log.level", Default: log.InfoLevel.String(), Help: "Log level: panic, fatal, error, warn, info, debug or trace", Check: checkLogLevel},
+
+	// keybindings: status view / global
+	{Key: "keybinding.preset", Default: "default", Help: "Built-in keybinding preset: " + strings.Join(keybinding.PresetNames(), " or ") + " (keys set below take precedence)", Check: checkPreset},
+	{Key: "keybinding.sequence-timeout", Default: "1s", Help: "How long to wait for the next key of a key sequence (e.g. \"g g\")", Check: checkPositiveDuration},
+	{Key: "keybinding.quit", Default: "ctrl+c", Help: "Quit", Check: checkKeys},
+	{Key: "keybinding.toggle-view", Default: "tab", Help: "Switch between the layer, file tree, details and image config panes", Check: checkKeys},
+	{Key: "keybinding.filter-files", Default: "ctrl+f, ctrl+slash", Help: "Show/hide the file tree filter", Check: checkKeys},
+	{Key: "keybinding.help", Default: "?", Help: "Show/hide the keybinding help", Check: checkKeys},
+	// keybindings: pane layout (layer, filetree and details views)
+	{Key: "keybinding.narrow-left-column", Default: "<", Help: "Make the layer and details panes narrower (and the file tree wider)", Check: checkKeys},
+	{Key: "keybinding.widen-left-column", Default: ">", Help: "Make the layer and details panes wider (and the file tree narrower)", Check: checkKeys},
+	{Key: "keybinding.shrink-details-pane", Default: "[", Help: "Make the details pane lower (and the layer pane higher)", Check: checkKeys},
+	{Key: "keybinding.grow-details-pane", Default: "]", Help: "Make the details pane higher (and the layer pane lower)", Check: checkKeys},
+	{Key: "keybinding.maximize-pane", Default: "m", Help: "Maximize the selected pane or restore all panes", Check: checkKeys},
+	{Key: "keybinding.reset-layout", Default: "=", Help: "Restore the pane sizes of filetree.pane-width", Check: checkKeys},
+	// keybindings: cursor movement (all views)
+	{Key: "keybinding.cursor-up", Default: "up", Help: "Move the cursor up", Check: checkKeys},
+	{Key: "keybinding.cursor-down", Default: "down", Help: "Move the cursor down", Check: checkKeys},
+	{Key: "keybinding.cursor-left", Default: "left", Help: "Move the cursor left (parent directory in the file tree)", Check: checkKeys},
+	{Key: "keybinding.cursor-right", Default: "right", Help: "Move the cursor right (enter the directory in the file tree)", Check: checkKeys},
+	{Key: "keybinding.goto-top", Default: "home", Help: "Go to the first row", Check: checkKeys},
+	{Key: "keybinding.goto-bottom", Default: "end", Help: "Go to the last row", Check: checkKeys},
+	{Key: "keybinding.page-up", Default: "pgup", Help: "Previous page", Check: checkKeys},
+	{Key: "keybinding.page-down", Default: "pgdn", Help: "Next page", Check: checkKeys},
+	// keybindings: layer view
+	{Key: "keybinding.compare-all", Default: "ctrl+a", Help: "Show the aggregated changes up to the selected layer", Check: checkKeys},
+	{Key: "keybinding.compare-layer", Default: "ctrl+l", Help: "Show the changes of the selected layer", Check: checkKeys},
+	{Key: "keybinding.compare-mark", Default: "ctrl+k", Help: "Mark the selected layer as a bound of the compare range", Check: checkKeys},
+	{Key: "keybinding.squash-preview", Default: "s", Help: "Show/hide what merging the layers of the compare range into one would save", Check: checkKeys},
+	{Key: "keybinding.squash-write", Default: "S", Help: "Write the image with the layers of the shown squash merged to squash.output-file", Check: checkKeys},
+	// keybindings: filetree view
+	{Key: "keybinding.toggle-collapse-dir", Default: "space", Help: "Collapse/expand the selected directory", Check: checkKeys},
+	{Key: "keybinding.toggle-collapse-all-dir", Default: "ctrl+space", Help: "Collapse/expand all directories", Check: checkKeys},
+	{Key: "keybinding.collapse-dir", Default: "", Help: "Collapse the selected directory (unbound by default)", Check: checkKeys},
+	{Key: "keybinding.expand-dir", Default: "", Help: "Expand the selected directory (unbound by default)", Check: checkKeys},
+	{Key: "keybinding.toggle-filetree-attributes", Default: "ctrl+b", Help: "Show/hide the file attributes", Check: checkKeys},
+	{Key: "keybinding.toggle-added-files", Default: "ctrl+a", Help: "Show/hide added files", Check: checkKeys},
+	{Key: "keybinding.toggle-removed-files", Default: "ctrl+r", Help: "Show/hide removed files", Check: checkKeys},
+	{Key: "keybinding.toggle-modified-files", Default: "ctrl+m", Help: "Show/hide modified files", Check: checkKeys},
+	{Key: "keybinding.toggle-unchanged-files", Default: "ctrl+u", Help: "Show/hide unmodified files", Check: checkKeys},
+	{Key: "keybinding.sort-files", Default: "ctrl+o", Help: "Sort the file tree by name, size or change type", Check: checkKeys},
+	{Key: "keybinding.search", Default: "/", Help: "Search the file tree", Check: checkKeys},
+	{Key: "keybinding.search-next", Default: "n", Help: "Go to the next search match", Check: checkKeys},
+	{Key: "keybinding.search-prev", Default: "N", Help: "Go to the previous search match", Check: checkKeys},
+	{Key: "keybinding.view-file", Default: "v", Help: "Open/close the file viewer for the selected file", Check: checkKeys},
+	{Key: "keybinding.extract-file", Default: "e", Help: "Extract the selected file or directory to extract.output-dir", Check: checkKeys},
+	{Key: "keybinding.mark-removal", Default: "x", Help: "Mark/unmark the selected file or directory for removal (saved to slim.marks-file)", Check: checkKeys},
+	// keybindings: details view
+	{Key: "keybinding.sort-inefficiencies", Default: "ctrl+o", Help: "Sort the inefficiencies by wasted space or count", Check: checkKeys},
+	// keybindings: file viewer
+	{Key: "keybinding.viewer-toggle-diff", Default: "d", Help: "Switch the file viewer between the contents and the diff against the previous version", Check: checkKeys},
+	{Key: "keybinding.viewer-toggle-hex", Default: "x", Help: "Switch the file viewer between text and hex dump", Check: checkKeys},
+
+	{Key: "theme.name", Default: "default", Help: "Color theme: " + strings.Join(theme.Names(nil), ", ") + " or one defined under themes (colors are dropped when NO_COLOR is set)", Check: checkTheme},
+	{Key: "theme.glyphs", Default: "unicode", Help: "Characters of the file tree and the pane decorations: " + strings.Join(theme.GlyphSetNames(), " or "), Check: checkGlyphs},
+
+	{Key: "mouse.enabled", Default: true, Help: "Select panes, rows and layers with the mouse (hold Shift to select text in most terminals)", Check: checkBool},
+	{Key: "mouse.double-click-interval", Default: "500ms", Help: "Longest time between the two clicks of a double click", Check: checkPositiveDuration},
+
+	{Key: "layout.persist", Default: true, Help: "Save the pane sizes changed while LGM is running and restore them on the next run", Check: checkBool},
+	{Key: "layout.file", Default: "", Help: "File the pane layout is saved to (empty: layout.yaml next to the default config file)"},
+
+	{Key: "diff.hide", Default: []string{}, Help: "Change types hidden in the file tree: added, removed, changed and/or unchanged", Check: checkDiffTypes},
+
+	{Key: "layer.show-aggregated-changes", Default: false, Help: "Start by showing the aggregated changes instead of the changes of the selected layer", Check: checkBool},
+	{Key: "layer.columns", Default: image.DefaultLayerColumns, Help: "Columns of the layer pane, in order: " + strings.Join(image.LayerColumnNames(), ", ") + " (bar: size relative to the image, wasted: bytes of inefficient files, line: Dockerfile line of the layer, added automatically with --dockerfile)", Check: checkLayerColumns},
+
+	{Key: "filetree.collapse-dir", Default: false, Help: "Start with all directories collapsed", Check: checkBool},
+	{Key: "filetree.pane-width", Default: 0.5, Help: "Width of the file tree pane as a fraction of the screen (greater than 0 and less than 1)", Check: checkPaneWidth},
+	{Key: "filetree.show-attributes", Default: true, Help: "Show the file attributes (permission, owner and size)", Check: checkBool},
+	{Key: "filetree.sort-order", Default: "name", Help: "Sort order of the file tree: name, size or type", Check: checkSortOrder},
+
+	{Key: "cache.memory-limit", Default: "512MB", Help: "Memory used to cache the compared file trees (e.g. 256MB, 1GB)", Check: checkByteSize},
+
+	{Key: "viewer.spool-layers", Default: true, Help: "Keep a copy of the image layers in a temporary directory while LGM is running, so that the file viewer can show the file contents, files can be extracted from the file tree and squashed images can be written from the layer pane", Check: checkBool},
+	{Key: "viewer.spool-limit", Default: "2GB", Help: "Largest total size of the image layers kept with viewer.spool-layers (e.g. 500MB, 10GB, 0 for no limit). Larger images are analyzed without the copy", Check: checkByteSize},
+	{Key: "viewer.max-file-size", Default: "1MB", Help: "Largest part of a file shown in the file viewer (longer files are truncated)", Check: checkByteSize},
+
+	{Key: "extract.output-dir", Default: ".", Help: "Directory the file tree extracts the selected file or directory to"},
+	{Key: "slim.marks-file", Default: "LGM-slim.yaml", Help: "Rules file the files marked for removal in the file tree are saved to (see \"LGM slim\" and \"LGM export --remove-marked\")"},
+	{Key: "squash.output-file", Default: "LGM-squashed.tar", Help: "Image archive the layer pane writes the squashed image to"},
+
+	{Key: "advice.min-saving", Default: "100KB", Help: "Smallest estimated saving of the Dockerfile suggestions shown in the layer details and written with --advice", Check: checkByteSize},
+
+	{Key: "html.max-tree-entries", Default: 5000, Help: "Most files and directories shown in the file tree of each layer in the HTML report (--html)", Check: checkPositiveInt},
+
+	{Key: "lint.before-build", Default: false, Help: "Lint the Dockerfile before \"LGM build\" and do not build when it fails (see lint.fail-on)", Check: checkBool},
+	{Key: "lint.fail-on", Default: "error", Help: "Lowest severity that makes \"LGM lint\" fail: error, warning, info or off (never fail)", Check: checkSeverity},
+	// lint rules: severity of each rule of "LGM lint" (error, warning, info or off)
+	{Key: "lint.rules.base-unpinned", Default: "warning", Help: "Base image without a version tag or digest", Check: checkSeverity},
+	{Key: "lint.rules.base-latest", Default: "warning", Help: "Base image with the latest tag", Check: checkSeverity},
+	{Key: "lint.rules.add-url", Default: "warning", Help: "ADD downloading a remote URL without --checksum", Check: checkSeverity},
+	{Key: "lint.rules.missing-user", Default: "warning", Help: "Final stage running as root (no USER or USER root)", Check: checkSeverity},
+	{Key: "lint.rules.apt-cleanup", Default: "info", Help: "apt-get install without removing /var/lib/apt/lists in the same RUN", Check: checkSeverity},
+	{Key: "lint.rules.sudo", Default: "warning", Help: "sudo in RUN", Check: checkSeverity},
+	{Key: "lint.rules.multiple-cmd", Default: "warning", Help: "More than one CMD in a stage (only the last one takes effect)", Check: checkSeverity},
+	{Key: "lint.rules.shell-entrypoint", Default: "warning", Help: "ENTRYPOINT in shell form (the process does not receive signals)", Check: checkSeverity},
+}
+
+// lookupConfigOption 返回给定名称的配置项，不存在时返回nil。
+func lookupConfigOption(key string) *configOption {
+	for idx := range configOptions {
+		if configOptions[idx].Key == key {
+			return &configOptions[idx]
+		}
+	}
+	return nil
+}
+
+// customThemes 返回配置文件中定义的主题（themes.<名称>.<元素>: <样式>）
+func customThemes() map[string]map[string]string {
+	themes := make(map[string]map[string]string)
+	for name, styles := range viper.GetStringMap("themes") {
+		themes[name] = cast.ToStringMapString(styles)
+	}
+	return themes
+}
+
+// checkThemeKey 检查配置文件中自定义主题的一个样式（themes.<名称>.<元素>）
+func checkThemeKey(key string, value interface{}) error {
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("expected themes.<theme>.<element>")
+	}
+	if !theme.IsElement(parts[2]) {
+		return fmt.Errorf("unknown theme element '%s' (available: %s)", parts[2], strings.Join(theme.Elements, ", "))
+	}
+	_, err := theme.ParseStyle(cast.ToString(value))
+	return err
+}
+
+func checkBool(value interface{}) error {
+	_, err := cast.ToBoolE(value)
+	return err
+}
+
+func checkLogLevel(value interface{}) error {
+	_, err := log.ParseLevel(cast.ToString(value))
+	return err
+}
+
+func checkKeys(value interface{}) error {
+	keys, err := cast.ToStringE(value)
+	if err != nil {
+		return err
+	}
+	_, err = keybinding.ParseAll(keys)
+	return err
+}
+
+func checkPreset(value interface{}) error {
+	name := cast.ToString(value)
+	if _, exists := keybinding.Presets[name]; !exists && name != "" {
+		return fmt.Errorf("unknown preset '%s' (available: %s)", name, strings.Join(keybinding.PresetNames(), ", "))
+	}
+	return nil
+}
+
+func checkTheme(value interface{}) error {
+	name := cast.ToString(value)
+	names := theme.Names(customThemes())
+	for _, available := range names {
+		if available == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown theme '%s' (available: %s)", name, strings.Join(names, ", "))
+}
+
+func checkGlyphs(value interface{}) error {
+	_, err := theme.LookupGlyphs(cast.ToString(value))
+	return err
+}
+
+func checkPositiveDuration(value interface{}) error {
+	duration, err := cast.ToDurationE(value)
+	if err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return fmt.Errorf("must be greater than 0, got %s", duration)
+	}
+	return nil
+}
+
+func checkPositiveInt(value interface{}) error {
+	number, err := cast.ToIntE(value)
+	if err != nil {
+		return err
+	}
+	if number <= 0 {
+		return fmt.Errorf("must be greater than 0, got %d", number)
+	}
+	return nil
+}
+
+func checkDiffTypes(value interface{}) error {
+	types, err := cast.ToStringSliceE(value)
+	if err != nil {
+		return err
+	}
+	for _, diffType := range types {
+		switch strings.ToLower(diffType) {
+		case "added", "removed", "changed", "unchanged":
+		default:
+			return fmt.Errorf("unknown change type '%s' (available: added, removed, changed, unchanged)", diffType)
+		}
+	}
+	return nil
+}
+
+func checkLayerColumns(value interface{}) error {
+	columns, err := cast.ToStringSliceE(value)
+	if err != nil {
+		return err
+	}
+	_, err = image.ParseLayerColumns(columns)
+	return err
+}
+
+func checkPaneWidth(value interface{}) error {
+	width, err := cast.ToFloat64E(value)
+	if err != nil {
+		return err
+	}
+	if width <= 0 || width >= 1 {
+		return fmt.Errorf("must be greater than 0 and less than 1, got %v", width)
+	}
+	return nil
+}
+
+func checkSortOrder(value interface{}) error {
+	_, err := filetree.ParseSortOrder(cast.ToString(value))
+	return err
+}
+
+func checkSeverity(value interface{}) error {
+	_, err := linter.ParseSeverity(cast.ToString(value))
+	return err
+}
+
+func checkByteSize(value interface{}) error {
+	_, err := humanize.ParseBytes(cast.ToString(value))
+	return err
+}
@@ -16,6 +16,9 @@ package cmd
 
 import (
 	"LGM/filetree"
+	"LGM/i18n"
+	"LGM/keybinding"
+	"LGM/theme"
 	"LGM/utils"
 	"fmt"
 	"io/ioutil"
@@ -30,8 +33,17 @@ import (
 )
 
 var cfgFile string
+var lang string
 var exportFile string
 var ciConfigFile string
+var dockerfilePath string
+var adviceFile string
+var htmlFile string
+
+// 环境变量的前缀以及从配置项名称到环境变量名称的替换规则
+const envPrefix = "LGM"
+
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
 
 
 // rootCmd 表示在没有任何子命令的情况下调用时的基命令
@@ -63,6 +75,7 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.LGM.yaml)")
+	rootCmd.PersistentFlags().StringVar(&lang, "lang", "", "language of the UI and the progress output: "+strings.Join(i18n.Languages(), " or ")+" (default from LC_ALL, LC_MESSAGES or LANG)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -70,52 +83,56 @@ func init() {
 
 	rootCmd.Flags().StringVarP(&exportFile, "json", "j", "", "Skip the interactive TUI and write the layer analysis statistics to a given file.")
 	rootCmd.Flags().StringVar(&ciConfigFile, "ci-config", ".LGM-ci", "If CI=true in the environment, use the given yaml to drive validation rules.")
+	rootCmd.Flags().StringVar(&dockerfilePath, "dockerfile", "", "Map the instructions of the given Dockerfile (its last stage) to the image layers.")
+	rootCmd.Flags().StringVar(&adviceFile, "advice", "", "Skip the interactive TUI and write Dockerfile optimization suggestions (Markdown) to the given file.")
+	rootCmd.Flags().StringVar(&htmlFile, "html", "", "Skip the interactive TUI and write a self-contained HTML report (layers, file changes, inefficient files and a size treemap) to the given file.")
 
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	if lang == "" {
+		lang = i18n.Detect()
+	}
+	if err := i18n.SetLanguage(lang); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
 	filepathToCfg := getCfgFile(cfgFile)
 	viper.SetConfigFile(filepathToCfg)
 
-	viper.SetDefault("log.level", log.InfoLevel.String())
-	viper.SetDefault("log.path", "./LGM.log")
-	viper.SetDefault("log.enabled", true)
-	// keybindings: status view / global
-	viper.SetDefault("keybinding.quit", "ctrl+c")
-	viper.SetDefault("keybinding.toggle-view", "tab")
-	viper.SetDefault("keybinding.filter-files", "ctrl+f, ctrl+slash")
-	// keybindings: layer view
-	viper.SetDefault("keybinding.compare-all", "ctrl+a")
-	viper.SetDefault("keybinding.compare-layer", "ctrl+l")
-	// keybindings: filetree view
-	viper.SetDefault("keybinding.toggle-collapse-dir", "space")
-	viper.SetDefault("keybinding.toggle-collapse-all-dir", "ctrl+space")
-	viper.SetDefault("keybinding.toggle-filetree-attributes", "ctrl+b")
-	viper.SetDefault("keybinding.toggle-added-files", "ctrl+a")
-	viper.SetDefault("keybinding.toggle-removed-files", "ctrl+r")
-	viper.SetDefault("keybinding.toggle-modified-files", "ctrl+m")
-	viper.SetDefault("keybinding.toggle-unchanged-files", "ctrl+u")
-	viper.SetDefault("keybinding.page-up", "pgup")
-	viper.SetDefault("keybinding.page-down", "pgdn")
-
-	viper.SetDefault("diff.hide", "")
-
-	viper.SetDefault("layer.show-aggregated-changes", false)
-
-	viper.SetDefault("filetree.collapse-dir", false)
-	viper.SetDefault("filetree.pane-width", 0.5)
-	viper.SetDefault("filetree.show-attributes", true)
+	for _, option := range configOptions {
+		viper.SetDefault(option.Key, option.Default)
+	}
 
+	// environment variables override the config file, e.g. LGM_FILETREE_PANE_WIDTH for filetree.pane-width
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(envKeyReplacer)
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	if err := viper.ReadInConfig(); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, "unable to read config file:", err)
+	}
+
+	// the preset only provides defaults, so keys set in the config file still take precedence
+	// (an unknown preset falls back to the default keys and is reported by "LGM config validate")
+	if err := keybinding.ApplyPreset(viper.GetString("keybinding.preset")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	// the pane layout is kept next to the default config file unless configured otherwise
+	if viper.GetString("layout.file") == "" {
+		viper.SetDefault("layout.file", defaultLayoutPath())
 	}
 
 	// set global defaults (for performance)
 	filetree.GlobalFileTreeCollapse = viper.GetBool("filetree.collapse-dir")
+
+	// an invalid theme falls back to the default theme and is reported by "LGM config validate"
+	if err := theme.Apply(viper.GetString("theme.name"), viper.GetString("theme.glyphs"), customThemes()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
 }
 
 // initLogging 使用格式化程序和位置设置日志对象
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"LGM/i18n"
+	"LGM/image"
+	"LGM/runtime"
+	"LGM/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+var (
+	slimRulesFile    string
+	slimOutputFile   string
+	slimTags         []string
+	slimFormat       string
+	exportMarked     bool
+	exportOutputFile string
+	exportTags       []string
+	exportFormat     string
+)
+
+// slimCmd 表示slim命令
+var slimCmd = &cobra.Command{
+	Use:   "slim IMAGE",
+	Short: "Writes a copy of a docker image without the files matched by a rules file.",
+	Long: `Writes a copy of a docker image without the files matched by a rules file.
+
+The rules file is YAML and lists path globs to remove (the same syntax as the
+path: query term, a matching directory is removed with all of its contents):
+
+  remove:
+    - /var/cache/apt/**
+    - /usr/share/doc
+    - "*.pyc"
+
+The layers containing removed files are rewritten, the other layers are kept as
+they are. The new image is written as a docker-archive (--format docker) or as an
+OCI image layout with index.json and blobs/sha256 (--format oci); both can be
+loaded with "docker load -i FILE". It is tagged with the original tags plus
+"-slim" unless --tag is given. For example:
+
+  LGM slim ubuntu:latest --rules slim.yaml -o ubuntu-slim.tar`,
+	Args: cobra.ExactArgs(1),
+	Run:  doSlimCmd,
+}
+
+// exportCmd 表示export命令
+var exportCmd = &cobra.Command{
+	Use:   "export IMAGE",
+	Short: "Writes a copy of a docker image, optionally without the files marked in the file tree.",
+	Long: `Writes a copy of a docker image, optionally without the files marked in the file tree.
+
+Files and directories marked for removal in the file tree are kept in the
+slim.marks-file rules file. With --remove-marked they are removed from the image
+like "LGM slim IMAGE --rules <marks file>" does. The image is written in the
+format given by --format (docker or oci, see "LGM slim --help"). For example:
+
+  LGM export ubuntu:latest --remove-marked -o ubuntu-slim.tar`,
+	Args: cobra.ExactArgs(1),
+	Run:  doExportCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(slimCmd)
+	rootCmd.AddCommand(exportCmd)
+
+	slimCmd.Flags().StringVar(&slimRulesFile, "rules", "slim.yaml", "Rules file listing the paths to remove.")
+	slimCmd.Flags().StringVarP(&slimOutputFile, "output", "o", "slim.tar", "File to write the image archive to.")
+	slimCmd.Flags().StringSliceVar(&slimTags, "tag", nil, "Tags of the new image (default the original tags with a \"-slim\" suffix).")
+	slimCmd.Flags().StringVar(&slimFormat, "format", string(image.DockerArchive), "Format of the image archive: "+strings.Join(image.ArchiveFormats, ", ")+".")
+
+	exportCmd.Flags().BoolVar(&exportMarked, "remove-marked", false, "Remove the files marked for removal in the file tree (slim.marks-file).")
+	exportCmd.Flags().StringVarP(&exportOutputFile, "output", "o", "image.tar", "File to write the image archive to.")
+	exportCmd.Flags().StringSliceVar(&exportTags, "tag", nil, "Tags of the new image (default the original tags, with a \"-slim\" suffix when files are removed).")
+	exportCmd.Flags().StringVar(&exportFormat, "format", string(image.DockerArchive), "Format of the image archive: "+strings.Join(image.ArchiveFormats, ", ")+".")
+}
+
+// doSlimCmd 按规则文件精简给定的镜像
+func doSlimCmd(cmd *cobra.Command, args []string) {
+	defer utils.CleanUp()
+
+	initLogging()
+
+	runtime.Slim(runtime.Options{
+		ImageId:       args[0],
+		RulesFile:     slimRulesFile,
+		OutputFile:    slimOutputFile,
+		Tags:          slimTags,
+		ArchiveFormat: archiveFormat(slimFormat),
+	})
+}
+
+// doExportCmd 导出给定的镜像，可以删除在文件树中标记的文件
+func doExportCmd(cmd *cobra.Command, args []string) {
+	defer utils.CleanUp()
+
+	initLogging()
+
+	options := runtime.Options{
+		ImageId:       args[0],
+		OutputFile:    exportOutputFile,
+		Tags:          exportTags,
+		ArchiveFormat: archiveFormat(exportFormat),
+	}
+	if exportMarked {
+		options.RulesFile = viper.GetString("slim.marks-file")
+		if _, err := os.Stat(options.RulesFile); err != nil {
+			utils.PrintAndExit(i18n.T("no files are marked for removal: %v", err))
+		}
+	}
+	runtime.Slim(options)
+}
+
+// archiveFormat 解析--format的值，无效时退出。
+func archiveFormat(name string) image.ArchiveFormat {
+	format, err := image.ParseArchiveFormat(name)
+	if err != nil {
+		utils.PrintAndExit(i18n.T("invalid argument '%s' for --%s: %v", name, "format", err))
+	}
+	return format
+}
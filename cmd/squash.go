@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"LGM/runtime"
+	"LGM/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	squashFrom       int
+	squashTo         int
+	squashOutputFile string
+	squashTags       []string
+)
+
+// squashCmd 表示squash命令
+var squashCmd = &cobra.Command{
+	Use:   "squash IMAGE",
+	Short: "Shows what merging a range of layers into one would save, optionally writing the squashed image.",
+	Long: `Shows what merging a range of layers into one would save, optionally writing the squashed image.
+
+The layers --from to --to (0 is the base layer, by default every layer above
+the base layer like docker build --squash) are merged into one layer: files
+overwritten or removed by a later layer of the range no longer take space. The
+size of the merged layer, the projected image size and the efficiency score
+before and after are printed. The TUI shows the same for the marked compare
+range in the details pane.
+
+With -o the squashed image is written as a docker-archive that can be loaded
+with "docker load -i FILE", tagged with the original tags plus "-squashed" unless
+--tag is given. For example:
+
+  LGM squash myapp:latest --from 3 --to 6 -o myapp-squashed.tar`,
+	Args: cobra.ExactArgs(1),
+	Run:  doSquashCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(squashCmd)
+
+	squashCmd.Flags().IntVar(&squashFrom, "from", 1, "Index of the first layer to merge (0 is the base layer).")
+	squashCmd.Flags().IntVar(&squashTo, "to", -1, "Index of the last layer to merge (-1 is the last layer).")
+	squashCmd.Flags().StringVarP(&squashOutputFile, "output", "o", "", "File to write the squashed image archive to (by default only the projection is shown).")
+	squashCmd.Flags().StringSliceVar(&squashTags, "tag", nil, "Tags of the new image (default the original tags with a \"-squashed\" suffix).")
+}
+
+// doSquashCmd 预测合并给定镜像的图层的结果，并可以写入合并后的镜像
+func doSquashCmd(cmd *cobra.Command, args []string) {
+	defer utils.CleanUp()
+
+	initLogging()
+
+	runtime.Squash(runtime.Options{
+		ImageId:     args[0],
+		SquashLower: squashFrom,
+		SquashUpper: squashTo,
+		OutputFile:  squashOutputFile,
+		Tags:        squashTags,
+	})
+}
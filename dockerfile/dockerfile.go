@@ -0,0 +1,373 @@
+package dockerfile
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dockerfile 是解析后的Dockerfile：解析器指令以及按源文件顺序排列的所有指令（分为各个构建阶段）
+type Dockerfile struct {
+	Path string
+	// Directives 是文件开头的解析器指令（例如 "# syntax=..."、"# escape=`"），键为小写
+	Directives map[string]string
+	// Escape 是转义字符（默认为 '\'，可以由escape解析器指令修改）
+	Escape rune
+	// Instructions 是所有指令（包括第一个FROM之前的全局ARG）
+	Instructions []*Instruction
+	// Args 是第一个FROM之前的全局ARG，Stages 是各个构建阶段（多阶段构建中的每个FROM）
+	Args   []*Instruction
+	Stages []*Stage
+}
+
+// Stage 是一个构建阶段：一条FROM指令以及其后直到下一条FROM的指令
+type Stage struct {
+	Index int
+	// Name 是 "FROM image AS name" 中的名称（没有时为空）
+	Name string
+	// Base 是基础镜像或之前的阶段的名称（展开了全局ARG的默认值）
+	Base         string
+	From         *Instruction
+	Instructions []*Instruction
+}
+
+// Instruction 是Dockerfile中的一条指令
+type Instruction struct {
+	// Cmd 是大写的指令名称，例如 "RUN"
+	Cmd string
+	// Flags 是指令名称之后的选项（例如 "--from=builder"），Args 是其余的参数（续行已连接）
+	Flags []string
+	Args  string
+	// JSON 是exec形式（JSON数组）的参数，shell形式时为nil
+	JSON     []string
+	Heredocs []Heredoc
+	// Original 是指令在源文件中的原文（包括续行、指令中的注释和heredoc），Line 和 EndLine 是其第一行和最后一行（从1开始）
+	Original string
+	Line     int
+	EndLine  int
+	// Stage 是指令所在的构建阶段（Dockerfile.Stages中的索引），第一个FROM之前的全局ARG为-1
+	Stage int
+	// Comments 是紧接在指令之前的注释行（去掉了 "#" 和空白，空行之前的注释不包括在内）
+	Comments []string
+}
+
+// Heredoc 是RUN、COPY或ADD指令中的一个heredoc（"<<EOF"）
+type Heredoc struct {
+	Name    string
+	Content string
+	// Expand 指示是否展开内容中的变量（分隔符没有引号时），Chomp 指示是否去掉各行开头的制表符（"<<-EOF"）
+	Expand bool
+	Chomp  bool
+}
+
+// instructions 是所有有效的指令名称
+var instructions = map[string]bool{
+	"ADD": true, "ARG": true, "CMD": true, "COPY": true, "ENTRYPOINT": true, "ENV": true, "EXPOSE": true,
+	"FROM": true, "HEALTHCHECK": true, "LABEL": true, "MAINTAINER": true, "ONBUILD": true, "RUN": true,
+	"SHELL": true, "STOPSIGNAL": true, "USER": true, "VOLUME": true, "WORKDIR": true,
+}
+
+// IsInstruction 返回给定的名称（不区分大小写）是否为有效的指令名称。
+func IsInstruction(name string) bool {
+	return instructions[strings.ToUpper(name)]
+}
+
+var (
+	directivePattern = regexp.MustCompile(`^#\s*([a-zA-Z][a-zA-Z0-9]*)\s*=\s*(.*?)\s*$`)
+	heredocPattern   = regexp.MustCompile(`<<(-?)(["']?)([a-zA-Z_][a-zA-Z0-9_]*)(["']?)`)
+)
+
+// ParseFile 解析给定路径的Dockerfile。
+func ParseFile(path string) (*Dockerfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	dockerfile, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	dockerfile.Path = path
+	return dockerfile, nil
+}
+
+// Parse 解析Dockerfile：续行、注释、解析器指令、heredoc以及多阶段构建。错误中包括出错的行号。
+func Parse(reader io.Reader) (*Dockerfile, error) {
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) > 0 {
+		lines[0] = strings.TrimPrefix(lines[0], "\ufeff")
+	}
+
+	dockerfile := &Dockerfile{Directives: make(map[string]string), Escape: '\\'}
+	idx := 0
+	// parser directives are only recognized before anything else, including empty lines and other comments
+	for ; idx < len(lines); idx++ {
+		match := directivePattern.FindStringSubmatch(strings.TrimSpace(lines[idx]))
+		if match == nil {
+			break
+		}
+		key := strings.ToLower(match[1])
+		if _, ok := dockerfile.Directives[key]; ok {
+			return nil, fmt.Errorf("line %d: only one %s parser directive can be used", idx+1, key)
+		}
+		dockerfile.Directives[key] = match[2]
+	}
+	if escape, ok := dockerfile.Directives["escape"]; ok {
+		if escape != "\\" && escape != "`" {
+			return nil, fmt.Errorf("invalid escape parser directive '%s' (must be ` or \\)", escape)
+		}
+		dockerfile.Escape = rune(escape[0])
+	}
+
+	var comments []string
+	for idx < len(lines) {
+		trimmed := strings.TrimSpace(lines[idx])
+		if trimmed == "" {
+			comments = nil
+			idx++
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(trimmed, "#")))
+			idx++
+			continue
+		}
+		instruction, next, err := dockerfile.parseInstruction(lines, idx)
+		if err != nil {
+			return nil, err
+		}
+		instruction.Comments, comments = comments, nil
+		if err := dockerfile.add(instruction); err != nil {
+			return nil, err
+		}
+		idx = next
+	}
+	if len(dockerfile.Stages) == 0 {
+		return nil, fmt.Errorf("no FROM instruction")
+	}
+	return dockerfile, nil
+}
+
+// parseInstruction 解析从第start行（lines中的索引）开始的指令，返回该指令以及其后的第一行。
+func (dockerfile *Dockerfile) parseInstruction(lines []string, start int) (*Instruction, int, error) {
+	var logical strings.Builder
+	idx := start
+	for ; idx < len(lines); idx++ {
+		trimmed := strings.TrimSpace(lines[idx])
+		if idx > start && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+			// comments and empty lines within a continued instruction are dropped
+			continue
+		}
+		line := strings.TrimRightFunc(lines[idx], isSpace)
+		if strings.HasSuffix(line, string(dockerfile.Escape)) {
+			logical.WriteString(strings.TrimSuffix(line, string(dockerfile.Escape)))
+			continue
+		}
+		logical.WriteString(line)
+		break
+	}
+	if idx == len(lines) {
+		// the last line ends with the escape character
+		idx--
+	}
+
+	text := strings.TrimSpace(logical.String())
+	name := text
+	rest := ""
+	if pos := strings.IndexFunc(text, isSpace); pos >= 0 {
+		name, rest = text[:pos], strings.TrimSpace(text[pos:])
+	}
+	instruction := &Instruction{Cmd: strings.ToUpper(name), Line: start + 1, Stage: len(dockerfile.Stages) - 1}
+	if !instructions[instruction.Cmd] {
+		return nil, 0, fmt.Errorf("line %d: unknown instruction: %s", start+1, name)
+	}
+
+	switch instruction.Cmd {
+	case "FROM", "RUN", "COPY", "ADD", "HEALTHCHECK":
+		instruction.Flags, rest = splitFlags(rest)
+	}
+	instruction.Args = rest
+	switch instruction.Cmd {
+	case "RUN", "CMD", "ENTRYPOINT", "SHELL", "COPY", "ADD", "VOLUME":
+		if strings.HasPrefix(rest, "[") {
+			var list []string
+			if err := json.Unmarshal([]byte(rest), &list); err == nil {
+				instruction.JSON = list
+			}
+		}
+	}
+
+	end := idx + 1
+	if instruction.JSON == nil && (instruction.Cmd == "RUN" || instruction.Cmd == "COPY" || instruction.Cmd == "ADD") {
+		for _, match := range heredocPattern.FindAllStringSubmatchIndex(rest, -1) {
+			if match[0] > 0 && rest[match[0]-1] == '<' {
+				// a here-string ("<<<") of the shell
+				continue
+			}
+			heredoc := Heredoc{
+				Name:   rest[match[6]:match[7]],
+				Chomp:  match[3] > match[2],
+				Expand: match[5] == match[4],
+			}
+			var content []string
+			terminated := false
+			for ; end < len(lines); end++ {
+				line := lines[end]
+				if heredoc.Chomp {
+					line = strings.TrimLeft(line, "\t")
+				}
+				if line == heredoc.Name {
+					terminated = true
+					end++
+					break
+				}
+				content = append(content, line)
+			}
+			if !terminated {
+				return nil, 0, fmt.Errorf("line %d: unterminated heredoc %s", start+1, heredoc.Name)
+			}
+			if len(content) > 0 {
+				heredoc.Content = strings.Join(content, "\n") + "\n"
+			}
+			instruction.Heredocs = append(instruction.Heredocs, heredoc)
+		}
+	}
+	instruction.EndLine = end
+	instruction.Original = strings.Join(lines[start:end], "\n")
+	return instruction, end, nil
+}
+
+// add 将指令加入其所在的构建阶段，FROM开始一个新的阶段。
+func (dockerfile *Dockerfile) add(instruction *Instruction) error {
+	dockerfile.Instructions = append(dockerfile.Instructions, instruction)
+	if instruction.Cmd != "FROM" {
+		if len(dockerfile.Stages) == 0 {
+			if instruction.Cmd != "ARG" {
+				return fmt.Errorf("line %d: %s before the first FROM (only ARG is allowed there)", instruction.Line, instruction.Cmd)
+			}
+			dockerfile.Args = append(dockerfile.Args, instruction)
+			return nil
+		}
+		stage := dockerfile.Stages[len(dockerfile.Stages)-1]
+		stage.Instructions = append(stage.Instructions, instruction)
+		return nil
+	}
+
+	fields := strings.Fields(instruction.Args)
+	if len(fields) != 1 && !(len(fields) == 3 && strings.EqualFold(fields[1], "AS")) {
+		return fmt.Errorf("line %d: FROM requires either one or three arguments (FROM image [AS name])", instruction.Line)
+	}
+	stage := &Stage{Index: len(dockerfile.Stages), From: instruction}
+	instruction.Stage = stage.Index
+	stage.Base = Expand(fields[0], dockerfile.GlobalArgs(), dockerfile.Escape)
+	if len(fields) == 3 {
+		stage.Name = strings.ToLower(fields[2])
+	}
+	dockerfile.Stages = append(dockerfile.Stages, stage)
+	return nil
+}
+
+// GlobalArgs 返回全局ARG的默认值（FROM中可以使用，阶段中的 "ARG name" 也使用这些值）。
+func (dockerfile *Dockerfile) GlobalArgs() map[string]string {
+	vars := make(map[string]string)
+	for _, arg := range dockerfile.Args {
+		for _, pair := range arg.Pairs() {
+			vars[pair.Key] = Expand(pair.Value, vars, dockerfile.Escape)
+		}
+	}
+	return vars
+}
+
+// Stage 返回给定名称或索引的构建阶段（为空时返回最后一个阶段），找不到时返回nil。
+func (dockerfile *Dockerfile) Stage(name string) *Stage {
+	if name == "" {
+		return dockerfile.Stages[len(dockerfile.Stages)-1]
+	}
+	for _, stage := range dockerfile.Stages {
+		if stage.Name != "" && stage.Name == strings.ToLower(name) {
+			return stage
+		}
+	}
+	if idx, err := strconv.Atoi(name); err == nil && idx >= 0 && idx < len(dockerfile.Stages) {
+		return dockerfile.Stages[idx]
+	}
+	return nil
+}
+
+// Parent 返回阶段的基础阶段（"FROM builder"），基础为镜像时返回nil。
+func (dockerfile *Dockerfile) Parent(stage *Stage) *Stage {
+	for _, candidate := range dockerfile.Stages[:stage.Index] {
+		if candidate.Name != "" && candidate.Name == strings.ToLower(stage.Base) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// Chain 返回构建给定阶段的镜像所执行的指令（不包括FROM）：基础为之前的阶段时，先是该阶段的指令。
+// 这些指令按顺序出现在镜像的历史记录中（其他阶段只提供COPY --from的文件）。
+func (dockerfile *Dockerfile) Chain(stage *Stage) []*Instruction {
+	var chain []*Instruction
+	if parent := dockerfile.Parent(stage); parent != nil {
+		chain = dockerfile.Chain(parent)
+	}
+	return append(chain, stage.Instructions...)
+}
+
+// Flag 返回指令的给定选项（例如 "from"）的值，没有该选项时返回false。
+func (instruction *Instruction) Flag(name string) (string, bool) {
+	for _, flag := range instruction.Flags {
+		key, value := strings.TrimPrefix(flag, "--"), ""
+		if pos := strings.Index(key, "="); pos >= 0 {
+			key, value = key[:pos], key[pos+1:]
+		}
+		if key == name {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Text 返回单行形式的指令：名称、选项和参数，连续的空白合并为一个空格（不包括heredoc的内容）。
+func (instruction *Instruction) Text() string {
+	words := append([]string{instruction.Cmd}, instruction.Flags...)
+	words = append(words, strings.Fields(instruction.Args)...)
+	return strings.Join(words, " ")
+}
+
+// String 返回指令的原文。
+func (instruction *Instruction) String() string {
+	return instruction.Original
+}
+
+// splitFlags 将参数开头的 "--name=value" 形式的选项与其余的参数分开。
+func splitFlags(args string) ([]string, string) {
+	var flags []string
+	for strings.HasPrefix(args, "--") {
+		end := strings.IndexFunc(args, isSpace)
+		if end < 0 {
+			end = len(args)
+		}
+		flags = append(flags, args[:end])
+		args = strings.TrimSpace(args[end:])
+	}
+	return flags, args
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
@@ -0,0 +1,150 @@
+package dockerfile
+
+import (
+	"strings"
+)
+
+// Pair 是ENV、ARG或LABEL中的一个 "key=value"（ARG可以没有值）
+type Pair struct {
+	Key   string
+	Value string
+	// HasValue 指示是否给出了值（"ARG name" 没有值）
+	HasValue bool
+}
+
+// Pairs 返回ENV、ARG或LABEL指令中的各个键值对（去掉值的引号），也支持旧的 "ENV key value" 形式。
+func (instruction *Instruction) Pairs() []Pair {
+	words := splitWords(instruction.Args)
+	if len(words) == 0 {
+		return nil
+	}
+	if instruction.Cmd == "ENV" && !strings.Contains(words[0], "=") {
+		// the legacy form sets a single variable to the rest of the line
+		value := ""
+		if pos := strings.IndexFunc(strings.TrimSpace(instruction.Args), isSpace); pos >= 0 {
+			value = strings.TrimSpace(strings.TrimSpace(instruction.Args)[pos:])
+		}
+		return []Pair{{Key: words[0], Value: value, HasValue: true}}
+	}
+	pairs := make([]Pair, 0, len(words))
+	for _, word := range words {
+		pair := Pair{Key: unquote(word)}
+		if pos := strings.Index(word, "="); pos >= 0 {
+			pair = Pair{Key: unquote(word[:pos]), Value: unquote(word[pos+1:]), HasValue: true}
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// Expand 展开给定文本中的变量（"$NAME"、"${NAME}"、"${NAME:-default}" 和 "${NAME:+alternative}"），
+// 转义字符之后的 "$" 保留原样，未定义的变量展开为空字符串。
+func Expand(text string, vars map[string]string, escape rune) string {
+	var result strings.Builder
+	runes := []rune(text)
+	for idx := 0; idx < len(runes); idx++ {
+		r := runes[idx]
+		if r == escape && idx+1 < len(runes) && runes[idx+1] == '$' {
+			result.WriteRune('$')
+			idx++
+			continue
+		}
+		if r != '$' || idx+1 >= len(runes) {
+			result.WriteRune(r)
+			continue
+		}
+		if runes[idx+1] == '{' {
+			end := idx + 2
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end == len(runes) {
+				result.WriteString(string(runes[idx:]))
+				break
+			}
+			result.WriteString(expandBraced(string(runes[idx+2:end]), vars, escape))
+			idx = end
+			continue
+		}
+		end := idx + 1
+		for end < len(runes) && isNameRune(runes[end], end == idx+1) {
+			end++
+		}
+		if end == idx+1 {
+			result.WriteRune(r)
+			continue
+		}
+		result.WriteString(vars[string(runes[idx+1:end])])
+		idx = end - 1
+	}
+	return result.String()
+}
+
+// expandBraced 展开 "${...}" 中的内容。
+func expandBraced(expr string, vars map[string]string, escape rune) string {
+	if pos := strings.Index(expr, ":-"); pos >= 0 {
+		if value := vars[expr[:pos]]; value != "" {
+			return value
+		}
+		return Expand(expr[pos+2:], vars, escape)
+	}
+	if pos := strings.Index(expr, ":+"); pos >= 0 {
+		if vars[expr[:pos]] != "" {
+			return Expand(expr[pos+2:], vars, escape)
+		}
+		return ""
+	}
+	return vars[expr]
+}
+
+func isNameRune(r rune, first bool) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (!first && r >= '0' && r <= '9')
+}
+
+// splitWords 按空白分割文本，引号中的空白不分割（引号保留）。
+func splitWords(text string) []string {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+	for _, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case isSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		}
+		word.WriteRune(r)
+		inWord = true
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// unquote 去掉文本中的引号（例如 `"a b"` 或 a"b c"d 中的引号）。
+func unquote(text string) string {
+	var result strings.Builder
+	var quote rune
+	for _, r := range text {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		default:
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
@@ -1,66 +1,134 @@
 package filetree
 
-import "github.com/sirupsen/logrus"
+import (
+	"container/list"
+	"runtime"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// nodeMemoryEstimate 是单个（非共享）FileNode大约占用的字节数，用于估算缓存的内存占用。
+const nodeMemoryEstimate = 320
 
 type TreeCacheKey struct {
 	bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int
 }
 
+// treeCacheEntry 是LRU列表中的一个缓存项
+type treeCacheEntry struct {
+	key   TreeCacheKey
+	tree  *FileTree
+	bytes uint64
+}
+
+// treeCacheCall 表示一次正在进行的树构建，其他请求相同树的调用者等待它完成而不是重复构建。
+type treeCacheCall struct {
+	done chan struct{}
+	tree *FileTree
+}
+
+// TreeCache 按需构建并缓存比较树。缓存以LRU方式淘汰，估算的内存占用不超过给定的上限，可以在多个goroutine之间共享。
+// 注意：返回的树与图层树共享节点，调用者不能修改它们（需要修改时请先Copy()）。
 type TreeCache struct {
 	refTrees []*FileTree
-	cache map[TreeCacheKey]*FileTree
+	maxBytes uint64
+
+	lock     sync.Mutex
+	bytes    uint64
+	lru      *list.List
+	cache    map[TreeCacheKey]*list.Element
+	inflight map[TreeCacheKey]*treeCacheCall
+	workers  chan struct{}
 }
 
-func NewFileTreeCache(refTrees []*FileTree) TreeCache {
-	return TreeCache{
-		refTrees:refTrees,
-		cache:make(map[TreeCacheKey]*FileTree),
+// NewFileTreeCache 为给定的图层树创建一个空缓存，maxBytes为缓存树的估算内存上限（0表示不限制）。
+func NewFileTreeCache(refTrees []*FileTree, maxBytes uint64) *TreeCache {
+	// 预先计算所有节点的路径：图层树的节点在并发构建时会被共享，之后只能被读取
+	for _, tree := range refTrees {
+		err := tree.VisitDepthChildFirst(func(node *FileNode) error {
+			node.Path()
+			return nil
+		}, nil)
+		if err != nil {
+			logrus.Errorf("unable to propagate ref tree: %+v", err)
+		}
+	}
+
+	return &TreeCache{
+		refTrees: refTrees,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		cache:    make(map[TreeCacheKey]*list.Element),
+		inflight: make(map[TreeCacheKey]*treeCacheCall),
+		workers:  make(chan struct{}, runtime.NumCPU()),
 	}
 }
 
+// Get 返回给定层范围的比较树，如果尚未缓存则立即构建（如果其他goroutine正在构建则等待其结果）。
 func (cache *TreeCache) Get(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) *FileTree {
-	key :=  TreeCacheKey{bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop}
-	if value, exists := cache.cache[key]; exists {
-		return value
-	}else {
-		
+	key := TreeCacheKey{bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop}
+
+	cache.lock.Lock()
+	if element, exists := cache.cache[key]; exists {
+		cache.lru.MoveToFront(element)
+		cache.lock.Unlock()
+		return element.Value.(*treeCacheEntry).tree
 	}
-	value := cache.buildTree(key)
-	cache.cache[key] = value
-	return value
+	if call, exists := cache.inflight[key]; exists {
+		cache.lock.Unlock()
+		<-call.done
+		return call.tree
+	}
+	call := &treeCacheCall{done: make(chan struct{})}
+	cache.inflight[key] = call
+	cache.lock.Unlock()
+
+	call.tree = cache.buildTree(key)
+
+	cache.lock.Lock()
+	delete(cache.inflight, key)
+	cache.add(key, call.tree)
+	cache.lock.Unlock()
+	close(call.done)
+
+	return call.tree
 }
 
-func (cache *TreeCache) Build()  {
-	var bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int
-	// case 1: layer compare (top tree SIZE is fixed (BUT floats forward), Bottom tree SIZE changes)
-	for selectIdx := 0; selectIdx < len(cache.refTrees); selectIdx++ {
-		bottomTreeStart = 0
-		topTreeStop = selectIdx
-
-		if selectIdx == 0 {
-			bottomTreeStop = selectIdx
-			topTreeStart = selectIdx
-		} else {
-			bottomTreeStop = selectIdx - 1
-			topTreeStart = selectIdx
-		}
+// Prefetch 在后台goroutine中构建给定层范围的比较树。如果树已缓存、正在构建或所有后台worker都在忙，则不执行任何操作。
+func (cache *TreeCache) Prefetch(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) {
+	key := TreeCacheKey{bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop}
 
-		cache.Get(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop)
+	cache.lock.Lock()
+	_, cached := cache.cache[key]
+	_, building := cache.inflight[key]
+	cache.lock.Unlock()
+	if cached || building {
+		return
 	}
 
-	// case 2: aggregated compare (bottom tree is ENTIRELY fixed, top tree SIZE changes)
-	for selectIdx := 0; selectIdx < len(cache.refTrees); selectIdx++ {
-		bottomTreeStart = 0
-		topTreeStop = selectIdx
-		if selectIdx == 0 {
-			bottomTreeStop = selectIdx
-			topTreeStart = selectIdx
-		} else {
-			bottomTreeStop = 0
-			topTreeStart = 1
-		}
+	select {
+	case cache.workers <- struct{}{}:
+		go func() {
+			defer func() { <-cache.workers }()
+			cache.Get(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop)
+		}()
+	default:
+		// prefetching is best effort, don't queue up work
+	}
+}
+
+// add 将新构建的树放在LRU列表的最前面，并淘汰最久未使用的树直到不超过内存上限。调用者必须持有锁。
+func (cache *TreeCache) add(key TreeCacheKey, tree *FileTree) {
+	entry := &treeCacheEntry{key: key, tree: tree, bytes: uint64(tree.ownedSize()) * nodeMemoryEstimate}
+	cache.cache[key] = cache.lru.PushFront(entry)
+	cache.bytes += entry.bytes
 
-		cache.Get(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop)
+	// always keep the newest tree, even if it alone exceeds the limit
+	for cache.maxBytes > 0 && cache.bytes > cache.maxBytes && cache.lru.Len() > 1 {
+		oldest := cache.lru.Remove(cache.lru.Back()).(*treeCacheEntry)
+		delete(cache.cache, oldest.key)
+		cache.bytes -= oldest.bytes
 	}
 }
 
@@ -74,5 +142,3 @@ func (cache *TreeCache) buildTree(key TreeCacheKey) *FileTree {
 	}
 	return newTree
 }
-
-
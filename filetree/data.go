@@ -2,9 +2,11 @@ package filetree
 
 import (
 	"archive/tar"
+	"fmt"
 	"github.com/cespare/xxhash"
 	"github.com/sirupsen/logrus"
 	"io"
+	"strings"
 )
 
 const (
@@ -14,6 +16,12 @@ const (
 	Removed
 )
 
+const (
+	SortByName SortOrder = iota
+	SortBySize
+	SortByDiffType
+)
+
 var GlobalFileTreeCollapse bool
 
 // NewNodeData 为FileNode创建空的 NodeData 结构
@@ -100,10 +108,48 @@ func (data *FileInfo) Copy() *FileInfo {
 	}
 }
 
+// String 返回DiffType的名称
+func (diff DiffType) String() string {
+	switch diff {
+	case Unchanged:
+		return "unchanged"
+	case Changed:
+		return "modified"
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	}
+	return fmt.Sprintf("DiffType(%d)", int(diff))
+}
+
 // merge 将两个DiffType合并为一个结果。本质上，返回给定值，除非两个值不同，在这种情况下，我们只能确定存在"change".
 func (diff DiffType) merge(other DiffType) DiffType {
 	if diff == other {
 		return diff
 	}
 	return Changed
-}
\ No newline at end of file
+}
+var sortOrderNames = []string{
+	SortByName:     "name",
+	SortBySize:     "size",
+	SortByDiffType: "type",
+}
+
+// String 返回排序方式的名称（即配置文件中使用的名称）。
+func (order SortOrder) String() string {
+	if order < 0 || int(order) >= len(sortOrderNames) {
+		return fmt.Sprintf("SortOrder(%d)", int(order))
+	}
+	return sortOrderNames[order]
+}
+
+// ParseSortOrder 根据名称（name、size或type）返回对应的排序方式。
+func ParseSortOrder(name string) (SortOrder, error) {
+	for order, orderName := range sortOrderNames {
+		if strings.EqualFold(name, orderName) {
+			return SortOrder(order), nil
+		}
+	}
+	return SortByName, fmt.Errorf("unknown sort order: %q", name)
+}
@@ -1,12 +1,11 @@
 package filetree
 
 import (
+	"LGM/theme"
 	"archive/tar"
 	"fmt"
 	"github.com/dustin/go-humanize"
-	"github.com/fatih/color"
 	"github.com/phayes/permbits"
-	"github.com/sirupsen/logrus"
 	"sort"
 	"strings"
 )
@@ -15,11 +14,20 @@ const (
 	AttributeFormat = "%s%s %11s %10s "
 )
 
-var diffTypeColor = map[DiffType]*color.Color{
-	Added:     color.New(color.FgGreen),
-	Removed:   color.New(color.FgRed),
-	Changed:   color.New(color.FgYellow),
-	Unchanged: color.New(color.Reset),
+// diffTypeStyle 是各DiffType的文件名在主题中的样式（满足搜索条件的文件名使用theme.SearchMatch高亮）。
+var diffTypeStyle = map[DiffType]string{
+	Added:     theme.Added,
+	Removed:   theme.Removed,
+	Changed:   theme.Changed,
+	Unchanged: theme.Unchanged,
+}
+
+// diffTypeSortRank 是按变更类型排序时各DiffType的先后顺序（变更的文件在前）。
+var diffTypeSortRank = map[DiffType]int{
+	Added:     0,
+	Changed:   1,
+	Removed:   2,
+	Unchanged: 3,
 }
 
 
@@ -52,9 +60,15 @@ func (node *FileNode) String() string {
 
 	display = node.Name
 	if node.Data.FileInfo.TypeFlag == tar.TypeSymlink || node.Data.FileInfo.TypeFlag == tar.TypeLink {
-		display += " → " + node.Data.FileInfo.LinkName
+		display += theme.Glyphs.Link + node.Data.FileInfo.LinkName
+	}
+	if node.Data.ViewInfo.Matched {
+		return theme.Current.Color(theme.SearchMatch).Sprint(display)
+	}
+	if node.Data.ViewInfo.Marked {
+		return theme.Current.Color(theme.MarkedForRemoval).Sprint(display)
 	}
-	return diffTypeColor[node.Data.DiffType].Sprint(display)
+	return theme.Current.Color(diffTypeStyle[node.Data.DiffType]).Sprint(display)
 }
 
 // renderTreeLine 在更大的ASCII树的上下文中返回表示此FileNode的字符串。
@@ -62,20 +76,20 @@ func (node *FileNode) renderTreeLine(spaces []bool, last bool, collapsed bool) s
 	var otherBranches string
 	for _, space := range spaces {
 		if space {
-			otherBranches += noBranchSpace
+			otherBranches += theme.Glyphs.TreeSpace
 		} else {
-			otherBranches += branchSpace
+			otherBranches += theme.Glyphs.TreeBranch
 		}
 	}
 
-	thisBranch := middleItem
+	thisBranch := theme.Glyphs.TreeMiddle
 	if last {
-		thisBranch = lastItem
+		thisBranch = theme.Glyphs.TreeLast
 	}
 
-	collapsedIndicator := uncollapsedItem
+	collapsedIndicator := theme.Glyphs.TreeExpanded
 	if collapsed {
-		collapsedIndicator = collapsedItem
+		collapsedIndicator = theme.Glyphs.TreeCollapsed
 	}
 
 	return otherBranches + thisBranch + collapsedIndicator + node.String() + newLine
@@ -83,7 +97,11 @@ func (node *FileNode) renderTreeLine(spaces []bool, last bool, collapsed bool) s
 
 // AddChild 创建一个相对于当前FileNode的新节点。
 func (node *FileNode) AddChild(name string, data FileInfo) (child *FileNode)  {
-	// never allow processing of purely whiteout flag files (for now)
+	// the opaque marker only flags its directory, other purely whiteout flag files are not processed (for now)
+	if name == opaqueWhiteout {
+		node.Opaque = true
+		return nil
+	}
 	if strings.HasPrefix(name, doubleWhiteoutPrefix) {
 		return nil
 	}
@@ -91,11 +109,14 @@ func (node *FileNode) AddChild(name string, data FileInfo) (child *FileNode)  {
 	child = NewNode(node, name, data)
 	if node.Children[name] != nil {
 		// tree node already exists, replace the payload, keep the children
-		node.Children[name].Data.FileInfo = *data.Copy()
+		existing := node.ownChild(name)
+		existing.Data.FileInfo = *data.Copy()
+		existing.invalidateSize()
 	} else {
 		//fmt.Printf("In AddChild TreeSize: %d\n", node.Tree.Size)
 		node.Children[name] = child
 		node.Tree.Size++
+		node.invalidateSize()
 	}
 
 	return child
@@ -106,13 +127,119 @@ func (node *FileNode) Copy(parent *FileNode) *FileNode {
 	newNode := NewNode(parent, node.Name, node.Data.FileInfo)
 	newNode.Data.ViewInfo = node.Data.ViewInfo
 	newNode.Data.DiffType = node.Data.DiffType
+	newNode.size = node.size
+	newNode.Opaque = node.Opaque
 	for name, child := range node.Children {
 		newNode.Children[name] = child.Copy(newNode)
-		child.Parent = newNode
 	}
 	return newNode
 }
 
+// shallowCopy 相对于新父节点复制当前节点本身，子节点仍与原节点共享（写时复制）。
+func (node *FileNode) shallowCopy(parent *FileNode) *FileNode {
+	newNode := &FileNode{
+		Tree:     parent.Tree,
+		Parent:   parent,
+		Name:     node.Name,
+		Data:     node.Data,
+		Children: make(map[string]*FileNode, len(node.Children)),
+		path:     node.path,
+		size:     node.size,
+		Opaque:   node.Opaque,
+	}
+	newNode.Data.FileInfo = *node.Data.FileInfo.Copy()
+	for name, child := range node.Children {
+		newNode.Children[name] = child
+	}
+	return newNode
+}
+
+// ownChild 返回属于当前节点所在树的给定子节点。如果子节点仍与其他树共享，则先复制它再替换（写时复制）。
+// 注意：当前节点本身必须已经属于它的树。
+func (node *FileNode) ownChild(name string) *FileNode {
+	child := node.Children[name]
+	if child == nil || child.Tree == node.Tree {
+		return child
+	}
+	child = child.shallowCopy(node)
+	node.Children[name] = child
+	return child
+}
+
+// subtreeSize 返回以当前节点为根的子树中的节点数（包括当前节点）。
+func (node *FileNode) subtreeSize() int {
+	size := 1
+	for _, child := range node.Children {
+		size += child.subtreeSize()
+	}
+	return size
+}
+
+// invalidateSize 使当前节点及其所有祖先节点缓存的累计大小失效。 注意：当前节点必须已经属于它的树。
+func (node *FileNode) invalidateSize() {
+	// 缓存有效的节点的所有子孙节点的缓存也一定有效，因此遇到已失效的节点就可以停止
+	for curNode := node; curNode != nil && curNode.size.valid; curNode = curNode.Parent {
+		curNode.size.valid = false
+	}
+}
+
+// sizes 返回以当前节点为根的子树的累计大小，必要时重新计算并缓存。
+func (node *FileNode) sizes() nodeSize {
+	if node.size.valid {
+		return node.size
+	}
+
+	size := nodeSize{valid: true, all: node.Data.FileInfo.Size}
+	if node.Data.DiffType != Removed {
+		size.live = node.Data.FileInfo.Size
+	}
+	for _, child := range node.Children {
+		childSize := child.sizes()
+		size.all += childSize.all
+		size.live += childSize.live
+	}
+	node.size = size
+	return size
+}
+
+// CumulativeSize 返回当前节点及其所有子节点的文件大小之和。不包括已删除的子项的文件大小（除非当前节点本身是已删除的目录，此时返回已删除文件的累计大小）。
+func (node *FileNode) CumulativeSize() int64 {
+	if node.Data.DiffType == Removed {
+		return node.sizes().all
+	}
+	return node.sizes().live
+}
+
+// sortedChildNames 按给定的顺序返回子节点的名称。无法区分先后的节点按名称排序。
+func (node *FileNode) sortedChildNames(order SortOrder) []string {
+	keys := make([]string, 0, len(node.Children))
+	for key := range node.Children {
+		keys = append(keys, key)
+	}
+
+	switch order {
+	case SortBySize:
+		sort.Slice(keys, func(i, j int) bool {
+			iSize, jSize := node.Children[keys[i]].CumulativeSize(), node.Children[keys[j]].CumulativeSize()
+			if iSize != jSize {
+				return iSize > jSize
+			}
+			return keys[i] < keys[j]
+		})
+	case SortByDiffType:
+		sort.Slice(keys, func(i, j int) bool {
+			iRank, jRank := diffTypeSortRank[node.Children[keys[i]].Data.DiffType], diffTypeSortRank[node.Children[keys[j]].Data.DiffType]
+			if iRank != jRank {
+				return iRank < jRank
+			}
+			return keys[i] < keys[j]
+		})
+	default:
+		sort.Strings(keys)
+	}
+	return keys
+}
+
 // VisitDepthChildFirst 深度优先迭代树（从此FileNode开始），首先评估最深的深度（冒泡访问）
 func (node *FileNode) VisitDepthChildFirst(visitor Visitor, evaluator VisitEvaluator) error {
 	var keys []string
@@ -138,6 +265,11 @@ func (node *FileNode) VisitDepthChildFirst(visitor Visitor, evaluator VisitEvalu
 
 // VisitDepthParentFirst 深度优先迭代树（从此FileNode开始），首先评估最浅的深度（下沉时访问）
 func (node *FileNode) VisitDepthParentFirst(visitor Visitor, evaluator VisitEvaluator) error {
+	return node.visitDepthParentFirst(visitor, evaluator, SortByName)
+}
+
+// visitDepthParentFirst 与VisitDepthParentFirst相同，但按给定的顺序访问同级节点。
+func (node *FileNode) visitDepthParentFirst(visitor Visitor, evaluator VisitEvaluator, order SortOrder) error {
 	var err error
 
 	doVisit := evaluator != nil && evaluator(node) || evaluator == nil
@@ -154,14 +286,9 @@ func (node *FileNode) VisitDepthParentFirst(visitor Visitor, evaluator VisitEval
 		}
 	}
 
-	var keys []string
-	for key := range node.Children {
-		keys = append(keys, key)
-	}
-	sort.Strings(keys)
-	for _, name := range keys {
+	for _, name := range node.sortedChildNames(order) {
 		child := node.Children[name]
-		err = child.VisitDepthParentFirst(visitor, evaluator)
+		err = child.visitDepthParentFirst(visitor, evaluator, order)
 		if err != nil {
 			return err
 		}
@@ -198,11 +325,10 @@ func (node *FileNode) Remove() error {
 	if node == node.Tree.Root {
 		return fmt.Errorf("cannot remove the tree root")
 	}
-	for _, child := range node.Children {
-		child.Remove()
-	}
+	// 子节点可能与其他树共享，因此这里只统计数量而不逐个修改它们
+	node.Tree.Size -= node.subtreeSize()
 	delete(node.Parent.Children, node.Name)
-	node.Tree.Size--
+	node.Parent.invalidateSize()
 	return nil
 }
 
@@ -258,26 +384,7 @@ func (node *FileNode) MetadataString() string {
 	group := node.Data.FileInfo.Gid
 	userGroup := fmt.Sprintf("%d:%d", user, group)
 
-	var sizeBytes int64
-
-	if node.IsLeaf() {
-		sizeBytes = node.Data.FileInfo.Size
-	} else {
-		sizer := func(curNode *FileNode) error {
-			// 不包括已删除的子项的文件大小（除非有问题的节点是已删除的目录，然后显示已删除文件的累计大小）
-			if curNode.Data.DiffType != Removed || node.Data.DiffType == Removed {
-				sizeBytes += curNode.Data.FileInfo.Size
-			}
-			return nil
-		}
-
-		err := node.VisitDepthChildFirst(sizer, nil)
-		if err != nil {
-			logrus.Errorf("unable to propagate node for metadata: %+v", err)
-		}
-	}
-
-	size := humanize.Bytes(uint64(sizeBytes))
+	size := humanize.Bytes(uint64(node.CumulativeSize()))
 
-	return diffTypeColor[node.Data.DiffType].Sprint(fmt.Sprintf(AttributeFormat, dir, fileMode, userGroup, size))
+	return theme.Current.Color(diffTypeStyle[node.Data.DiffType]).Sprint(fmt.Sprintf(AttributeFormat, dir, fileMode, userGroup, size))
 }
\ No newline at end of file
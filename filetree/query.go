@@ -0,0 +1,459 @@
+package filetree
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dustin/go-humanize"
+)
+
+// queryFields 是查询语言支持的所有字段
+var queryFields = []string{"size", "type", "uid", "gid", "diff", "path", "name", "mode"}
+
+// Query 是解析后的文件树查询。查询由空白分隔的若干条件组成，节点必须满足所有条件才匹配，例如：
+//
+//	size>10MB type:symlink uid:0 diff:added path:/usr/lib/** mode:+s
+//
+// 支持的条件：
+//	size OP SIZE   大小比较（OP为 > >= < <= = 或 :），目录使用累计大小，例如 size>=1.5MB
+//	uid OP N       所有者ID比较，例如 uid:0
+//	gid OP N       组ID比较
+//	type:T         文件类型：file、dir、symlink、hardlink、char、block、fifo
+//	diff:D         变更类型：added、removed、modified（changed）、unchanged
+//	path:GLOB      完整路径匹配，*不跨越目录，**可以跨越目录；不以/开头的模式可以匹配任意深度
+//	name:GLOB      文件名匹配
+//	mode:+rwxst    具有（+）或不具有（-）给定的权限位，s为setuid/setgid，t为sticky；也可以是八进制，例如 mode:4755
+//	REGEX          没有字段名的条件是对完整路径的正则表达式匹配
+//
+// 任何条件前加!表示取反，包含空白的值可以用双引号括起来。
+type Query struct {
+	source string
+	terms  []queryTerm
+}
+
+// queryTerm 是查询中的单个条件，field 是条件的字段名（正则表达式条件为空）
+type queryTerm struct {
+	field  string
+	negate bool
+	match  func(node *FileNode) bool
+}
+
+// QueryError 描述查询中无效的条件
+type QueryError struct {
+	Term   string
+	Offset int
+	Reason string
+}
+
+// Error 返回错误描述（列号从1开始）
+func (err *QueryError) Error() string {
+	return fmt.Sprintf("invalid term %q at column %d: %s", err.Term, err.Offset+1, err.Reason)
+}
+
+// queryToken 是查询字符串中的一个（已去除引号的）条件及其位置
+type queryToken struct {
+	text   string
+	offset int
+}
+
+// ParseQuery 解析给定的查询字符串。空查询返回nil（即不过滤任何节点）。
+func ParseQuery(source string) (*Query, error) {
+	tokens, err := tokenizeQuery(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	query := &Query{source: source}
+	for _, token := range tokens {
+		term, reason := parseQueryTerm(token.text)
+		if reason != "" {
+			return nil, &QueryError{Term: token.text, Offset: token.offset, Reason: reason}
+		}
+		query.terms = append(query.terms, term)
+	}
+	return query, nil
+}
+
+// String 返回原始查询字符串
+func (query *Query) String() string {
+	if query == nil {
+		return ""
+	}
+	return query.source
+}
+
+// Match 返回给定节点是否满足查询的所有条件。nil查询匹配所有节点。
+func (query *Query) Match(node *FileNode) bool {
+	if query == nil {
+		return true
+	}
+	for _, term := range query.terms {
+		if term.match(node) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// HasField 返回查询是否包含给定字段的条件（包括取反的条件）。nil查询不包含任何条件。
+func (query *Query) HasField(field string) bool {
+	if query == nil {
+		return false
+	}
+	for _, term := range query.terms {
+		if term.field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenizeQuery 按空白拆分查询字符串，双引号内的空白不拆分。
+func tokenizeQuery(source string) ([]queryToken, error) {
+	var tokens []queryToken
+	var current strings.Builder
+	start, inQuotes, inToken := 0, false, false
+	quoteOffset := 0
+
+	for offset, char := range source {
+		switch {
+		case char == '"':
+			if !inToken {
+				start, inToken = offset, true
+			}
+			inQuotes = !inQuotes
+			quoteOffset = offset
+		case unicode.IsSpace(char) && !inQuotes:
+			if inToken {
+				tokens = append(tokens, queryToken{text: current.String(), offset: start})
+				current.Reset()
+				inToken = false
+			}
+		default:
+			if !inToken {
+				start, inToken = offset, true
+			}
+			current.WriteRune(char)
+		}
+	}
+	if inQuotes {
+		return nil, &QueryError{Term: source[start:], Offset: quoteOffset, Reason: "unterminated quote"}
+	}
+	if inToken {
+		tokens = append(tokens, queryToken{text: current.String(), offset: start})
+	}
+	return tokens, nil
+}
+
+// parseQueryTerm 解析单个条件，出错时返回错误原因。
+func parseQueryTerm(text string) (term queryTerm, reason string) {
+	if strings.HasPrefix(text, "!") {
+		term.negate = true
+		text = text[1:]
+	}
+	if text == "" {
+		return term, "empty term"
+	}
+
+	field, op, value := splitQueryTerm(text)
+	term.field = field
+	switch field {
+	case "":
+		regex, err := regexp.Compile(text)
+		if err != nil {
+			return term, err.Error()
+		}
+		term.match = func(node *FileNode) bool {
+			return regex.MatchString(node.Path())
+		}
+	case "size":
+		size, err := humanize.ParseBytes(value)
+		if err != nil {
+			return term, fmt.Sprintf("invalid size %q", value)
+		}
+		compare, reason := numericComparison(op, int64(size))
+		if reason != "" {
+			return term, reason
+		}
+		term.match = func(node *FileNode) bool {
+			return compare(node.CumulativeSize())
+		}
+	case "uid", "gid":
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return term, fmt.Sprintf("invalid %s %q", field, value)
+		}
+		compare, reason := numericComparison(op, id)
+		if reason != "" {
+			return term, reason
+		}
+		if field == "uid" {
+			term.match = func(node *FileNode) bool {
+				return compare(int64(node.Data.FileInfo.Uid))
+			}
+		} else {
+			term.match = func(node *FileNode) bool {
+				return compare(int64(node.Data.FileInfo.Gid))
+			}
+		}
+	case "type":
+		if op != ":" {
+			return term, "expected type:TYPE"
+		}
+		typeFlag, exists := queryTypes[strings.ToLower(value)]
+		if !exists {
+			return term, fmt.Sprintf("unknown type %q (expected file, dir, symlink, hardlink, char, block or fifo)", value)
+		}
+		term.match = func(node *FileNode) bool {
+			return nodeTypeFlag(node) == typeFlag
+		}
+	case "diff":
+		if op != ":" {
+			return term, "expected diff:TYPE"
+		}
+		diffType, exists := queryDiffTypes[strings.ToLower(value)]
+		if !exists {
+			return term, fmt.Sprintf("unknown diff type %q (expected added, removed, modified or unchanged)", value)
+		}
+		term.match = func(node *FileNode) bool {
+			return node.Data.DiffType == diffType
+		}
+	case "path":
+		if op != ":" {
+			return term, "expected path:GLOB"
+		}
+		regex, err := globToRegexp(value)
+		if err != nil {
+			return term, err.Error()
+		}
+		term.match = func(node *FileNode) bool {
+			return regex.MatchString(node.Path())
+		}
+	case "name":
+		if op != ":" {
+			return term, "expected name:GLOB"
+		}
+		if _, err := path.Match(value, ""); err != nil {
+			return term, fmt.Sprintf("invalid pattern %q", value)
+		}
+		term.match = func(node *FileNode) bool {
+			matched, _ := path.Match(value, strings.TrimPrefix(node.Name, whiteoutPrefix))
+			return matched
+		}
+	case "mode":
+		if op != ":" {
+			return term, "expected mode:+BITS, mode:-BITS or mode:OCTAL"
+		}
+		match, reason := modeMatcher(value)
+		if reason != "" {
+			return term, reason
+		}
+		term.match = match
+	default:
+		return term, fmt.Sprintf("unknown field %q (expected one of %s)", field, strings.Join(queryFields, ", "))
+	}
+	return term, ""
+}
+
+// splitQueryTerm 将条件拆分为字段、运算符和值。没有字段名的条件（正则表达式）返回空字段。
+func splitQueryTerm(text string) (field, op, value string) {
+	idx := strings.IndexFunc(text, func(char rune) bool {
+		return !unicode.IsLetter(char)
+	})
+	if idx <= 0 {
+		return "", "", text
+	}
+
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", ":"} {
+		if strings.HasPrefix(text[idx:], candidate) {
+			field, op, value = strings.ToLower(text[:idx]), candidate, text[idx+len(candidate):]
+			// size:>10MB 等同于 size>10MB
+			if op == ":" {
+				for _, inner := range []string{">=", "<=", ">", "<", "="} {
+					if strings.HasPrefix(value, inner) {
+						op, value = inner, value[len(inner):]
+						break
+					}
+				}
+			}
+			break
+		}
+	}
+
+	// 只有':'可以用于任意字段，其余情况下未知的单词是正则表达式的一部分
+	if field != "" && op != ":" && !isQueryField(field) {
+		return "", "", text
+	}
+	return field, op, value
+}
+
+// isQueryField 返回给定名称是否为支持的字段
+func isQueryField(name string) bool {
+	for _, field := range queryFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// numericComparison 返回与给定值按运算符比较的函数
+func numericComparison(op string, value int64) (func(int64) bool, string) {
+	switch op {
+	case ">":
+		return func(actual int64) bool { return actual > value }, ""
+	case ">=":
+		return func(actual int64) bool { return actual >= value }, ""
+	case "<":
+		return func(actual int64) bool { return actual < value }, ""
+	case "<=":
+		return func(actual int64) bool { return actual <= value }, ""
+	case "=", ":":
+		return func(actual int64) bool { return actual == value }, ""
+	}
+	return nil, fmt.Sprintf("unknown operator %q", op)
+}
+
+var queryTypes = map[string]byte{
+	"file":     tar.TypeReg,
+	"f":        tar.TypeReg,
+	"dir":      tar.TypeDir,
+	"d":        tar.TypeDir,
+	"symlink":  tar.TypeSymlink,
+	"l":        tar.TypeSymlink,
+	"hardlink": tar.TypeLink,
+	"h":        tar.TypeLink,
+	"char":     tar.TypeChar,
+	"c":        tar.TypeChar,
+	"block":    tar.TypeBlock,
+	"b":        tar.TypeBlock,
+	"fifo":     tar.TypeFifo,
+	"p":        tar.TypeFifo,
+}
+
+var queryDiffTypes = map[string]DiffType{
+	"added":     Added,
+	"removed":   Removed,
+	"modified":  Changed,
+	"changed":   Changed,
+	"unchanged": Unchanged,
+}
+
+// nodeTypeFlag 返回节点的tar类型。没有tar条目的中间目录也视为目录。
+func nodeTypeFlag(node *FileNode) byte {
+	switch {
+	case node.Data.FileInfo.IsDir || node.Data.FileInfo.TypeFlag == tar.TypeDir || len(node.Children) > 0:
+		return tar.TypeDir
+	case node.Data.FileInfo.TypeFlag == tar.TypeRegA:
+		return tar.TypeReg
+	}
+	return node.Data.FileInfo.TypeFlag
+}
+
+// modeMatcher 解析mode条件的值
+func modeMatcher(value string) (func(node *FileNode) bool, string) {
+	if value == "" {
+		return nil, "empty mode"
+	}
+
+	if value[0] == '+' || value[0] == '-' {
+		var mask os.FileMode
+		for _, bit := range value[1:] {
+			switch bit {
+			case 'r':
+				mask |= 0444
+			case 'w':
+				mask |= 0222
+			case 'x':
+				mask |= 0111
+			case 's':
+				mask |= os.ModeSetuid | os.ModeSetgid
+			case 't':
+				mask |= os.ModeSticky
+			default:
+				return nil, fmt.Sprintf("unknown permission bit %q (expected r, w, x, s or t)", bit)
+			}
+		}
+		if mask == 0 {
+			return nil, "no permission bits given"
+		}
+		want := value[0] == '+'
+		return func(node *FileNode) bool {
+			return (node.Data.FileInfo.Mode&mask != 0) == want
+		}, ""
+	}
+
+	octal, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || octal > 07777 {
+		return nil, fmt.Sprintf("invalid mode %q", value)
+	}
+	return func(node *FileNode) bool {
+		return unixMode(node.Data.FileInfo.Mode) == uint32(octal)
+	}, ""
+}
+
+// unixMode 将os.FileMode转换为unix风格的八进制权限（包括setuid、setgid和sticky位）
+func unixMode(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return bits
+}
+
+// globToRegexp 将路径通配模式转换为正则表达式：*和?不跨越目录，**可以跨越目录，以/**结尾的模式也匹配目录本身。
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	if glob == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !strings.HasPrefix(glob, "/") {
+		expr.WriteString("(.*/)?")
+	}
+
+	pattern := glob
+	trailingAny := strings.HasSuffix(pattern, "/**")
+	if trailingAny {
+		pattern = strings.TrimSuffix(pattern, "/**")
+	}
+
+	runes := []rune(pattern)
+	for idx := 0; idx < len(runes); idx++ {
+		switch char := runes[idx]; char {
+		case '*':
+			if idx+1 < len(runes) && runes[idx+1] == '*' {
+				expr.WriteString(".*")
+				idx++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	if trailingAny {
+		expr.WriteString("(/.*)?")
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
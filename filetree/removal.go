@@ -0,0 +1,69 @@
+package filetree
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// RemovalRules 是从镜像中删除文件的规则：每条规则是一个路径通配模式（语法与查询的path:相同），与规则匹配的目录连同其所有内容一起删除。
+type RemovalRules struct {
+	patterns []string
+	regexps  []*regexp.Regexp
+}
+
+// NewRemovalRules 编译给定的路径通配模式。
+func NewRemovalRules(patterns []string) (*RemovalRules, error) {
+	rules := &RemovalRules{}
+	for _, pattern := range patterns {
+		if err := rules.add(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// add 添加一条规则。
+func (rules *RemovalRules) add(pattern string) error {
+	regex, err := globToRegexp(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	rules.patterns = append(rules.patterns, pattern)
+	rules.regexps = append(rules.regexps, regex)
+	return nil
+}
+
+// Patterns 按添加的顺序返回所有规则。
+func (rules *RemovalRules) Patterns() []string {
+	return append([]string(nil), rules.patterns...)
+}
+
+// Len 返回规则的数量。
+func (rules *RemovalRules) Len() int {
+	return len(rules.patterns)
+}
+
+// Matches 指示给定的路径（例如 "/var/cache/apt"）是否被删除，即路径本身或其所在的某个目录与规则匹配。
+func (rules *RemovalRules) Matches(filePath string) bool {
+	for current := path.Clean("/" + filePath); current != "/"; current = path.Dir(current) {
+		for _, regex := range rules.regexps {
+			if regex.MatchString(current) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Toggle 在规则中添加或去掉给定的路径（作为只匹配该路径的规则），返回路径是否已被添加。
+func (rules *RemovalRules) Toggle(filePath string) (bool, error) {
+	for idx, pattern := range rules.patterns {
+		if pattern == filePath {
+			rules.patterns = append(rules.patterns[:idx], rules.patterns[idx+1:]...)
+			rules.regexps = append(rules.regexps[:idx], rules.regexps[idx+1:]...)
+			return false, nil
+		}
+	}
+	return true, rules.add(filePath)
+}
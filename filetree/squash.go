@@ -0,0 +1,172 @@
+package filetree
+
+import (
+	"archive/tar"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SquashedLayer 是将连续的几层合并为一层的结果（与 docker build --squash 相同）
+type SquashedLayer struct {
+	// Tree 是合并后的层的文件树：被之后的层覆盖或删除的文件不再出现，只保留删除更低的层中的文件所需的whiteout
+	Tree *FileTree
+	// Lower 和 Upper 是合并的第一层和最后一层
+	Lower int
+	Upper int
+	// layers 记录合并时每个节点的条目来自哪一层，sources 是合并完成后按路径（whiteout保留其名称）整理的结果
+	layers  map[*FileNode]int
+	sources map[string]int
+}
+
+// SquashTrees 将 trees[lower..upper] 合并为一层。
+func SquashTrees(trees []*FileTree, lower, upper int) (*SquashedLayer, error) {
+	if lower < 0 || upper >= len(trees) || lower > upper {
+		return nil, fmt.Errorf("invalid layer range %d-%d (the image has %d layers)", lower, upper, len(trees))
+	}
+	squashed := &SquashedLayer{
+		Tree:    NewFileTree(),
+		Lower:   lower,
+		Upper:   upper,
+		layers:  make(map[*FileNode]int),
+		sources: make(map[string]int),
+	}
+	squashed.Tree.Name = fmt.Sprintf("squashed %d-%d", lower, upper)
+	for name, child := range trees[lower].Root.Children {
+		squashed.Tree.Root.Children[name] = squashed.copy(child, squashed.Tree.Root, lower)
+	}
+	for idx := lower + 1; idx <= upper; idx++ {
+		squashed.merge(squashed.Tree.Root, trees[idx].Root, idx)
+	}
+	squashed.prune(trees, squashed.Tree.Root)
+
+	squashed.Tree.Size = squashed.Tree.Root.subtreeSize() - 1
+	squashed.Tree.FileSize = 0
+	err := squashed.Tree.VisitDepthChildFirst(func(node *FileNode) error {
+		if node == squashed.Tree.Root {
+			return nil
+		}
+		if !node.IsWhiteout() {
+			squashed.Tree.FileSize += uint64(node.Data.FileInfo.Size)
+		}
+		if layerIdx, ok := squashed.layers[node]; ok {
+			squashed.sources[entryPath(node)] = layerIdx
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	squashed.layers = nil
+
+	// a hard link that loses its target becomes a copy of the contents the target had in the link's layer
+	err = squashed.Tree.VisitDepthChildFirst(func(node *FileNode) error {
+		info := node.Data.FileInfo
+		if info.TypeFlag != tar.TypeLink {
+			return nil
+		}
+		layerIdx := squashed.Source(node.Path())
+		target := path.Clean("/" + info.LinkName)
+		if layerIdx < 0 || squashed.Source(target) == layerIdx {
+			return nil
+		}
+		if targetNode, err := trees[layerIdx].GetNode(target); err == nil {
+			squashed.Tree.FileSize += uint64(targetNode.Data.FileInfo.Size)
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return squashed, nil
+}
+
+// Source 返回合并后的层中给定路径（tar中的条目路径，whiteout包括其 ".wh." 前缀）的条目来自哪一层，该条目不在合并后的层中时返回-1。
+func (squashed *SquashedLayer) Source(entry string) int {
+	if layerIdx, ok := squashed.sources[path.Clean("/"+entry)]; ok {
+		return layerIdx
+	}
+	return -1
+}
+
+// copy 相对于新父节点复制给定层中的节点及其子树，并记录它们来自该层。
+func (squashed *SquashedLayer) copy(node, parent *FileNode, layerIdx int) *FileNode {
+	newNode := NewNode(parent, node.Name, node.Data.FileInfo)
+	newNode.Opaque = node.Opaque
+	if node.Data.FileInfo.TypeFlag != 0 {
+		squashed.layers[newNode] = layerIdx
+	}
+	for name, child := range node.Children {
+		newNode.Children[name] = squashed.copy(child, newNode, layerIdx)
+	}
+	return newNode
+}
+
+// merge 将上层节点的子节点合并到当前节点下：上层的条目替换已有的条目，whiteout删除已有的路径，不透明的目录清空已有的内容。
+func (squashed *SquashedLayer) merge(node, upper *FileNode, layerIdx int) {
+	for name, upperChild := range upper.Children {
+		if upperChild.IsWhiteout() {
+			delete(node.Children, strings.TrimPrefix(name, whiteoutPrefix))
+			node.Children[name] = squashed.copy(upperChild, node, layerIdx)
+			continue
+		}
+
+		lowerChild := node.Children[name]
+		_, removed := node.Children[whiteoutPrefix+name]
+		delete(node.Children, whiteoutPrefix+name)
+		if lowerChild == nil || !lowerChild.isDir() || !upperChild.isDir() {
+			child := squashed.copy(upperChild, node, layerIdx)
+			// a directory taking the place of a removed path must hide what the layers below have there
+			if child.isDir() && (removed || lowerChild != nil) {
+				child.Opaque = true
+			}
+			node.Children[name] = child
+			continue
+		}
+
+		// a parent directory without an entry of its own keeps the entry of the lower layer
+		if upperChild.Data.FileInfo.TypeFlag != 0 {
+			lowerChild.Data.FileInfo = *upperChild.Data.FileInfo.Copy()
+			squashed.layers[lowerChild] = layerIdx
+		}
+		if upperChild.Opaque {
+			lowerChild.Children = make(map[string]*FileNode)
+			lowerChild.Opaque = true
+		}
+		squashed.merge(lowerChild, upperChild, layerIdx)
+	}
+}
+
+// prune 删除不再需要的whiteout（要删除的路径在合并的层之下不存在）和没有内容的父目录，并清除不需要的不透明标记。
+func (squashed *SquashedLayer) prune(trees []*FileTree, node *FileNode) {
+	existsBelow := func(node *FileNode) bool {
+		return squashed.Lower > 0 && LayerOf(trees, node.Path(), squashed.Lower-1) >= 0
+	}
+	for name, child := range node.Children {
+		squashed.prune(trees, child)
+		switch {
+		case child.IsWhiteout() && !existsBelow(child):
+			delete(node.Children, name)
+		case child.Data.FileInfo.TypeFlag == 0 && len(child.Children) == 0 && !(child.Opaque && existsBelow(child)):
+			// a parent directory of removed whiteouts only
+			delete(node.Children, name)
+		case child.Opaque && !existsBelow(child):
+			child.Opaque = false
+		}
+	}
+	node.size.valid = false
+}
+
+// isDir 返回节点是否为目录（包括在层中没有自己的条目的父目录）。
+func (node *FileNode) isDir() bool {
+	info := node.Data.FileInfo
+	return info.IsDir || info.TypeFlag == tar.TypeDir || (info.TypeFlag == 0 && len(node.Children) > 0)
+}
+
+// entryPath 返回节点在层tar中的条目路径，与Path()不同，whiteout保留其 ".wh." 前缀。
+func entryPath(node *FileNode) string {
+	if node.IsWhiteout() {
+		return path.Join(node.Parent.Path(), node.Name)
+	}
+	return node.Path()
+}
@@ -10,14 +10,9 @@ import (
 
 const (
 	newLine              = "\n"
-	noBranchSpace        = "    "
-	branchSpace          = "│   "
-	middleItem           = "├─"
-	lastItem             = "└─"
 	whiteoutPrefix       = ".wh."
 	doubleWhiteoutPrefix = ".wh..wh.."
-	uncollapsedItem      = "─ "
-	collapsedItem        = "⊕ "
+	opaqueWhiteout       = doubleWhiteoutPrefix + "opq"
 )
 
 // NewFileTree 创建一个空的FileTree
@@ -42,7 +37,7 @@ func (tree *FileTree) AddPath(path string, data FileInfo) (*FileNode, []*FileNod
 		}
 		// find or create node
 		if node.Children[name] != nil {
-			node = node.Children[name]
+			node = node.ownChild(name)
 		} else {
 			// 不要附加有效载荷。 有效负载的目的地是Path的终端节点，而不是任何中间节点。
 			node = node.AddChild(name, FileInfo{})
@@ -57,6 +52,7 @@ func (tree *FileTree) AddPath(path string, data FileInfo) (*FileNode, []*FileNod
 		// attach payload to the last specified node
 		if idx == len(nodeNames)-1 {
 			node.Data.FileInfo = data
+			node.invalidateSize()
 		}
 
 	}
@@ -80,9 +76,9 @@ func (tree *FileTree) VisitDepthChildFirst(visitor Visitor, evaluator VisitEvalu
 	return tree.Root.VisitDepthChildFirst(visitor, evaluator)
 }
 
-// VisitDepthParentFirst 深度优先迭代给定的树，首先评估最浅的深度（下沉时访问）
+// VisitDepthParentFirst 深度优先迭代给定的树，首先评估最浅的深度（下沉时访问）。同级节点按树的SortOrder访问，与渲染的顺序一致。
 func (tree *FileTree) VisitDepthParentFirst(visitor Visitor, evaluator VisitEvaluator) error {
-	return tree.Root.VisitDepthParentFirst(visitor, evaluator)
+	return tree.Root.visitDepthParentFirst(visitor, evaluator, tree.SortOrder)
 }
 
 // StringBetween 以ASCII表示形式返回部分树。
@@ -112,13 +108,8 @@ func (tree *FileTree) renderStringTreeBetween(startRow, stopRow int, showAttribu
 		var currentParams renderParams
 		currentParams, paramsToVisit = paramsToVisit[0], paramsToVisit[1:]
 
-		// 记下稍后要访问的下一个node
-		var keys []string
-		for key := range currentParams.node.Children {
-			keys = append(keys, key)
-		}
-		// 按顺序访问nodes
-		sort.Strings(keys)
+		// 记下稍后要访问的下一个node，按顺序访问nodes
+		keys := currentParams.node.sortedChildNames(tree.SortOrder)
 
 		var childParams = make([]renderParams, 0)
 		for idx, name := range keys {
@@ -194,11 +185,28 @@ func (tree *FileTree) VisibleSize() int {
 	return size
 }
 
+// ownedSize 返回只属于此树（未与其他树共享）的节点数，即此树实际占用的节点数量。
+func (tree *FileTree) ownedSize() int {
+	var size int
+	var visit func(node *FileNode)
+	visit = func(node *FileNode) {
+		size++
+		for _, child := range node.Children {
+			if child.Tree == tree {
+				visit(child)
+			}
+		}
+	}
+	visit(tree.Root)
+	return size
+}
+
 // Copy 返回给定文件树的副本
 func (tree *FileTree) Copy() *FileTree {
 	newTree := NewFileTree()
 	newTree.Size = tree.Size
 	newTree.FileSize = tree.FileSize
+	newTree.SortOrder = tree.SortOrder
 	newTree.Root = tree.Root.Copy(newTree.Root)
 
 	// update the tree pointers
@@ -214,28 +222,79 @@ func (tree *FileTree) Copy() *FileTree {
 	return newTree
 }
 
+// Fork 返回给定文件树的写时复制副本：新树只拥有自己的根节点，其余节点与原树共享，直到被修改时才复制。
+// 注意：修改返回的树中的视图状态时必须使用SetViewInfo，不能直接修改节点的ViewInfo（节点可能与其他树共享）。
+func (tree *FileTree) Fork() *FileTree {
+	newTree := NewFileTree()
+	newTree.Size = tree.Size
+	newTree.FileSize = tree.FileSize
+	newTree.SortOrder = tree.SortOrder
+	for name, child := range tree.Root.Children {
+		newTree.Root.Children[name] = child
+	}
+	return newTree
+}
+
 // Stack 将两棵树合并在一起。这是通过将给定的树“堆叠”到所属树的顶部来完成的。
+// 下层不存在的子树会直接与上层树共享，而不是逐个节点复制。
 func (tree *FileTree) Stack(upper *FileTree) error {
-	graft := func(node *FileNode) error{
-		if node.IsWhiteout() {
-			err := tree.RemovePath(node.Path())
-			if err != nil {
-				return fmt.Errorf("cannot remove node %s: %v", node.Path(), err.Error())
+	return tree.Root.stack(upper.Root)
+}
+
+// stack 将给定（上层）节点的子节点合并到当前节点下。当前节点必须已经属于它的树。
+func (node *FileNode) stack(upper *FileNode) error {
+	var keys []string
+	for key := range upper.Children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		upperChild := upper.Children[name]
+		if upperChild.IsWhiteout() {
+			lowerName := strings.TrimPrefix(name, whiteoutPrefix)
+			lowerChild := node.Children[lowerName]
+			if lowerChild == nil {
+				return fmt.Errorf("cannot remove node %s: path does not exist", upperChild.Path())
 			}
-		} else {
-			newNode, _, err := tree.AddPath(node.Path(), node.Data.FileInfo)
-			if err != nil {
-				return fmt.Errorf("cannot add node %s: %v", newNode.Path(), err.Error())
+			node.Tree.Size -= lowerChild.subtreeSize()
+			delete(node.Children, lowerName)
+			node.invalidateSize()
+			continue
+		}
+
+		lowerChild := node.Children[name]
+		if lowerChild == upperChild {
+			// already shared with the upper tree, nothing to merge
+			continue
+		}
+		if lowerChild == nil {
+			node.Children[name] = upperChild
+			node.Tree.Size += upperChild.subtreeSize()
+			node.invalidateSize()
+			continue
+		}
+
+		lowerChild = node.ownChild(name)
+		if upperChild.Opaque {
+			// an opaque directory hides everything below it
+			for lowerName, child := range lowerChild.Children {
+				node.Tree.Size -= child.subtreeSize()
+				delete(lowerChild.Children, lowerName)
 			}
 		}
-		return nil
+		lowerChild.Data.FileInfo = *upperChild.Data.FileInfo.Copy()
+		lowerChild.invalidateSize()
+		err := lowerChild.stack(upperChild)
+		if err != nil {
+			return err
+		}
 	}
-	return upper.VisitDepthChildFirst(graft, nil)
+	return nil
 }
 
-// StackTreeRange 将一系列树组合成一棵树
+// StackTreeRange 将一系列树组合成一棵树。结果与给定的树共享未修改的子树。
 func StackTreeRange(trees []*FileTree, start, stop int) *FileTree {
-	tree := trees[0].Copy()
+	tree := trees[0].Fork()
 	for idx := start; idx <= stop; idx++ {
 		err := tree.Stack(trees[idx])
 		if err != nil {
@@ -245,9 +304,35 @@ func StackTreeRange(trees []*FileTree, start, stop int) *FileTree {
 	return tree
 }
 
+// LayerOf 返回将 trees[0..stop] 叠加后给定路径的节点来自哪一层（路径本身在该层中），路径不存在或已被删除（包括被不透明的目录隐藏）时返回-1。
+func LayerOf(trees []*FileTree, path string, stop int) int {
+	nodeNames := strings.Split(strings.Trim(path, "/"), "/")
+	for idx := stop; idx >= 0; idx-- {
+		node := trees[idx].Root
+		for _, name := range nodeNames {
+			// a whiteout of the path or of one of its parents removes it from the layers below
+			if node.Children[whiteoutPrefix+name] != nil {
+				return -1
+			}
+			parent := node
+			node = node.Children[name]
+			if node == nil {
+				if parent.Opaque {
+					return -1
+				}
+				break
+			}
+		}
+		if node != nil {
+			return idx
+		}
+	}
+	return -1
+}
+
 // RemovePath 在给定其路径的情况下从树中删除节点。
 func (tree *FileTree) RemovePath(path string) error {
-	node, err := tree.GetNode(path)
+	node, err := tree.getOwnedNode(path)
 	if err != nil {
 		return err
 	}
@@ -270,6 +355,84 @@ func (tree *FileTree) GetNode(path string) (*FileNode, error) {
 	return node, nil
 }
 
+// SetViewInfo 设置树中与给定节点路径相同的节点的视图状态。与其他树共享的节点（及其祖先）只在视图状态改变时才复制（写时复制），
+// 因此共享节点的其他树（例如缓存中的比较树）不受影响。
+func (tree *FileTree) SetViewInfo(node *FileNode, info ViewInfo) error {
+	path := node.Path()
+	current, err := tree.GetNode(path)
+	if err != nil {
+		return err
+	}
+	if current.Data.ViewInfo == info {
+		return nil
+	}
+	current, err = tree.getOwnedNode(path)
+	if err != nil {
+		return err
+	}
+	current.Data.ViewInfo = info
+	return nil
+}
+
+// CopyViewInfo 将给定树中的视图状态复制到当前树中路径相同的节点（当前树中不存在的路径被忽略）。
+// 只访问from自己拥有的节点：视图状态只能通过SetViewInfo修改，共享的节点的视图状态不会被修改过。
+func (tree *FileTree) CopyViewInfo(from *FileTree) {
+	var visit func(node *FileNode)
+	visit = func(node *FileNode) {
+		if node != from.Root {
+			// the path may not exist in this tree
+			tree.SetViewInfo(node, node.Data.ViewInfo)
+		}
+		for _, child := range node.Children {
+			if child.Tree == from {
+				visit(child)
+			}
+		}
+	}
+	visit(from.Root)
+}
+
+// VisitVisible 按渲染的顺序（见StringBetween）访问可见的节点：没有隐藏并且父目录没有折叠的节点。
+// 与使用Parent判断父目录的访问不同，它也适用于与其他树共享节点的树。
+func (tree *FileTree) VisitVisible(visitor Visitor) error {
+	return tree.Root.visitVisible(visitor, tree.SortOrder)
+}
+
+func (node *FileNode) visitVisible(visitor Visitor, order SortOrder) error {
+	if node.Data.ViewInfo.Collapsed {
+		return nil
+	}
+	for _, name := range node.sortedChildNames(order) {
+		child := node.Children[name]
+		if child.Data.ViewInfo.Hidden {
+			continue
+		}
+		if err := visitor(child); err != nil {
+			return err
+		}
+		if err := child.visitVisible(visitor, order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getOwnedNode 与GetNode相同，但会复制路径上仍与其他树共享的节点，使返回的节点可以安全地修改。
+func (tree *FileTree) getOwnedNode(path string) (*FileNode, error) {
+	nodeNames := strings.Split(strings.Trim(path, "/"), "/")
+	node := tree.Root
+	for _, name := range nodeNames {
+		if name == "" {
+			continue
+		}
+		if node.Children[name] == nil {
+			return nil, fmt.Errorf("path does not exist: %s", path)
+		}
+		node = node.ownChild(name)
+	}
+	return node, nil
+}
+
 type compareMark struct {
 	lowerNode *FileNode
 	upperNode *FileNode
@@ -293,6 +456,14 @@ func (tree *FileTree) CompareAndMark(upper *FileTree) error {
 			}
 			return nil
 		}
+		if upperNode.Opaque {
+			if lowerNode, _ := originalTree.GetNode(upperNode.Path()); lowerNode != nil {
+				err := tree.markHidden(lowerNode, upperNode)
+				if err != nil {
+					return err
+				}
+			}
+		}
 
 		// 注意：由于我们没有与原始树进行比较（复制树很昂贵），我们可能会错误地将添加节点的父节点标记为已修改。 这将在以后更正。
 		originalLowerNode, _ := originalTree.GetNode(upperNode.Path())
@@ -310,7 +481,7 @@ func (tree *FileTree) CompareAndMark(upper *FileTree) error {
 		}
 
 		// 该文件存在于较低layer
-		lowerNode, _ := tree.GetNode(upperNode.Path())
+		lowerNode, _ := tree.getOwnedNode(upperNode.Path())
 		diffType := lowerNode.compare(upperNode)
 		modifications = append(modifications, compareMark{lowerNode: lowerNode, upperNode: upperNode, tentative: diffType, final: -1})
 
@@ -332,19 +503,37 @@ func (tree *FileTree) CompareAndMark(upper *FileTree) error {
 
 		// 在拥有的树上保持上层的有效负载
 		pair.lowerNode.Data.FileInfo = *pair.upperNode.Data.FileInfo.Copy()
+		pair.lowerNode.invalidateSize()
 	}
 	return nil
 }
 
 // markRemoved 将给定路径处的filenode注释为已删除。
 func (tree *FileTree) markRemoved(path string) error {
-	node, err := tree.GetNode(path)
+	node, err := tree.getOwnedNode(path)
 	if err != nil {
 		return err
 	}
 	return node.AssignDiffType(Removed)
 }
 
+// markHidden 将下层目录中不在上层（不透明的）目录中的节点注释为已删除，两者都有的子目录递归处理。
+func (tree *FileTree) markHidden(lowerNode, upperNode *FileNode) error {
+	for name, child := range lowerNode.Children {
+		upperChild := upperNode.Children[name]
+		if upperChild != nil {
+			if err := tree.markHidden(child, upperChild); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tree.markRemoved(child.Path()); err != nil {
+			return fmt.Errorf("cannot remove node %s: %v", child.Path(), err.Error())
+		}
+	}
+	return nil
+}
+
 // deriveDiffType 确定当前FileNode的DiffType。 注意：节点的DiffType始终是其属性及其内容的DiffType。 内容是目录子项的文件的字节。
 func (node *FileNode) deriveDiffType(diffType DiffType) error{
 	if node.IsLeaf() {
@@ -364,11 +553,12 @@ func (node *FileNode) AssignDiffType(diffType DiffType) error {
 	var err error
 
 	node.Data.DiffType = diffType
+	node.invalidateSize()
 
 	if diffType == Removed {
 		// if we've removed this node, then all children have been removed as well
-		for _, child := range node.Children {
-			err = child.AssignDiffType(diffType)
+		for name := range node.Children {
+			err = node.ownChild(name).AssignDiffType(diffType)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,118 @@
+package filetree
+
+import (
+	"archive/tar"
+	"fmt"
+	"testing"
+)
+
+const (
+	benchFileCount  = 500000
+	benchLayerCount = 10
+)
+
+var benchTrees []*FileTree
+
+// syntheticImage 构造一个约有50万个文件的合成镜像：基础层包含大部分文件，
+// 之后的每一层都会新增一个目录、覆盖下层的一部分文件并删除（whiteout）另一部分文件。
+func syntheticImage() []*FileTree {
+	if benchTrees != nil {
+		return benchTrees
+	}
+
+	baseFiles := benchFileCount / 2
+	layerFiles := (benchFileCount - baseFiles) / (benchLayerCount - 1)
+
+	addFile := func(tree *FileTree, path string, size int64) {
+		tree.FileSize += uint64(size)
+		tree.AddPath(path, FileInfo{Path: path, TypeFlag: tar.TypeReg, Size: size, hash: uint64(len(path)) + uint64(size)})
+	}
+
+	trees := make([]*FileTree, 0, benchLayerCount)
+	base := NewFileTree()
+	for idx := 0; idx < baseFiles; idx++ {
+		addFile(base, fmt.Sprintf("/usr/lib/pkg%d/dir%d/file%d", idx%500, idx%50, idx), int64(idx%4096))
+	}
+	trees = append(trees, base)
+
+	for layerIdx := 1; layerIdx < benchLayerCount; layerIdx++ {
+		tree := NewFileTree()
+		for idx := 0; idx < layerFiles; idx++ {
+			switch {
+			case idx%10 == 0:
+				// overwrite a file from the base layer
+				addFile(tree, fmt.Sprintf("/usr/lib/pkg%d/dir%d/file%d", idx%500, idx%50, idx), int64(layerIdx))
+			case idx%10 == 1 && layerIdx%2 == 0:
+				// remove a file from the base layer (a different one for every layer)
+				removeIdx := (idx + layerIdx*layerFiles) % baseFiles
+				addFile(tree, fmt.Sprintf("/usr/lib/pkg%d/dir%d/.wh.file%d", removeIdx%500, removeIdx%50, removeIdx), 0)
+			default:
+				addFile(tree, fmt.Sprintf("/opt/layer%d/dir%d/file%d", layerIdx, idx%100, idx), int64(idx%1024))
+			}
+		}
+		trees = append(trees, tree)
+	}
+
+	benchTrees = trees
+	return trees
+}
+
+// BenchmarkStackTreeRange 堆叠所有层，结果与图层树共享未修改的子树。
+func BenchmarkStackTreeRange(b *testing.B) {
+	trees := syntheticImage()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		StackTreeRange(trees, 0, len(trees)-1)
+	}
+}
+
+// cacheKeys 返回浏览所有图层时用到的比较树：每一层与其下所有层的比较，以及第一层与其上各层合并后的比较（共2×N棵，与原先预先构建的一致）。
+func cacheKeys(layerCount int) []TreeCacheKey {
+	keys := []TreeCacheKey{{0, 0, 0, 0}}
+	for selectIdx := 1; selectIdx < layerCount; selectIdx++ {
+		keys = append(keys, TreeCacheKey{0, selectIdx - 1, selectIdx, selectIdx})
+	}
+	keys = append(keys, TreeCacheKey{0, 0, 0, 0})
+	for selectIdx := 1; selectIdx < layerCount; selectIdx++ {
+		keys = append(keys, TreeCacheKey{0, 0, 1, selectIdx})
+	}
+	return keys
+}
+
+// BenchmarkTreeCacheEagerCopy 按原先的方式预先构建所有比较树：每棵树都是完整的深拷贝（没有结构共享），并且同时保留在内存中。
+func BenchmarkTreeCacheEagerCopy(b *testing.B) {
+	trees := syntheticImage()
+	keys := cacheKeys(len(trees))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		built := make(map[TreeCacheKey]*FileTree)
+		for _, key := range keys {
+			if built[key] != nil {
+				continue
+			}
+			tree := StackTreeRange(trees, key.bottomTreeStart, key.bottomTreeStop).Copy()
+			for idx := key.topTreeStart; idx <= key.topTreeStop; idx++ {
+				if err := tree.CompareAndMark(trees[idx]); err != nil {
+					b.Fatal(err)
+				}
+			}
+			built[key] = tree
+		}
+	}
+}
+
+// BenchmarkTreeCacheGetAll 通过TreeCache按需构建同样的比较树（与图层树共享未修改的子树，不限制缓存的内存）。
+func BenchmarkTreeCacheGetAll(b *testing.B) {
+	trees := syntheticImage()
+	keys := cacheKeys(len(trees))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		cache := NewFileTreeCache(trees, 0)
+		for _, key := range keys {
+			cache.Get(key.bottomTreeStart, key.bottomTreeStop, key.topTreeStart, key.topTreeStop)
+		}
+	}
+}
@@ -12,6 +12,7 @@ type FileTree struct {
 	FileSize 	uint64
 	Name 		string
 	Id 			uuid.UUID
+	SortOrder 	SortOrder
 }
 
 // FileNode表示单个文件，它与下面文件的关系，它存在的树以及给定文件的元数据。
@@ -22,6 +23,16 @@ type FileNode struct {
 	Data		NodeData
 	Children	map[string]*FileNode
 	path 		string
+	size 		nodeSize
+	// Opaque 表示目录在所在的层中被标记为不透明（.wh..wh..opq），下层中该目录的内容都被删除
+	Opaque		bool
+}
+
+// nodeSize 缓存以FileNode为根的子树的累计文件大小，节点（或其子树）被修改时失效。
+type nodeSize struct {
+	valid 		bool
+	all 		int64 // 子树中所有节点的大小
+	live 		int64 // 子树中未被删除的节点的大小
 }
 
 // NodeData是FileNode的有效负载
@@ -35,6 +46,10 @@ type NodeData struct {
 type ViewInfo struct {
 	Collapsed 	bool
 	Hidden		bool
+	// Matched 表示节点满足当前的搜索条件，渲染时会高亮显示
+	Matched		bool
+	// Marked 表示节点被标记为从镜像中删除（见RemovalRules）
+	Marked		bool
 }
 
 // FileInfo包含特定FileNode的tar元数据
@@ -53,6 +68,9 @@ type FileInfo struct {
 // DiffType定义两个FileNode之间的比较结果
 type DiffType int
 
+// SortOrder定义渲染和遍历树时同级节点的排列顺序
+type SortOrder int
+
 // EfficiencyData表示给定文件树路径的存储和引用统计信息。
 type EfficiencyData struct {
 	Path				string
@@ -0,0 +1,315 @@
+package i18n
+
+// english 是英文消息目录。英文原文即消息的键，这里列出所有消息，作为翻译其他语言时的完整清单。
+var english = Catalog{
+	// command line
+	"No image argument given": "No image argument given",
+
+	// image fetching
+	"Image not available locally. Trying to pull '%s'...": "Image not available locally. Trying to pull '%s'...",
+
+	// progress output (runtime)
+	"cannot parse query: %v":                             "cannot parse query: %v",
+	"Analyzing image...":                                 "Analyzing image...",
+	"cannot analyze image: %v":                           "cannot analyze image: %v",
+	"cannot search layer %d: %v":                         "cannot search layer %d: %v",
+	"%d matching files":                                  "%d matching files",
+	"Fetching image...":                                  "Fetching image...",
+	"(this can take a while with large images)":          "(this can take a while with large images)",
+	"cannot fetch image: %v":                             "cannot fetch image: %v",
+	"Parsing image...":                                   "Parsing image...",
+	"cannot parse image: %v":                             "cannot parse image: %v",
+	"Building image...":                                  "Building image...",
+	"cannot write export file: %v":                       "cannot write export file: %v",
+	"Analyzing image... (export to '%s')":                "Analyzing image... (export to '%s')",
+	"invalid layer %d: the image has %d layers":          "invalid layer %d: the image has %d layers",
+	"Extracting %s from layer %d...":                     "Extracting %s from layer %d...",
+	"cannot extract files: %v":                           "cannot extract files: %v",
+	"skipped %s":                                         "skipped %s",
+	"%d files (%s) extracted to %s":                      "%d files (%s) extracted to %s",
+	"cannot read the rules file: %v":                     "cannot read the rules file: %v",
+	"Writing image to '%s'...":                           "Writing image to '%s'...",
+	"cannot write the image: %v":                         "cannot write the image: %v",
+	"%d entries removed from %d layers":                  "%d entries removed from %d layers",
+	"Layer size: %s %s %s (%s saved)":                    "Layer size: %s %s %s (%s saved)",
+	"Tags: %s":                                           "Tags: %s",
+	"Load it with: docker load -i %s":                    "Load it with: docker load -i %s",
+	"invalid layer range %d-%d: the image has %d layers": "invalid layer range %d-%d: the image has %d layers",
+	"Squashing layers %d-%d...":                          "Squashing layers %d-%d...",
+	"cannot squash the layers: %v":                       "cannot squash the layers: %v",
+	"Merged layer size: %s %s %s":                        "Merged layer size: %s %s %s",
+
+	// Dockerfile mapping (runtime)
+	"Mapping Dockerfile '%s'...":                       "Mapping Dockerfile '%s'...",
+	"cannot parse the Dockerfile: %v":                  "cannot parse the Dockerfile: %v",
+	"unknown build stage '%s'":                         "unknown build stage '%s'",
+	"%d of %d instructions found in the image history": "%d of %d instructions found in the image history",
+	"Total image size: %s %s %s":                       "Total image size: %s %s %s",
+	"Potential wasted space: %s %s %s":                 "Potential wasted space: %s %s %s",
+	"Image efficiency score: %d %% %s %d %%":           "Image efficiency score: %d %% %s %d %%",
+
+	// keybinding help: pane names and actions (keybinding.Registry)
+	"Global":                         "Global",
+	"Layers":                         "Layers",
+	"Filetree":                       "Filetree",
+	"Details":                        "Details",
+	"Image Config":                   "Image Config",
+	"Search":                         "Search",
+	"Help":                           "Help",
+	"Quit":                           "Quit",
+	"Switch view":                    "Switch view",
+	"Show/hide the file tree filter": "Show/hide the file tree filter",
+	"Show/hide this help (not in the filter and search bars)": "Show/hide this help (not in the filter and search bars)",
+	"Narrow the layer and details panes":                      "Narrow the layer and details panes",
+	"Widen the layer and details panes":                       "Widen the layer and details panes",
+	"Shrink the details pane":                                 "Shrink the details pane",
+	"Grow the details pane":                                   "Grow the details pane",
+	"Maximize/restore the selected pane":                      "Maximize/restore the selected pane",
+	"Restore the default pane sizes":                          "Restore the default pane sizes",
+	"Select the previous layer":                               "Select the previous layer",
+	"Select the next layer":                                   "Select the next layer",
+	"Select the first layer":                                  "Select the first layer",
+	"Select the last layer":                                   "Select the last layer",
+	"Previous page":                                           "Previous page",
+	"Next page":                                               "Next page",
+	"Show the changes of the selected layer":                  "Show the changes of the selected layer",
+	"Show the aggregated changes up to the selected layer":    "Show the aggregated changes up to the selected layer",
+	"Mark the selected layer as a bound of the compare range": "Mark the selected layer as a bound of the compare range",
+	"Move the cursor up":                                      "Move the cursor up",
+	"Move the cursor down":                                    "Move the cursor down",
+	"Go to the parent directory":                              "Go to the parent directory",
+	"Expand and enter the directory":                          "Expand and enter the directory",
+	"Go to the first file":                                    "Go to the first file",
+	"Go to the last file":                                     "Go to the last file",
+	"Collapse/expand the selected directory":                  "Collapse/expand the selected directory",
+	"Collapse the selected directory":                         "Collapse the selected directory",
+	"Expand the selected directory":                           "Expand the selected directory",
+	"Collapse/expand all directories":                         "Collapse/expand all directories",
+	"Show/hide the file attributes":                           "Show/hide the file attributes",
+	"Show/hide added files":                                   "Show/hide added files",
+	"Show/hide removed files":                                 "Show/hide removed files",
+	"Show/hide modified files":                                "Show/hide modified files",
+	"Show/hide unmodified files":                              "Show/hide unmodified files",
+	"Sort by name, size or change type":                       "Sort by name, size or change type",
+	"Search the file tree":                                    "Search the file tree",
+	"Go to the next search match":                             "Go to the next search match",
+	"Go to the previous search match":                         "Go to the previous search match",
+	"Select the previous inefficiency":                        "Select the previous inefficiency",
+	"Select the next inefficiency":                            "Select the next inefficiency",
+	"Select the first inefficiency":                           "Select the first inefficiency",
+	"Select the last inefficiency":                            "Select the last inefficiency",
+	"Show the selected file in the file tree":                 "Show the selected file in the file tree",
+	"Sort by wasted space or count":                           "Sort by wasted space or count",
+	"Keep the search matches and return to the file tree":     "Keep the search matches and return to the file tree",
+	"Cancel the search":                                       "Cancel the search",
+	"Scroll up":                                               "Scroll up",
+	"Scroll down":                                             "Scroll down",
+	"Go to the first row":                                     "Go to the first row",
+	"Go to the last row":                                      "Go to the last row",
+	"Close this help":                                         "Close this help",
+	"File Viewer":                                             "File Viewer",
+	"View the selected file (or double click it)":             "View the selected file (or double click it)",
+	"Extract the selected file or directory":                  "Extract the selected file or directory",
+	"Mark/unmark the selected file or directory for removal":  "Mark/unmark the selected file or directory for removal",
+	"Scroll left":                                             "Scroll left",
+	"Scroll right":                                            "Scroll right",
+	"Switch between the contents and the diff":                "Show the contents or the diff against the previous version",
+	"Switch between the text and the hex dump":                "Show the text or a hex dump",
+	"Close the file viewer":                                   "Close the file viewer",
+	"Show/hide what merging the compared layers would save":   "Show/hide what merging the compared layers would save",
+	"Write the image with the compared layers squashed":       "Write the image with the compared layers squashed",
+
+	// TUI panes and status bar
+	"Count":                            "Count",
+	"Total Space":                      "Total Space",
+	"Total Image size:":                "Total Image size:",
+	"Image efficiency score:":          "Image efficiency score:",
+	"Potential wasted space:":          "Potential wasted space:",
+	"Layer Details":                    "Layer Details",
+	"Image Details":                    "Image Details",
+	"Digest: ":                         "Digest: ",
+	"Created: ":                        "Created: ",
+	"Author: ":                         "Author: ",
+	"Command:":                         "Command:",
+	"Dockerfile:":                      "Dockerfile:",
+	"Path":                             "Path",
+	"Sort by count":                    "Sort by count",
+	"Sort by size":                     "Sort by size",
+	"Show in file tree":                "Show in file tree",
+	"No matches":                       "No matches",
+	"Match %d of %d":                   "Match %d of %d",
+	"%d matches":                       "%d matches",
+	"Current Layer Contents":           "Current Layer Contents",
+	"Aggregated Layer Contents":        "Aggregated Layer Contents",
+	"Changes Between Layers %d and %d": "Changes Between Layers %d and %d",
+	" (by %s)":                         " (by %s)",
+	"Permission":                       "Permission",
+	"UID:GID":                          "UID:GID",
+	"Size":                             "Size",
+	"Collapse dir":                     "Collapse dir",
+	"Collapse all dir":                 "Collapse all dir",
+	"Added":                            "Added",
+	"Removed":                          "Removed",
+	"Modified":                         "Modified",
+	"Unmodified":                       "Unmodified",
+	"Attributes":                       "Attributes",
+	"Sort: %s":                         "Sort: %s",
+	"Filter: ":                         "Filter: ",
+	"Type to filter the file tree (e.g. size>10MB type:file path:/usr/lib/**)": "Type to filter the file tree (e.g. size>10MB type:file path:/usr/lib/**)",
+	"Keybindings":                 "Keybindings",
+	"Scroll":                      "Scroll",
+	"Close help":                  "Close help",
+	"Layers (comparing %d %s %d)": "Layers (comparing %d %s %d)",
+	"Cmp":                         "Cmp",
+	"Command":                     "Command",
+	"Created":                     "Created",
+	"Age":                         "Age",
+	"Digest":                      "Digest",
+	"Author":                      "Author",
+	"Files":                       "Files",
+	"Share":                       "Share",
+	"Line":                        "Line",
+	"Wasted":                      "Wasted",
+	"Show layer changes":          "Show layer changes",
+	"Show aggregated changes":     "Show aggregated changes",
+	"Mark compare range":          "Mark compare range",
+	"Search: ":                    "Search: ",
+	"Type to search the file tree (e.g. name:*.so size>1MB), Enter to confirm, Esc to cancel": "Type to search the file tree (e.g. name:*.so size>1MB), Enter to confirm, Esc to cancel",
+	"Filter": "Filter",
+
+	// image config pane
+	"User: ":                     "User: ",
+	"Working dir: ":              "Working dir: ",
+	"Entrypoint: ":               "Entrypoint: ",
+	"Cmd: ":                      "Cmd: ",
+	"Stop signal: ":              "Stop signal: ",
+	"Exposed ports: ":            "Exposed ports: ",
+	"Volumes: ":                  "Volumes: ",
+	"Healthcheck: ":              "Healthcheck: ",
+	"interval %s":                "interval %s",
+	"timeout %s":                 "timeout %s",
+	"start period %s":            "start period %s",
+	"retries %d":                 "retries %d",
+	"Env:":                       "Env:",
+	"Labels:":                    "Labels:",
+	"Annotations:":               "Annotations:",
+	"(no runtime configuration)": "(no runtime configuration)",
+
+	// file viewer
+	"Contents":              "Contents",
+	"Diff":                  "Diff",
+	"Hex":                   "Hex",
+	"Close":                 "Close",
+	"Version from layer %d": "Version from layer %d",
+	"Removed in the selected layers, showing the version from layer %d": "Removed in the selected layers, showing the version from layer %d",
+	"The file does not exist in the compared layers":                    "The file does not exist in the compared layers",
+	"The file is unchanged since layer %d":                              "The file is unchanged since layer %d",
+	"(layer %d)":                                                        "(layer %d)",
+	"Only the first %s of the file is shown":                            "Only the first %s of the file is shown",
+	"Only the first %s of the file is compared":                         "Only the first %s of the file is compared",
+	"The contents of the binary files are the same":                     "The contents of the binary files are the same",
+	"The binary files differ":                                           "The binary files differ",
+	"The compared part of the contents is the same":                     "The compared part of the contents is the same",
+	"The contents are the same, only the file attributes changed":       "The contents are the same, only the file attributes changed",
+	"Symbolic link to %s":                                               "Symbolic link to %s",
+	"Not a regular file":                                                "Not a regular file",
+	"File contents are not available (enable viewer.spool-layers or raise viewer.spool-limit to keep the image layers)": "File contents are not available (enable viewer.spool-layers or raise viewer.spool-limit to keep the image layers)",
+	"cannot read the file: %v": "cannot read the file: %v",
+
+	// file extraction (file tree)
+	"%d files (%s) extracted to %s, %d skipped": "%d files (%s) extracted to %s, %d skipped",
+
+	// marking files for removal (file tree)
+	"cannot mark the file: %v":                                        "cannot mark the file: %v",
+	"cannot save the marked files: %v":                                "cannot save the marked files: %v",
+	"Marked %s for removal (saved to %s)":                             "Marked %s for removal (saved to %s)",
+	"Unmarked %s, but a marked directory or pattern still removes it": "Unmarked %s, but a marked directory or pattern still removes it",
+	"Unmarked %s": "Unmarked %s",
+
+	// squash projection (layer pane)
+	"Squash (what-if)":                         "Squash (what-if)",
+	"Squash %d %s %d (what-if)":                "Squash %d %s %d (what-if)",
+	"Merged layer size:":                       "Merged layer size:",
+	"Mark the layers to merge with %s first":   "Mark the layers to merge with %s first",
+	"Show the squash projection with %s first": "Show the squash projection with %s first",
+	"The image layers are not available (enable viewer.spool-layers or raise viewer.spool-limit to keep them)": "The image layers are not available (enable viewer.spool-layers or raise viewer.spool-limit to keep them)",
+	"Layers %d-%d squashed into %s (layer size %s %s %s)":                                                      "Layers %d-%d squashed into %s (layer size %s %s %s)",
+
+	// Dockerfile suggestions (advisor)
+	"Clean the apt lists and package cache in the same RUN at %s":                                                  "Clean the apt lists and package cache in the same RUN at %s",
+	"Use apk add --no-cache at %s":                                                                                 "Use apk add --no-cache at %s",
+	"Clean the yum cache in the same RUN at %s":                                                                    "Clean the yum cache in the same RUN at %s",
+	"Clean the dnf cache in the same RUN at %s":                                                                    "Clean the dnf cache in the same RUN at %s",
+	"Use pip install --no-cache-dir at %s":                                                                         "Use pip install --no-cache-dir at %s",
+	"Clean the npm cache in the same RUN at %s":                                                                    "Clean the npm cache in the same RUN at %s",
+	"Add --no-install-recommends to apt-get install at %s":                                                         "Add --no-install-recommends to apt-get install at %s",
+	"Merge the RUN at %s and %s: %s deletes or replaces %s added by %s":                                            "Merge the RUN at %s and %s: %s deletes or replaces %s added by %s",
+	"Use %s --chown/--chmod at %s instead of changing the files at %s, which stores them twice":                    "Use %s --chown/--chmod at %s instead of changing the files at %s, which stores them twice",
+	"Do not copy the files deleted at %s in the %s at %s (add them to .dockerignore or use RUN --mount=type=bind)": "Do not copy the files deleted at %s in the %s at %s (add them to .dockerignore or use RUN --mount=type=bind)",
+	"Move the COPY at %s below the dependency install at %s for better caching, copy only %s before it":            "Move the COPY at %s below the dependency install at %s for better caching, copy only %s before it",
+	"line %d":                          "line %d",
+	"layer %d":                         "layer %d",
+	"Dockerfile suggestions":           "Dockerfile suggestions",
+	"Image:":                           "Image:",
+	"No suggestions.":                  "No suggestions.",
+	"Estimated saving:":                "Estimated saving:",
+	"Estimated saving per rebuild:":    "Estimated saving per rebuild:",
+	"Suggestion":                       "Suggestion",
+	"Estimated saving":                 "Estimated saving",
+	"%s per rebuild":                   "%s per rebuild",
+	"Writing suggestions to '%s'...":   "Writing suggestions to '%s'...",
+	"cannot write the suggestions: %v": "cannot write the suggestions: %v",
+	"%d suggestions, estimated saving %s (%s per rebuild)": "%d suggestions, estimated saving %s (%s per rebuild)",
+	"Suggestions:":             "Suggestions:",
+	"(saves %s)":               "(saves %s)",
+	"Dockerfile suggestions:":  "Dockerfile suggestions:",
+	"%d (estimated saving %s)": "%d (estimated saving %s)",
+
+	// Dockerfile lint (linter)
+	"Pin the base image %s to a version tag or digest":                                     "Pin the base image %s to a version tag or digest",
+	"Use a version tag instead of latest for the base image %s":                            "Use a version tag instead of latest for the base image %s",
+	"ADD does not verify the download of %s, use ADD --checksum or download it in a RUN":   "ADD does not verify the download of %s, use ADD --checksum or download it in a RUN",
+	"The final stage runs as root, add a USER instruction":                                 "The final stage runs as root, add a USER instruction",
+	"The final stage runs as root (USER %s)":                                               "The final stage runs as root (USER %s)",
+	"Remove /var/lib/apt/lists in the same RUN as apt-get install":                         "Remove /var/lib/apt/lists in the same RUN as apt-get install",
+	"Do not use sudo, switch users with USER instead":                                      "Do not use sudo, switch users with USER instead",
+	"This CMD is replaced by the CMD at line %d":                                           "This CMD is replaced by the CMD at line %d",
+	"Use the exec form (a JSON array) for ENTRYPOINT so that the process receives signals": "Use the exec form (a JSON array) for ENTRYPOINT so that the process receives signals",
+	"%d problems (%d errors, %d warnings, %d infos)":                                       "%d problems (%d errors, %d warnings, %d infos)",
+	"cannot write the lint report: %v":                                                     "cannot write the lint report: %v",
+	"Linting Dockerfile '%s'...":                                                           "Linting Dockerfile '%s'...",
+	"the Dockerfile has problems of severity %s or higher, the image is not built":         "the Dockerfile has problems of severity %s or higher, the image is not built",
+
+	// HTML report (report.WriteHTML)
+	"Image report":                     "Image report",
+	"File changes by layer":            "File changes by layer",
+	"Inefficient files":                "Inefficient files",
+	"Size treemap":                     "Size treemap",
+	"Layer %d":                         "Layer %d",
+	"No file changes.":                 "No file changes.",
+	"%d more entries not shown":        "%d more entries not shown",
+	"No inefficient files.":            "No inefficient files.",
+	"Generated by LGM on %s":           "Generated by LGM on %s",
+	"Writing HTML report to '%s'...":   "Writing HTML report to '%s'...",
+	"cannot write the HTML report: %v": "cannot write the HTML report: %v",
+
+	// build (runtime.runBuild, cmd.lgmFlags)
+	"cannot build image: %v":             "cannot build image: %v",
+	"flag needs an argument: --%s":       "flag needs an argument: --%s",
+	"invalid argument '%s' for --%s: %v": "invalid argument '%s' for --%s: %v",
+
+	// lint before build (runtime.Run)
+	"the Dockerfile is not a local file, it is built without linting": "the Dockerfile is not a local file, it is built without linting",
+
+	// slim and export (runtime.Slim, cmd.doExportCmd)
+	"the image layers are not available":  "the image layers are not available",
+	"no files are marked for removal: %v": "no files are marked for removal: %v",
+
+	// spool limit (runtime.Run)
+	"The image layers are larger than viewer.spool-limit (%s), they are not kept": "The image layers are larger than viewer.spool-limit (%s), they are not kept",
+
+	// sort orders (filetree.SortOrder)
+	"name": "name",
+	"size": "size",
+	"type": "type",
+}
@@ -0,0 +1,315 @@
+package i18n
+
+// simplifiedChinese 是简体中文消息目录。
+var simplifiedChinese = Catalog{
+	// command line
+	"No image argument given": "没有指定镜像参数",
+
+	// image fetching
+	"Image not available locally. Trying to pull '%s'...": "本地没有该镜像，正在尝试拉取 '%s'...",
+
+	// progress output (runtime)
+	"cannot parse query: %v":                             "无法解析查询：%v",
+	"Analyzing image...":                                 "正在分析镜像...",
+	"cannot analyze image: %v":                           "无法分析镜像：%v",
+	"cannot search layer %d: %v":                         "无法搜索第 %d 层：%v",
+	"%d matching files":                                  "%d 个匹配的文件",
+	"Fetching image...":                                  "正在获取镜像...",
+	"(this can take a while with large images)":          "（镜像较大时可能需要一段时间）",
+	"cannot fetch image: %v":                             "无法获取镜像：%v",
+	"Parsing image...":                                   "正在解析镜像...",
+	"cannot parse image: %v":                             "无法解析镜像：%v",
+	"Building image...":                                  "正在构建镜像...",
+	"cannot write export file: %v":                       "无法写入导出文件：%v",
+	"Analyzing image... (export to '%s')":                "正在分析镜像...（导出到 '%s'）",
+	"invalid layer %d: the image has %d layers":          "无效的图层 %d：镜像共有 %d 层",
+	"Extracting %s from layer %d...":                     "正在从第 %[2]d 层提取 %[1]s...",
+	"cannot extract files: %v":                           "无法提取文件：%v",
+	"skipped %s":                                         "已跳过 %s",
+	"%d files (%s) extracted to %s":                      "已将 %d 个文件（%s）提取到 %s",
+	"cannot read the rules file: %v":                     "无法读取规则文件：%v",
+	"Writing image to '%s'...":                           "正在将镜像写入 '%s'...",
+	"cannot write the image: %v":                         "无法写入镜像：%v",
+	"%d entries removed from %d layers":                  "已从 %[2]d 层中删除 %[1]d 个条目",
+	"Layer size: %s %s %s (%s saved)":                    "图层大小：%s %s %s（节省 %s）",
+	"Tags: %s":                                           "标签：%s",
+	"Load it with: docker load -i %s":                    "使用以下命令加载：docker load -i %s",
+	"invalid layer range %d-%d: the image has %d layers": "无效的图层范围 %d-%d：镜像共有 %d 层",
+	"Squashing layers %d-%d...":                          "正在合并第 %d-%d 层...",
+	"cannot squash the layers: %v":                       "无法合并图层：%v",
+	"Merged layer size: %s %s %s":                        "合并后的层大小：%s %s %s",
+
+	// Dockerfile mapping (runtime)
+	"Mapping Dockerfile '%s'...":                       "正在对齐 Dockerfile '%s'...",
+	"cannot parse the Dockerfile: %v":                  "无法解析 Dockerfile：%v",
+	"unknown build stage '%s'":                         "未知的构建阶段 '%s'",
+	"%d of %d instructions found in the image history": "在镜像历史记录中找到 %d 条指令（共 %d 条）",
+	"Total image size: %s %s %s":                       "镜像总大小：%s %s %s",
+	"Potential wasted space: %s %s %s":                 "可能浪费的空间：%s %s %s",
+	"Image efficiency score: %d %% %s %d %%":           "镜像效率得分：%d %% %s %d %%",
+
+	// keybinding help: pane names and actions (keybinding.Registry)
+	"Global":                         "全局",
+	"Layers":                         "镜像层",
+	"Filetree":                       "文件树",
+	"Details":                        "详细信息",
+	"Image Config":                   "镜像配置",
+	"Search":                         "搜索",
+	"Help":                           "帮助",
+	"Quit":                           "退出",
+	"Switch view":                    "切换窗格",
+	"Show/hide the file tree filter": "显示/隐藏文件树筛选栏",
+	"Show/hide this help (not in the filter and search bars)": "显示/隐藏本帮助（在筛选栏和搜索栏中无效）",
+	"Narrow the layer and details panes":                      "缩窄镜像层和详细信息窗格",
+	"Widen the layer and details panes":                       "加宽镜像层和详细信息窗格",
+	"Shrink the details pane":                                 "减小详细信息窗格的高度",
+	"Grow the details pane":                                   "增加详细信息窗格的高度",
+	"Maximize/restore the selected pane":                      "最大化/还原选中的窗格",
+	"Restore the default pane sizes":                          "恢复默认的窗格大小",
+	"Select the previous layer":                               "选择上一层",
+	"Select the next layer":                                   "选择下一层",
+	"Select the first layer":                                  "选择第一层",
+	"Select the last layer":                                   "选择最后一层",
+	"Previous page":                                           "上一页",
+	"Next page":                                               "下一页",
+	"Show the changes of the selected layer":                  "显示选定层的变更",
+	"Show the aggregated changes up to the selected layer":    "显示截至选定层的累计变更",
+	"Mark the selected layer as a bound of the compare range": "将选定层标记为比较范围的边界",
+	"Move the cursor up":                                      "光标上移",
+	"Move the cursor down":                                    "光标下移",
+	"Go to the parent directory":                              "转到上级目录",
+	"Expand and enter the directory":                          "展开并进入目录",
+	"Go to the first file":                                    "转到第一个文件",
+	"Go to the last file":                                     "转到最后一个文件",
+	"Collapse/expand the selected directory":                  "折叠/展开选定的目录",
+	"Collapse the selected directory":                         "折叠选定的目录",
+	"Expand the selected directory":                           "展开选定的目录",
+	"Collapse/expand all directories":                         "折叠/展开所有目录",
+	"Show/hide the file attributes":                           "显示/隐藏文件属性",
+	"Show/hide added files":                                   "显示/隐藏新增的文件",
+	"Show/hide removed files":                                 "显示/隐藏删除的文件",
+	"Show/hide modified files":                                "显示/隐藏修改的文件",
+	"Show/hide unmodified files":                              "显示/隐藏未修改的文件",
+	"Sort by name, size or change type":                       "按名称、大小或变更类型排序",
+	"Search the file tree":                                    "搜索文件树",
+	"Go to the next search match":                             "转到下一个搜索结果",
+	"Go to the previous search match":                         "转到上一个搜索结果",
+	"Select the previous inefficiency":                        "选择上一个低效项",
+	"Select the next inefficiency":                            "选择下一个低效项",
+	"Select the first inefficiency":                           "选择第一个低效项",
+	"Select the last inefficiency":                            "选择最后一个低效项",
+	"Show the selected file in the file tree":                 "在文件树中显示选定的文件",
+	"Sort by wasted space or count":                           "按浪费的空间或次数排序",
+	"Keep the search matches and return to the file tree":     "保留搜索结果并返回文件树",
+	"Cancel the search":                                       "取消搜索",
+	"Scroll up":                                               "向上滚动",
+	"Scroll down":                                             "向下滚动",
+	"Go to the first row":                                     "转到第一行",
+	"Go to the last row":                                      "转到最后一行",
+	"Close this help":                                         "关闭本帮助",
+	"File Viewer":                                             "文件查看器",
+	"View the selected file (or double click it)":             "查看选中的文件（或双击该文件）",
+	"Extract the selected file or directory":                  "提取选中的文件或目录",
+	"Mark/unmark the selected file or directory for removal":  "标记/取消标记选中的文件或目录以删除",
+	"Scroll left":                                             "向左滚动",
+	"Scroll right":                                            "向右滚动",
+	"Switch between the contents and the diff":                "在文件内容和差异之间切换",
+	"Switch between the text and the hex dump":                "在文本和十六进制转储之间切换",
+	"Close the file viewer":                                   "关闭文件查看器",
+	"Show/hide what merging the compared layers would save":   "显示/隐藏合并比较的图层可以节省的空间",
+	"Write the image with the compared layers squashed":       "写入合并了比较的图层的镜像",
+
+	// TUI panes and status bar
+	"Count":                            "次数",
+	"Total Space":                      "总空间",
+	"Total Image size:":                "镜像总大小：",
+	"Image efficiency score:":          "镜像效率得分：",
+	"Potential wasted space:":          "可能浪费的空间：",
+	"Layer Details":                    "层详细信息",
+	"Image Details":                    "镜像详细信息",
+	"Digest: ":                         "摘要：",
+	"Created: ":                        "创建时间：",
+	"Author: ":                         "作者：",
+	"Command:":                         "命令：",
+	"Dockerfile:":                      "Dockerfile：",
+	"Path":                             "路径",
+	"Sort by count":                    "按次数排序",
+	"Sort by size":                     "按大小排序",
+	"Show in file tree":                "在文件树中显示",
+	"No matches":                       "没有匹配项",
+	"Match %d of %d":                   "第 %d 个，共 %d 个",
+	"%d matches":                       "%d 个匹配项",
+	"Current Layer Contents":           "当前层内容",
+	"Aggregated Layer Contents":        "累计层内容",
+	"Changes Between Layers %d and %d": "第 %d 层到第 %d 层之间的变更",
+	" (by %s)":                         "（按%s）",
+	"Permission":                       "权限",
+	"UID:GID":                          "UID:GID",
+	"Size":                             "大小",
+	"Collapse dir":                     "折叠目录",
+	"Collapse all dir":                 "折叠所有目录",
+	"Added":                            "新增",
+	"Removed":                          "删除",
+	"Modified":                         "修改",
+	"Unmodified":                       "未修改",
+	"Attributes":                       "属性",
+	"Sort: %s":                         "排序：%s",
+	"Filter: ":                         "筛选：",
+	"Type to filter the file tree (e.g. size>10MB type:file path:/usr/lib/**)": "输入条件筛选文件树（例如 size>10MB type:file path:/usr/lib/**）",
+	"Keybindings":                 "按键",
+	"Scroll":                      "滚动",
+	"Close help":                  "关闭帮助",
+	"Layers (comparing %d %s %d)": "镜像层（比较 %d %s %d）",
+	"Cmp":                         "比",
+	"Command":                     "命令",
+	"Created":                     "创建时间",
+	"Age":                         "时长",
+	"Digest":                      "摘要",
+	"Author":                      "作者",
+	"Files":                       "文件数",
+	"Share":                       "占比",
+	"Line":                        "行",
+	"Wasted":                      "浪费",
+	"Show layer changes":          "显示本层变更",
+	"Show aggregated changes":     "显示累计变更",
+	"Mark compare range":          "标记比较范围",
+	"Search: ":                    "搜索：",
+	"Type to search the file tree (e.g. name:*.so size>1MB), Enter to confirm, Esc to cancel": "输入条件搜索文件树（例如 name:*.so size>1MB），Enter 确认，Esc 取消",
+	"Filter": "筛选",
+
+	// image config pane
+	"User: ":                     "用户：",
+	"Working dir: ":              "工作目录：",
+	"Entrypoint: ":               "入口点：",
+	"Cmd: ":                      "默认命令：",
+	"Stop signal: ":              "停止信号：",
+	"Exposed ports: ":            "暴露的端口：",
+	"Volumes: ":                  "卷：",
+	"Healthcheck: ":              "健康检查：",
+	"interval %s":                "间隔 %s",
+	"timeout %s":                 "超时 %s",
+	"start period %s":            "启动等待 %s",
+	"retries %d":                 "重试 %d 次",
+	"Env:":                       "环境变量：",
+	"Labels:":                    "标签：",
+	"Annotations:":               "注解：",
+	"(no runtime configuration)": "（没有运行配置）",
+
+	// file viewer
+	"Contents":              "内容",
+	"Diff":                  "差异",
+	"Hex":                   "十六进制",
+	"Close":                 "关闭",
+	"Version from layer %d": "第 %d 层中的版本",
+	"Removed in the selected layers, showing the version from layer %d": "已在选中的图层中删除，显示第 %d 层中的版本",
+	"The file does not exist in the compared layers":                    "比较的图层中不存在该文件",
+	"The file is unchanged since layer %d":                              "该文件自第 %d 层以来没有变化",
+	"(layer %d)":                                                        "（第 %d 层）",
+	"Only the first %s of the file is shown":                            "只显示文件的前 %s",
+	"Only the first %s of the file is compared":                         "只比较文件的前 %s",
+	"The contents of the binary files are the same":                     "二进制文件的内容相同",
+	"The binary files differ":                                           "二进制文件不同",
+	"The compared part of the contents is the same":                     "比较的部分内容相同",
+	"The contents are the same, only the file attributes changed":       "内容相同，只有文件属性发生了变化",
+	"Symbolic link to %s":                                               "指向 %s 的符号链接",
+	"Not a regular file":                                                "不是普通文件",
+	"File contents are not available (enable viewer.spool-layers or raise viewer.spool-limit to keep the image layers)": "无法读取文件内容（启用 viewer.spool-layers 或提高 viewer.spool-limit 以保留镜像的各层）",
+	"cannot read the file: %v": "无法读取文件：%v",
+
+	// file extraction (file tree)
+	"%d files (%s) extracted to %s, %d skipped": "已将 %d 个文件（%s）提取到 %s，跳过 %d 个",
+
+	// marking files for removal (file tree)
+	"cannot mark the file: %v":                                        "无法标记文件：%v",
+	"cannot save the marked files: %v":                                "无法保存标记的文件：%v",
+	"Marked %s for removal (saved to %s)":                             "已将 %s 标记为删除（保存到 %s）",
+	"Unmarked %s, but a marked directory or pattern still removes it": "已取消标记 %s，但标记的目录或模式仍会删除它",
+	"Unmarked %s": "已取消标记 %s",
+
+	// squash projection (layer pane)
+	"Squash (what-if)":                         "合并预测",
+	"Squash %d %s %d (what-if)":                "合并 %d %s %d（预测）",
+	"Merged layer size:":                       "合并后的层大小：",
+	"Mark the layers to merge with %s first":   "请先用 %s 标记要合并的图层",
+	"Show the squash projection with %s first": "请先用 %s 显示合并预测",
+	"The image layers are not available (enable viewer.spool-layers or raise viewer.spool-limit to keep them)": "无法读取镜像的各层（启用 viewer.spool-layers 或提高 viewer.spool-limit 以保留它们）",
+	"Layers %d-%d squashed into %s (layer size %s %s %s)":                                                      "已将第 %d-%d 层合并写入 %s（层大小 %s %s %s）",
+
+	// Dockerfile suggestions (advisor)
+	"Clean the apt lists and package cache in the same RUN at %s":                                                  "在 %s 的同一个 RUN 中清理 apt 列表和软件包缓存",
+	"Use apk add --no-cache at %s":                                                                                 "在 %s 使用 apk add --no-cache",
+	"Clean the yum cache in the same RUN at %s":                                                                    "在 %s 的同一个 RUN 中清理 yum 缓存",
+	"Clean the dnf cache in the same RUN at %s":                                                                    "在 %s 的同一个 RUN 中清理 dnf 缓存",
+	"Use pip install --no-cache-dir at %s":                                                                         "在 %s 使用 pip install --no-cache-dir",
+	"Clean the npm cache in the same RUN at %s":                                                                    "在 %s 的同一个 RUN 中清理 npm 缓存",
+	"Add --no-install-recommends to apt-get install at %s":                                                         "在 %s 的 apt-get install 中添加 --no-install-recommends",
+	"Merge the RUN at %s and %s: %s deletes or replaces %s added by %s":                                            "合并 %[1]s 和 %[2]s 的 RUN：%[3]s 删除或替换了 %[5]s 添加的 %[4]s",
+	"Use %s --chown/--chmod at %s instead of changing the files at %s, which stores them twice":                    "在 %[2]s 使用 %[1]s --chown/--chmod，而不是在 %[3]s 修改这些文件（这样文件会保存两次）",
+	"Do not copy the files deleted at %s in the %s at %s (add them to .dockerignore or use RUN --mount=type=bind)": "不要在 %[3]s 的 %[2]s 中复制 %[1]s 删除的文件（将它们加入 .dockerignore 或使用 RUN --mount=type=bind）",
+	"Move the COPY at %s below the dependency install at %s for better caching, copy only %s before it":            "将 %s 的 COPY 移到 %s 安装依赖之后以更好地利用缓存，在安装之前只复制 %s",
+	"line %d":                          "第 %d 行",
+	"layer %d":                         "第 %d 层",
+	"Dockerfile suggestions":           "Dockerfile 修改建议",
+	"Image:":                           "镜像：",
+	"No suggestions.":                  "没有建议。",
+	"Estimated saving:":                "估计节省：",
+	"Estimated saving per rebuild:":    "每次重新构建估计节省：",
+	"Suggestion":                       "建议",
+	"Estimated saving":                 "估计节省",
+	"%s per rebuild":                   "每次重新构建 %s",
+	"Writing suggestions to '%s'...":   "正在将修改建议写入 '%s'...",
+	"cannot write the suggestions: %v": "无法写入修改建议：%v",
+	"%d suggestions, estimated saving %s (%s per rebuild)": "%d 条建议，估计节省 %s（每次重新构建 %s）",
+	"Suggestions:":             "修改建议：",
+	"(saves %s)":               "（节省 %s）",
+	"Dockerfile suggestions:":  "Dockerfile 修改建议：",
+	"%d (estimated saving %s)": "%d 条（估计节省 %s）",
+
+	// Dockerfile lint (linter)
+	"Pin the base image %s to a version tag or digest":                                     "将基础镜像 %s 固定到版本标签或摘要",
+	"Use a version tag instead of latest for the base image %s":                            "基础镜像 %s 请使用版本标签而不是 latest",
+	"ADD does not verify the download of %s, use ADD --checksum or download it in a RUN":   "ADD 不会校验下载的 %s，请使用 ADD --checksum 或在 RUN 中下载",
+	"The final stage runs as root, add a USER instruction":                                 "最终阶段以 root 运行，请添加 USER 指令",
+	"The final stage runs as root (USER %s)":                                               "最终阶段以 root 运行（USER %s）",
+	"Remove /var/lib/apt/lists in the same RUN as apt-get install":                         "在 apt-get install 所在的 RUN 中删除 /var/lib/apt/lists",
+	"Do not use sudo, switch users with USER instead":                                      "不要使用 sudo，请使用 USER 切换用户",
+	"This CMD is replaced by the CMD at line %d":                                           "此 CMD 被第 %d 行的 CMD 取代",
+	"Use the exec form (a JSON array) for ENTRYPOINT so that the process receives signals": "ENTRYPOINT 请使用 exec 形式（JSON 数组），以便进程能接收信号",
+	"%d problems (%d errors, %d warnings, %d infos)":                                       "%d 个问题（%d 个错误，%d 个警告，%d 个提示）",
+	"cannot write the lint report: %v":                                                     "无法写入检查结果：%v",
+	"Linting Dockerfile '%s'...":                                                           "正在检查 Dockerfile '%s'...",
+	"the Dockerfile has problems of severity %s or higher, the image is not built":         "Dockerfile 存在严重程度为 %s 或更高的问题，不构建镜像",
+
+	// HTML report (report.WriteHTML)
+	"Image report":                     "镜像报告",
+	"File changes by layer":            "各层的文件变化",
+	"Inefficient files":                "浪费空间的文件",
+	"Size treemap":                     "大小树图",
+	"Layer %d":                         "第 %d 层",
+	"No file changes.":                 "没有文件变化。",
+	"%d more entries not shown":        "另有 %d 项未显示",
+	"No inefficient files.":            "没有浪费空间的文件。",
+	"Generated by LGM on %s":           "由 LGM 生成于 %s",
+	"Writing HTML report to '%s'...":   "正在将 HTML 报告写入 '%s'...",
+	"cannot write the HTML report: %v": "无法写入 HTML 报告：%v",
+
+	// build (runtime.runBuild, cmd.lgmFlags)
+	"cannot build image: %v":             "无法构建镜像：%v",
+	"flag needs an argument: --%s":       "参数需要一个值：--%s",
+	"invalid argument '%s' for --%s: %v": "--%[2]s 的值 '%[1]s' 无效：%[3]v",
+
+	// lint before build (runtime.Run)
+	"the Dockerfile is not a local file, it is built without linting": "Dockerfile 不是本地文件，不检查直接构建",
+
+	// slim and export (runtime.Slim, cmd.doExportCmd)
+	"the image layers are not available":  "无法读取镜像的各层",
+	"no files are marked for removal: %v": "没有标记为删除的文件：%v",
+
+	// spool limit (runtime.Run)
+	"The image layers are larger than viewer.spool-limit (%s), they are not kept": "镜像的各层大于 viewer.spool-limit（%s），不保留它们",
+
+	// sort orders (filetree.SortOrder)
+	"name": "名称",
+	"size": "大小",
+	"type": "类型",
+}
@@ -0,0 +1,91 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Catalog 将界面文字的英文原文映射为译文。英文原文同时作为消息的键（与golang.org/x/text/message相同），
+// 因此英文目录中的每条消息都映射为自身，其他语言的目录缺少的消息显示英文原文。
+type Catalog map[string]string
+
+// Catalogs 是所有可用语言的消息目录，键为语言标签。
+var Catalogs = map[string]Catalog{
+	"en":    english,
+	"zh-CN": simplifiedChinese,
+}
+
+// 当前使用的语言
+var language = "en"
+
+// T 返回消息在当前语言中的译文，args 不为空时按fmt.Sprintf的规则格式化。
+func T(message string, args ...interface{}) string {
+	translated, exists := Catalogs[language][message]
+	if !exists || translated == "" {
+		translated = message
+	}
+	if len(args) == 0 {
+		return translated
+	}
+	return fmt.Sprintf(translated, args...)
+}
+
+// Language 返回当前使用的语言标签。
+func Language() string {
+	return language
+}
+
+// Languages 按字母顺序返回所有可用语言的标签。
+func Languages() []string {
+	names := make([]string, 0, len(Catalogs))
+	for name := range Catalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetLanguage 设置当前使用的语言。lang 可以是语言标签（例如 "zh-CN"）或locale（例如 "zh_CN.UTF-8"）；不支持的语言返回错误并保持原来的设置。
+func SetLanguage(lang string) error {
+	tag, exists := normalize(lang)
+	if !exists {
+		return fmt.Errorf("unsupported language '%s' (available: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	language = tag
+	return nil
+}
+
+// Detect 按POSIX的优先级（LC_ALL、LC_MESSAGES、LANG）从环境变量中确定语言，没有设置或不支持时使用英文。
+func Detect() string {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+		if tag, exists := normalize(value); exists {
+			return tag
+		}
+		return "en"
+	}
+	return "en"
+}
+
+// normalize 将语言标签或locale转换为消息目录的语言标签
+func normalize(lang string) (string, bool) {
+	// drop the encoding and the modifier: zh_CN.UTF-8@pinyin -> zh_CN
+	if idx := strings.IndexAny(lang, ".@"); idx >= 0 {
+		lang = lang[:idx]
+	}
+	lang = strings.ToLower(strings.Replace(lang, "_", "-", -1))
+
+	switch {
+	case lang == "c" || lang == "posix" || lang == "en" || strings.HasPrefix(lang, "en-"):
+		return "en", true
+	// only Simplified Chinese is available (Traditional Chinese locales such as zh-TW are not matched)
+	case lang == "zh" || lang == "zh-cn" || lang == "zh-sg" || strings.HasPrefix(lang, "zh-hans"):
+		return "zh-CN", true
+	}
+	return "", false
+}
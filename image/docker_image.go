@@ -2,6 +2,7 @@ package image
 
 import (
 	"LGM/filetree"
+	"LGM/i18n"
 	"LGM/utils"
 	"archive/tar"
 	"context"
@@ -25,6 +26,8 @@ func newDockerImageAnalyzer(imageId string) Analyzer {
 		jsonFiles: make(map[string][]byte),
 		layerMap:  make(map[string]*filetree.FileTree),
 		id:        imageId,
+		// the spooled layer tars by name, see SpoolLayers
+		spooledTars: make(map[string]string),
 	}
 }
 
@@ -56,6 +59,28 @@ func newDockerImageConfig(configBytes []byte) dockerImageConfig{
 	return imageConfig
 }
 
+// newOciAnnotations 返回OCI镜像索引及其第一个清单的注解（清单的注解优先），没有索引时返回nil。
+func newOciAnnotations(indexBytes []byte) map[string]string {
+	if indexBytes == nil {
+		return nil
+	}
+	var index ociImageIndex
+	if err := json.Unmarshal(indexBytes, &index); err != nil {
+		logrus.Errorf("unable to parse index.json: %+v", err)
+		return nil
+	}
+	annotations := make(map[string]string)
+	for key, value := range index.Annotations {
+		annotations[key] = value
+	}
+	if len(index.Manifests) > 0 {
+		for key, value := range index.Manifests[0].Annotations {
+			annotations[key] = value
+		}
+	}
+	return annotations
+}
+
 func (image *dockerImageAnalyzer) Fetch() (io.ReadCloser, error) {
 	var err error
 
@@ -117,7 +142,7 @@ func (image *dockerImageAnalyzer) Fetch() (io.ReadCloser, error) {
 	_, _, err = image.client.ImageInspectWithRaw(ctx, image.id)
 	if err != nil {
 		// don't use the API, the CLI has more informative output
-		fmt.Println("Image not available locally. Trying to pull '" + image.id + "'...")
+		fmt.Println(i18n.T("Image not available locally. Trying to pull '%s'...", image.id))
 		utils.RunDockerCmd("pull", image.id)
 	}
 
@@ -157,11 +182,18 @@ func (image *dockerImageAnalyzer) Parse(tarFile io.ReadCloser) error{
 				if err != nil {
 					return err
 				}
-				layerReader := tar.NewReader(tarReader)
-				err := image.processLayerTar(name, currentLayer, layerReader)
+				spoolReader, spoolDone, err := image.spoolLayer(name, tarReader)
 				if err != nil {
 					return err
 				}
+				layerReader := tar.NewReader(spoolReader)
+				err = image.processLayerTar(name, currentLayer, layerReader)
+				if err != nil {
+					return err
+				}
+				if err := spoolDone(); err != nil {
+					return err
+				}
 			} else if strings.HasSuffix(name, "json") {	//HasSuffix测试字符串是否以json后缀结尾。
 				fileBuffer, err := ioutil.ReadAll(tarReader)
 				if err != nil {
@@ -180,6 +212,7 @@ func (image *dockerImageAnalyzer) Analyze() (*AnalysisResult, error){
 
 	manifest := newDockerImageManifest(image.jsonFiles["manifest.json"])
 	config := newDockerImageConfig(image.jsonFiles[manifest.ConfigPath])
+	config.Config.Annotations = newOciAnnotations(image.jsonFiles["index.json"])
 
 	// build the content tree
 	for _, treeName := range manifest.LayerTarPaths {
@@ -207,16 +240,19 @@ func (image *dockerImageAnalyzer) Analyze() (*AnalysisResult, error){
 				break
 			}
 		}
+		layerHistIdx := -1
 		if histIdx < len(config.History) && !config.History[histIdx].EmptyLayer {
 			historyObj = config.History[histIdx]
+			layerHistIdx = histIdx
 			histIdx++
 		}
 
 		image.layers[layerIdx] = &dockerLayer{
-			history: historyObj,
-			index:   tarPathIdx,
-			tree:    image.trees[layerIdx],
-			tarPath: manifest.LayerTarPaths[tarPathIdx],
+			history:    historyObj,
+			index:      tarPathIdx,
+			tree:       tree,
+			tarPath:    manifest.LayerTarPaths[tarPathIdx],
+			historyIdx: layerHistIdx,
 		}
 		image.layers[layerIdx].history.Size = uint64(tree.FileSize)
 
@@ -242,7 +278,9 @@ func (image *dockerImageAnalyzer) Analyze() (*AnalysisResult, error){
 		wastedBytes += uint64(fileData.CumulativeSize)
 	}
 
-	return &AnalysisResult{
+	image.attributeWaste(inefficiencies)
+
+	result := &AnalysisResult{
 		Layers:            layers,
 		RefTrees:          image.trees,
 		Efficiency:        efficiency,
@@ -251,7 +289,58 @@ func (image *dockerImageAnalyzer) Analyze() (*AnalysisResult, error){
 		WastedBytes:       wastedBytes,
 		WastedUserPercent: float64(float64(wastedBytes) / float64(userSizeBytes)),
 		Inefficiencies:    inefficiencies,
-	}, nil
+		Config:            config.Config,
+		LayerFiles:        image.layerFiles(manifest.LayerTarPaths),
+		RepoTags:          manifest.RepoTags,
+		History:           newHistory(config.History, image.layers),
+	}
+	if archive := image.spooledArchive(manifest); archive != nil {
+		result.Slim = archive
+		result.Squash = archive
+	}
+	return result, nil
+}
+
+// newHistory 返回分析结果中的历史记录，记录每条记录对应的层（RefTrees中的索引）。
+func newHistory(entries []dockerImageHistoryEntry, layers []*dockerLayer) []HistoryEntry {
+	history := make([]HistoryEntry, len(entries))
+	for idx, entry := range entries {
+		history[idx] = HistoryEntry{CreatedBy: entry.CreatedBy, EmptyLayer: entry.EmptyLayer, Layer: -1}
+	}
+	for _, layer := range layers {
+		if layer.historyIdx >= 0 {
+			history[layer.historyIdx].Layer = layer.index
+		}
+	}
+	return history
+}
+
+// attributeWaste 将低效文件占用的空间分配到各层：每个副本计入其所在的层，被删除的目录的大小计入删除它的层（各层之和等于WastedBytes）。
+func (image *dockerImageAnalyzer) attributeWaste(inefficiencies filetree.EfficiencySlice) {
+	treeLayers := make(map[*filetree.FileTree]*dockerLayer)
+	for _, layer := range image.layers {
+		treeLayers[layer.tree] = layer
+	}
+	for _, data := range inefficiencies {
+		remaining := data.CumulativeSize
+		var whiteoutLayer *dockerLayer
+		for _, node := range data.Nodes {
+			layer, ok := treeLayers[node.Tree]
+			if !ok {
+				continue
+			}
+			if node.IsWhiteout() {
+				whiteoutLayer = layer
+				continue
+			}
+			layer.wastedBytes += uint64(node.Data.FileInfo.Size)
+			remaining -= node.Data.FileInfo.Size
+		}
+		// the size of a removed directory is only known to the whiteout
+		if whiteoutLayer != nil && remaining > 0 {
+			whiteoutLayer.wastedBytes += uint64(remaining)
+		}
+	}
 }
 
 func (image *dockerImageAnalyzer) processLayerTar(name string, layerIdx uint, reader *tar.Reader) error {
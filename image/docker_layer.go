@@ -1,10 +1,13 @@
 package image
 
 import (
+	"LGM/dockerfile"
 	"LGM/filetree"
+	"LGM/shell"
 	"fmt"
 	"github.com/dustin/go-humanize"
 	"strings"
+	"time"
 )
 
 const (
@@ -12,9 +15,14 @@ const (
 	LayerFormat = "%7s  %s"
 )
 
-// ShortId returns the truncated id of the current layer.
+// Command returns the instruction that created the current layer, normalized from the history (see NormalizeCommand).
 func (layer *dockerLayer) Command() string {
-	return strings.TrimPrefix(layer.history.CreatedBy, "/bin/sh -c ")
+	return NormalizeCommand(layer.history.CreatedBy)
+}
+
+// Instruction returns the Dockerfile instruction that produced the current layer (nil when no Dockerfile is mapped).
+func (layer *dockerLayer) Instruction() *dockerfile.Instruction {
+	return layer.instruction
 }
 
 // Tree returns the file tree representing the current layer.
@@ -27,6 +35,30 @@ func (layer *dockerLayer) Size() uint64 {
 	return layer.history.Size
 }
 
+// Created returns the creation time of the current layer (zero when the history is missing or malformed).
+func (layer *dockerLayer) Created() time.Time {
+	created, err := time.Parse(time.RFC3339Nano, layer.history.Created)
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
+
+// Author returns the author recorded in the history of the current layer.
+func (layer *dockerLayer) Author() string {
+	return layer.history.Author
+}
+
+// FileCount returns the number of files and directories in the current layer.
+func (layer *dockerLayer) FileCount() int {
+	return layer.tree.Size
+}
+
+// WastedBytes returns the bytes of the current layer that belong to inefficient files.
+func (layer *dockerLayer) WastedBytes() uint64 {
+	return layer.wastedBytes
+}
+
 // ShortId returns the truncated id of the current layer.
 func (layer *dockerLayer) TarId() string {
 	return strings.TrimSuffix(layer.tarPath, "/layer.tar")
@@ -55,13 +87,26 @@ func (layer *dockerLayer) ShortId() string {
 
 // String represents a layer in a columnar format.
 func (layer *dockerLayer) String() string {
-
-	if layer.index == 0 {
-		return fmt.Sprintf(LayerFormat,
-			humanize.Bytes(layer.Size()),
-			"FROM "+layer.ShortId())
-	}
 	return fmt.Sprintf(LayerFormat,
 		humanize.Bytes(layer.Size()),
-		layer.Command())
+		displayCommand(layer))
+}
+
+// displayCommand returns the command shown for the layer: the Dockerfile instruction when one is mapped,
+// otherwise the history command (the base image layer shows its id instead). A RUN is shortened to a summary of its commands.
+func displayCommand(layer Layer) string {
+	if instruction := layer.Instruction(); instruction != nil {
+		if instruction.Cmd == "RUN" && instruction.JSON == nil {
+			return "RUN " + shell.Summary(instruction.Args)
+		}
+		return instruction.Text()
+	}
+	if layer.Index() == 0 {
+		return "FROM " + layer.ShortId()
+	}
+	command := layer.Command()
+	if strings.HasPrefix(command, "RUN ") {
+		return "RUN " + shell.Summary(strings.TrimPrefix(command, "RUN "))
+	}
+	return command
 }
\ No newline at end of file
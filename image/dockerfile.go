@@ -0,0 +1,242 @@
+package image
+
+import (
+	"LGM/dockerfile"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HistoryEntry 是镜像历史记录中的一条，包括不对应任何层的记录（empty_layer，例如ENV或CMD）
+type HistoryEntry struct {
+	CreatedBy  string
+	EmptyLayer bool
+	// Layer 是记录对应的层（RefTrees中的索引），EmptyLayer为true时为-1
+	Layer int
+	// Instruction 是产生这条记录的Dockerfile指令（见MapDockerfile），没有对应的指令时为nil
+	Instruction *dockerfile.Instruction
+}
+
+var (
+	// buildArgsPattern 匹配RUN使用构建参数时历史记录中的 "|2 A=1 B=2 " 前缀
+	buildArgsPattern = regexp.MustCompile(`^\|(\d+)\s`)
+	// wordPattern 匹配比较指令与历史记录时使用的单词（引号、括号等符号不参与比较）
+	wordPattern = regexp.MustCompile(`[A-Za-z0-9._/:=@+~-]+`)
+)
+
+// MapDockerfile 将给定阶段（为空时为最后一个阶段）的指令与镜像的历史记录（包括empty_layer的记录）对齐，
+// 设置各条记录和各层对应的指令，返回对齐的指令数。基础镜像的历史记录没有对应的指令。
+func MapDockerfile(analysis *AnalysisResult, file *dockerfile.Dockerfile, target string) int {
+	stage := file.Stage(target)
+	if stage == nil {
+		return 0
+	}
+	chain := file.Chain(stage)
+
+	instructions := make([][]string, len(chain))
+	globals := file.GlobalArgs()
+	vars := make(map[string]string)
+	for idx, instruction := range chain {
+		if idx > 0 && instruction.Stage != chain[idx-1].Stage {
+			// the arguments of a stage are not inherited by the stages built on it, its environment is
+			vars = make(map[string]string)
+			for _, pair := range envPairs(chain[:idx]) {
+				vars[pair.Key] = dockerfile.Expand(pair.Value, vars, file.Escape)
+			}
+		}
+		instructions[idx] = words(dockerfile.Expand(strings.Join(instruction.Flags, " ")+" "+instruction.Args, vars, file.Escape))
+		if instruction.Cmd == "ARG" || instruction.Cmd == "ENV" {
+			for _, pair := range instruction.Pairs() {
+				if !pair.HasValue {
+					// "ARG name" takes the value of the global ARG of the same name
+					pair.Value = globals[pair.Key]
+					if value, ok := vars[pair.Key]; ok {
+						pair.Value = value
+					}
+				}
+				vars[pair.Key] = dockerfile.Expand(pair.Value, vars, file.Escape)
+			}
+		}
+	}
+
+	entries := make([][]string, len(analysis.History))
+	cmds := make([]string, len(analysis.History))
+	for idx, entry := range analysis.History {
+		var text string
+		cmds[idx], text = ParseCreatedBy(entry.CreatedBy)
+		entries[idx] = words(text)
+	}
+
+	// the best alignment keeps the order of both and maximizes the similarity of the aligned pairs,
+	// best[i][j] is the best score of chain[i:] aligned with the history[j:]
+	score := func(i, j int) float64 {
+		if chain[i].Cmd != cmds[j] {
+			return 0
+		}
+		return 1 + similarity(instructions[i], entries[j])
+	}
+	best := make([][]float64, len(chain)+1)
+	for i := range best {
+		best[i] = make([]float64, len(entries)+1)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for j := len(entries) - 1; j >= 0; j-- {
+			best[i][j] = math.Max(best[i+1][j], best[i][j+1])
+			if s := score(i, j); s > 0 && s+best[i+1][j+1] > best[i][j] {
+				best[i][j] = s + best[i+1][j+1]
+			}
+		}
+	}
+
+	for idx := range analysis.History {
+		analysis.History[idx].Instruction = nil
+	}
+	mapped := 0
+	for i, j := 0, 0; i < len(chain) && j < len(entries); {
+		switch {
+		case best[i][j] == best[i][j+1]:
+			// the base image's entries come first, so unmatched entries are skipped before instructions
+			j++
+		case best[i][j] == best[i+1][j]:
+			i++
+		default:
+			analysis.History[j].Instruction = chain[i]
+			mapped++
+			i++
+			j++
+		}
+	}
+
+	for _, layer := range analysis.Layers {
+		if layer, ok := layer.(*dockerLayer); ok {
+			layer.instruction = nil
+			if layer.historyIdx >= 0 {
+				layer.instruction = analysis.History[layer.historyIdx].Instruction
+			}
+		}
+	}
+	analysis.Dockerfile = file
+	return mapped
+}
+
+// LayerInstructions 返回构建给定层（RefTrees中的索引）的指令：上一层之后的empty_layer记录（例如ENV）和该层自身的记录对应的指令，
+// 最后一层还包括其后的记录（例如CMD）。没有对应的指令时返回nil。
+func (analysis *AnalysisResult) LayerInstructions(layerIdx int) []*dockerfile.Instruction {
+	var instructions []*dockerfile.Instruction
+	last := len(analysis.RefTrees) - 1
+	for _, entry := range analysis.History {
+		if !entry.EmptyLayer && entry.Layer < layerIdx {
+			instructions = instructions[:0]
+			continue
+		}
+		if !entry.EmptyLayer && entry.Layer > layerIdx {
+			break
+		}
+		if entry.Instruction != nil {
+			instructions = append(instructions, entry.Instruction)
+		}
+		if !entry.EmptyLayer && entry.Layer == layerIdx && layerIdx != last {
+			break
+		}
+	}
+	if len(instructions) == 0 {
+		return nil
+	}
+	return instructions
+}
+
+// ParseCreatedBy 返回历史记录（created_by）对应的指令名称和参数，支持经典构建器（"/bin/sh -c #(nop) ..."）和BuildKit（"RUN ... # buildkit"）的格式。
+func ParseCreatedBy(createdBy string) (string, string) {
+	text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(createdBy), "# buildkit"))
+	cmd := ""
+	if pos := strings.IndexFunc(text, func(r rune) bool { return r == ' ' || r == '\t' }); pos > 0 && dockerfile.IsInstruction(text[:pos]) && text[:pos] == strings.ToUpper(text[:pos]) {
+		cmd, text = text[:pos], strings.TrimSpace(text[pos:])
+	}
+	text = trimBuildArgs(text)
+	if strings.HasPrefix(text, "/bin/sh -c ") {
+		text = strings.TrimSpace(strings.TrimPrefix(text, "/bin/sh -c "))
+		if cmd == "" {
+			cmd = "RUN"
+		}
+	}
+	if strings.HasPrefix(text, "#(nop)") {
+		text = strings.TrimSpace(strings.TrimPrefix(text, "#(nop)"))
+		cmd = ""
+		if pos := strings.IndexAny(text, " \t"); pos > 0 {
+			cmd, text = strings.ToUpper(text[:pos]), strings.TrimSpace(text[pos:])
+		} else {
+			cmd, text = strings.ToUpper(text), ""
+		}
+	}
+	return cmd, text
+}
+
+// NormalizeCommand 返回历史记录（created_by）的规范形式："指令 参数"（例如 "RUN apt-get update"），
+// 去掉了 "/bin/sh -c"、"#(nop)"、构建参数（"|2 A=1 B=2"）和 "# buildkit" 标记。
+func NormalizeCommand(createdBy string) string {
+	cmd, text := ParseCreatedBy(createdBy)
+	if cmd == "" || text == "" {
+		return cmd + text
+	}
+	return cmd + " " + text
+}
+
+// trimBuildArgs 去掉历史记录开头的构建参数（"|2 A=1 B=2 "）。
+func trimBuildArgs(text string) string {
+	match := buildArgsPattern.FindStringSubmatch(text)
+	if match == nil {
+		return text
+	}
+	count, _ := strconv.Atoi(match[1])
+	rest := strings.TrimSpace(text[len(match[0]):])
+	for ; count > 0 && rest != ""; count-- {
+		pos := strings.IndexAny(rest, " \t")
+		if pos < 0 {
+			return ""
+		}
+		rest = strings.TrimSpace(rest[pos:])
+	}
+	return rest
+}
+
+// envPairs 返回给定指令中ENV设置的变量（构建在之前的阶段上的阶段继承这些变量，但不继承ARG）。
+func envPairs(chain []*dockerfile.Instruction) []dockerfile.Pair {
+	var pairs []dockerfile.Pair
+	for _, instruction := range chain {
+		if instruction.Cmd == "ENV" {
+			pairs = append(pairs, instruction.Pairs()...)
+		}
+	}
+	return pairs
+}
+
+// words 返回文本中参与比较的单词。
+func words(text string) []string {
+	return wordPattern.FindAllString(text, -1)
+}
+
+// similarity 返回两组单词的相似度（Jaccard系数，0到1）。
+func similarity(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	set := make(map[string]bool, len(a))
+	for _, word := range a {
+		set[word] = true
+	}
+	common, union := 0, len(set)
+	seen := make(map[string]bool, len(b))
+	for _, word := range b {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		if set[word] {
+			common++
+		} else {
+			union++
+		}
+	}
+	return float64(common) / float64(union)
+}
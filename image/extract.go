@@ -0,0 +1,348 @@
+package image
+
+import (
+	"LGM/filetree"
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExtractResult 是提取文件的结果
+type ExtractResult struct {
+	// Target 是提取的节点在目标目录中的路径
+	Target string
+	// Files 是提取的文件、目录和链接数
+	Files int
+	// Bytes 是写入的文件内容的字节数
+	Bytes int64
+	// Skipped 是没有提取的路径（设备文件、FIFO等）及原因
+	Skipped []string
+}
+
+// pendingFile 是等待从图层tar中写入的普通文件
+type pendingFile struct {
+	info filetree.FileInfo
+	out  string
+	// layer 是文件所在的图层
+	layer int
+}
+
+// extractor 保存一次提取的状态
+type extractor struct {
+	files  LayerFiles
+	trees  []*filetree.FileTree
+	result *ExtractResult
+	// asRoot 指示是否保留所有者（只有root可以修改所有者）
+	asRoot bool
+	// extracted 是已写入的普通文件（按树中的路径）写入的位置，用于创建硬链接
+	extracted map[string]string
+	// pending 是每一层中需要写入的普通文件（按tar中的路径），一个文件可以写入多个位置（硬链接）
+	pending map[int]map[string][]pendingFile
+	links   []pendingFile
+	dirs    []pendingFile
+}
+
+// Extract 将给定路径的节点（目录包括其所有内容）提取到dest目录中，与docker cp一样以节点的名称放在dest下（根目录的内容直接放在dest下）。
+// onlyLayer 为false时提取叠加 trees[0..layerIdx] 后的文件系统（已被删除的文件不提取），为true时只提取该层自身包含的文件。
+// 提取时保留模式和符号链接，硬链接在提取的文件之间重新创建，以root运行时还保留所有者。files 必须读取与trees相同的图层。
+func Extract(files LayerFiles, trees []*filetree.FileTree, layerIdx int, onlyLayer bool, nodePath, dest string) (*ExtractResult, error) {
+	if files == nil {
+		return nil, fmt.Errorf("the layer contents are not available")
+	}
+	if layerIdx < 0 || layerIdx >= len(trees) {
+		return nil, fmt.Errorf("invalid layer index %d (the image has %d layers)", layerIdx, len(trees))
+	}
+
+	var tree *filetree.FileTree
+	if onlyLayer {
+		tree = trees[layerIdx]
+	} else {
+		tree = filetree.StackTreeRange(trees, 0, layerIdx)
+	}
+	start, err := tree.GetNode(nodePath)
+	if err != nil {
+		return nil, err
+	}
+	if start.IsWhiteout() {
+		return nil, fmt.Errorf("path does not exist: %s", nodePath)
+	}
+
+	target, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+	if start != tree.Root {
+		target = filepath.Join(target, start.Name)
+	}
+	ex := &extractor{
+		files:     files,
+		trees:     trees,
+		result:    &ExtractResult{Target: target},
+		asRoot:    os.Geteuid() == 0,
+		extracted: make(map[string]string),
+		pending:   make(map[int]map[string][]pendingFile),
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, err
+	}
+
+	sourceLayer := func(node *filetree.FileNode) int {
+		if onlyLayer {
+			return layerIdx
+		}
+		return filetree.LayerOf(trees, node.Path(), layerIdx)
+	}
+	visitor := func(node *filetree.FileNode) error {
+		out := target
+		if node != start {
+			rel := strings.TrimPrefix(node.Path(), strings.TrimSuffix(start.Path(), "/"))
+			out = filepath.Join(target, filepath.FromSlash(rel))
+			if !strings.HasPrefix(out, target+string(filepath.Separator)) {
+				ex.skip(node.Path(), "outside of the target directory")
+				return nil
+			}
+		}
+		return ex.add(node, out, sourceLayer)
+	}
+	evaluator := func(node *filetree.FileNode) bool {
+		// the contents of a symlinked directory would be written through the link
+		return !node.IsWhiteout() && (node == start || node.Parent.Data.FileInfo.TypeFlag != tar.TypeSymlink)
+	}
+	if start == tree.Root {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return nil, err
+		}
+	}
+	if err := start.VisitDepthParentFirst(visitor, evaluator); err != nil {
+		return nil, err
+	}
+
+	if err := ex.writeFiles(); err != nil {
+		return nil, err
+	}
+	if err := ex.writeLinks(); err != nil {
+		return nil, err
+	}
+	// the directories get their mode last, a read only directory could not be written to before
+	for idx := len(ex.dirs) - 1; idx >= 0; idx-- {
+		if err := ex.setAttributes(ex.dirs[idx].out, ex.dirs[idx].info); err != nil {
+			return nil, err
+		}
+	}
+	return ex.result, nil
+}
+
+// add 提取给定的节点：创建目录和符号链接，普通文件和硬链接留到之后写入。
+func (ex *extractor) add(node *filetree.FileNode, out string, sourceLayer func(*filetree.FileNode) int) error {
+	info := node.Data.FileInfo
+	switch {
+	case info.IsDir || info.TypeFlag == tar.TypeDir || (info.TypeFlag == 0 && len(node.Children) > 0):
+		if info.TypeFlag == 0 {
+			// a parent directory without an entry of its own in the layer
+			info.Mode = os.ModeDir | 0755
+			info.Uid, info.Gid = 0, 0
+		}
+		if err := replaceNonDir(out); err != nil {
+			return err
+		}
+		// the directory stays writable until its contents are extracted
+		if err := os.MkdirAll(out, 0700); err != nil {
+			return err
+		}
+		ex.dirs = append(ex.dirs, pendingFile{info: info, out: out})
+	case info.TypeFlag == tar.TypeSymlink:
+		if err := replaceNonDir(out); err != nil {
+			return err
+		}
+		if err := os.Symlink(info.LinkName, out); err != nil {
+			return err
+		}
+		if ex.asRoot {
+			if err := os.Lchown(out, info.Uid, info.Gid); err != nil {
+				return err
+			}
+		}
+	case info.TypeFlag == tar.TypeLink:
+		ex.links = append(ex.links, pendingFile{info: info, out: out, layer: sourceLayer(node)})
+		return nil
+	case info.TypeFlag == tar.TypeReg || info.TypeFlag == tar.TypeRegA:
+		layerIdx := sourceLayer(node)
+		if layerIdx < 0 {
+			ex.skip(node.Path(), "not found in the layers")
+			return nil
+		}
+		ex.addPending(layerIdx, node.Path(), pendingFile{info: info, out: out, layer: layerIdx})
+	default:
+		ex.skip(node.Path(), "not a regular file, directory or link")
+		return nil
+	}
+	ex.result.Files++
+	return nil
+}
+
+// addPending 记录需要从给定图层的tar中写入的文件。
+func (ex *extractor) addPending(layerIdx int, path string, file pendingFile) {
+	if ex.pending[layerIdx] == nil {
+		ex.pending[layerIdx] = make(map[string][]pendingFile)
+	}
+	ex.pending[layerIdx][path] = append(ex.pending[layerIdx][path], file)
+}
+
+// writeFiles 依次读取需要的图层，写入其中的普通文件。
+func (ex *extractor) writeFiles() error {
+	var layers []int
+	for layerIdx := range ex.pending {
+		layers = append(layers, layerIdx)
+	}
+	sort.Ints(layers)
+	for _, layerIdx := range layers {
+		pending := ex.pending[layerIdx]
+		written := make(map[string]bool)
+		err := ex.files.Walk(layerIdx, func(header *tar.Header, reader io.Reader) error {
+			path := cleanTarPath(header.Name)
+			targets := pending[path]
+			if len(targets) == 0 || (header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA) {
+				return nil
+			}
+			// a path that is in the tar more than once is written again, the last entry wins
+			if err := ex.writeFile(targets[0], reader); err != nil {
+				return err
+			}
+			for _, target := range targets[1:] {
+				if err := ex.link(targets[0].out, target); err != nil {
+					return err
+				}
+			}
+			written[path] = true
+			ex.extracted[path] = targets[0].out
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for path := range pending {
+			if !written[path] {
+				ex.skip(path, fmt.Sprintf("not found in layer %d", layerIdx))
+				ex.result.Files -= len(pending[path])
+			}
+		}
+	}
+	return nil
+}
+
+// writeFile 将reader的内容写入给定的文件并设置其属性。
+func (ex *extractor) writeFile(file pendingFile, reader io.Reader) error {
+	if err := replaceNonDir(file.out); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(file.out, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(out, reader)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	ex.result.Bytes += written
+	return ex.setAttributes(file.out, file.info)
+}
+
+// writeLinks 创建硬链接：目标已提取时链接到提取的文件，否则从图层中写入目标的内容。
+func (ex *extractor) writeLinks() error {
+	var missing []pendingFile
+	for _, file := range ex.links {
+		target, ok := ex.extracted[cleanTarPath(file.info.LinkName)]
+		if !ok {
+			missing = append(missing, file)
+			continue
+		}
+		if err := ex.link(target, file); err != nil {
+			return err
+		}
+		ex.result.Files++
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// a hard link always refers to a file of the same layer
+	ex.pending = make(map[int]map[string][]pendingFile)
+	for _, file := range missing {
+		target := ex.resolveLink(file.layer, file.info.LinkName)
+		if target == "" {
+			ex.skip(cleanTarPath(file.info.Path), "the link target is not a regular file")
+			continue
+		}
+		ex.addPending(file.layer, target, file)
+		ex.result.Files++
+	}
+	return ex.writeFiles()
+}
+
+// resolveLink 返回给定图层中硬链接（可能经过其他硬链接）最终指向的普通文件，找不到时返回空字符串。
+func (ex *extractor) resolveLink(layerIdx int, linkName string) string {
+	if layerIdx < 0 {
+		return ""
+	}
+	target := cleanTarPath(linkName)
+	for depth := 0; depth < maxLinkDepth; depth++ {
+		node, err := ex.trees[layerIdx].GetNode(target)
+		if err != nil {
+			return ""
+		}
+		switch node.Data.FileInfo.TypeFlag {
+		case tar.TypeReg, tar.TypeRegA:
+			return target
+		case tar.TypeLink:
+			target = cleanTarPath(node.Data.FileInfo.LinkName)
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// link 将file创建为指向已写入的target的硬链接。
+func (ex *extractor) link(target string, file pendingFile) error {
+	if err := replaceNonDir(file.out); err != nil {
+		return err
+	}
+	return os.Link(target, file.out)
+}
+
+// setAttributes 设置文件的所有者（以root运行时）和模式。所有者先于模式设置，修改所有者会清除setuid和setgid位。
+func (ex *extractor) setAttributes(out string, info filetree.FileInfo) error {
+	if ex.asRoot {
+		if err := os.Lchown(out, info.Uid, info.Gid); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(out, info.Mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+}
+
+// skip 记录没有提取的路径。
+func (ex *extractor) skip(path, reason string) {
+	ex.result.Skipped = append(ex.result.Skipped, fmt.Sprintf("%s: %s", path, reason))
+}
+
+// replaceNonDir 删除给定位置已有的文件或链接（目录保留），以便在那里写入新的内容。
+func replaceNonDir(out string) error {
+	info, err := os.Lstat(out)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	return os.Remove(out)
+}
@@ -0,0 +1,213 @@
+package image
+
+import (
+	"LGM/theme"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+)
+
+// LayerColumn 描述图层窗格中的一列：名称（配置项 layer.columns 使用）、标题、宽度以及取值。
+type LayerColumn struct {
+	Name  string
+	Title string
+	// Width 是列的宽度（字符），为0时不限宽度（通常是最后一列）
+	Width int
+	// RightAlign 指示值是否右对齐（数值列）
+	RightAlign bool
+	value      func(layer Layer, imageSize uint64) string
+}
+
+const (
+	// CreatedFormat 是created列中时间的格式
+	CreatedFormat = "2006-01-02 15:04"
+	// barWidth 是bar列的宽度
+	barWidth = 10
+)
+
+// LayerColumns 是所有可用的列（按配置项 layer.columns 的顺序显示）。
+var LayerColumns = []LayerColumn{
+	{Name: "created", Title: "Created", Width: len(CreatedFormat), value: func(layer Layer, _ uint64) string {
+		if layer.Created().IsZero() {
+			return "-"
+		}
+		return layer.Created().Local().Format(CreatedFormat)
+	}},
+	{Name: "age", Title: "Age", Width: 5, RightAlign: true, value: func(layer Layer, _ uint64) string {
+		if layer.Created().IsZero() {
+			return "-"
+		}
+		return shortAge(time.Since(layer.Created()))
+	}},
+	{Name: "id", Title: "Digest", Width: 12, value: func(layer Layer, _ uint64) string {
+		id := strings.TrimPrefix(layer.Id(), "sha256:")
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		return id
+	}},
+	{Name: "author", Title: "Author", Width: 16, value: func(layer Layer, _ uint64) string {
+		return layer.Author()
+	}},
+	{Name: "files", Title: "Files", Width: 7, RightAlign: true, value: func(layer Layer, _ uint64) string {
+		return strconv.Itoa(layer.FileCount())
+	}},
+	{Name: "size", Title: "Size", Width: 7, RightAlign: true, value: func(layer Layer, _ uint64) string {
+		return humanize.Bytes(layer.Size())
+	}},
+	{Name: "bar", Title: "Share", Width: barWidth, value: func(layer Layer, imageSize uint64) string {
+		return sizeBar(layer.Size(), imageSize)
+	}},
+	{Name: "wasted", Title: "Wasted", Width: 7, RightAlign: true, value: func(layer Layer, _ uint64) string {
+		return humanize.Bytes(layer.WastedBytes())
+	}},
+	{Name: "line", Title: "Line", Width: 5, RightAlign: true, value: func(layer Layer, _ uint64) string {
+		if layer.Instruction() == nil {
+			return "-"
+		}
+		return strconv.Itoa(layer.Instruction().Line)
+	}},
+	{Name: "command", Title: "Command", value: func(layer Layer, _ uint64) string {
+		return displayCommand(layer)
+	}},
+}
+
+// DefaultLayerColumns 是配置项 layer.columns 的默认值（与LayerFormat相同的列）。
+var DefaultLayerColumns = []string{"size", "command"}
+
+// ParseLayerColumns 返回给定名称的列（按给定的顺序）。
+func ParseLayerColumns(names []string) ([]LayerColumn, error) {
+	columns := make([]LayerColumn, 0, len(names))
+	for _, name := range names {
+		column, err := lookupLayerColumn(strings.ToLower(strings.TrimSpace(name)))
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no layer columns given (available: %s)", strings.Join(LayerColumnNames(), ", "))
+	}
+	return columns, nil
+}
+
+// WithLineColumn 返回加入了line列的列（在command列之前，没有command列时在最后），已有line列时原样返回。
+func WithLineColumn(columns []LayerColumn) []LayerColumn {
+	position := len(columns)
+	for idx, column := range columns {
+		switch column.Name {
+		case "line":
+			return columns
+		case "command":
+			position = idx
+		}
+	}
+	line, _ := lookupLayerColumn("line")
+	result := append([]LayerColumn{}, columns[:position]...)
+	result = append(result, line)
+	return append(result, columns[position:]...)
+}
+
+// LayerColumnNames 返回所有列的名称。
+func LayerColumnNames() []string {
+	names := make([]string, len(LayerColumns))
+	for idx, column := range LayerColumns {
+		names[idx] = column.Name
+	}
+	return names
+}
+
+func lookupLayerColumn(name string) (LayerColumn, error) {
+	for _, column := range LayerColumns {
+		if column.Name == name {
+			return column, nil
+		}
+	}
+	return LayerColumn{}, fmt.Errorf("unknown layer column '%s' (available: %s)", name, strings.Join(LayerColumnNames(), ", "))
+}
+
+// Value 返回图层在该列中的值（未填充或截断），imageSize 是bar列的参照大小（整个镜像的大小）。
+func (column LayerColumn) Value(layer Layer, imageSize uint64) string {
+	return column.value(layer, imageSize)
+}
+
+// FormatLayerRow 按给定的列格式化一个图层，imageSize 是bar列的参照大小（整个镜像的大小）。
+func FormatLayerRow(columns []LayerColumn, layer Layer, imageSize uint64) string {
+	values := make([]string, len(columns))
+	for idx, column := range columns {
+		values[idx] = column.value(layer, imageSize)
+	}
+	return formatColumns(columns, values)
+}
+
+// FormatLayerHeader 按给定的列格式化标题行，title 翻译各列的标题。
+func FormatLayerHeader(columns []LayerColumn, title func(string) string) string {
+	values := make([]string, len(columns))
+	for idx, column := range columns {
+		values[idx] = title(column.Title)
+	}
+	return formatColumns(columns, values)
+}
+
+// formatColumns 按列宽填充或截断各列的值（与fmt的宽度一样按字符计算），列之间以两个空格分隔。
+func formatColumns(columns []LayerColumn, values []string) string {
+	cells := make([]string, len(columns))
+	for idx, column := range columns {
+		value := values[idx]
+		if column.Width == 0 {
+			cells[idx] = value
+			continue
+		}
+		if runes := []rune(value); len(runes) > column.Width {
+			value = string(runes[:column.Width])
+		}
+		padding := strings.Repeat(" ", column.Width-utf8.RuneCountInString(value))
+		if column.RightAlign {
+			cells[idx] = padding + value
+		} else {
+			cells[idx] = value + padding
+		}
+	}
+	return strings.TrimRight(strings.Join(cells, "  "), " ")
+}
+
+// sizeBar 返回表示给定大小占镜像大小比例的条形（非空的图层至少占一格）。
+func sizeBar(size, imageSize uint64) string {
+	filled := 0
+	if imageSize > 0 {
+		filled = int(float64(size)/float64(imageSize)*barWidth + 0.5)
+	}
+	if filled == 0 && size > 0 {
+		filled = 1
+	}
+	if filled > barWidth {
+		filled = barWidth
+	}
+	return strings.Repeat(theme.Glyphs.BarFull, filled) + strings.Repeat(theme.Glyphs.BarEmpty, barWidth-filled)
+}
+
+// shortAge 以紧凑的形式返回给定的时长（例如 "45s", "12m", "5h", "3d", "7mo", "2y"）。
+func shortAge(age time.Duration) string {
+	const day = 24 * time.Hour
+	if age < 0 {
+		age = 0
+	}
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age/time.Second))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age/time.Minute))
+	case age < day:
+		return fmt.Sprintf("%dh", int(age/time.Hour))
+	case age < 30*day:
+		return fmt.Sprintf("%dd", int(age/day))
+	case age < 365*day:
+		return fmt.Sprintf("%dmo", int(age/(30*day)))
+	default:
+		return fmt.Sprintf("%dy", int(age/(365*day)))
+	}
+}
@@ -0,0 +1,192 @@
+package image
+
+import (
+	"LGM/utils"
+	"archive/tar"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// SpoolLayers 指示解析镜像时是否将每一层的tar保存到临时目录（程序退出时删除），以便之后通过AnalysisResult.LayerFiles读取文件内容。
+var SpoolLayers = false
+
+// SpoolLimit 是保存的图层tar的总大小上限（0为不限制）。超过上限时删除已保存的图层，AnalysisResult.LayerFiles为nil。
+var SpoolLimit uint64
+
+// LayerFiles 读取镜像各层中的文件内容。
+type LayerFiles interface {
+	// ReadFile 返回给定图层（AnalysisResult.RefTrees中的索引）中给定路径的文件的前limit个字节
+	ReadFile(layerIdx int, filePath string, limit int64) ([]byte, error)
+	// Walk 按顺序对给定图层tar中的每个条目调用fn，reader读取条目的内容。fn返回错误时停止并返回该错误
+	Walk(layerIdx int, fn func(header *tar.Header, reader io.Reader) error) error
+}
+
+// spooledLayers 从解析时保存的图层tar中读取文件
+type spooledLayers struct {
+	// tarPaths 是每一层（按RefTrees的顺序）保存的tar文件
+	tarPaths []string
+}
+
+// maxLinkDepth 是解析硬链接时最多跟随的次数
+const maxLinkDepth = 8
+
+// spoolLayer 返回一个读取给定图层tar的reader，读取的内容同时写入临时目录中的文件（SpoolLayers为false或已超过SpoolLimit时直接返回给定的reader）。
+// 第一次保存时创建临时目录，并注册退出时删除它。done 在图层读取完成后调用，它将图层的其余部分（tar的结尾）写入文件。
+func (image *dockerImageAnalyzer) spoolLayer(name string, reader io.Reader) (layerReader io.Reader, done func() error, err error) {
+	if !SpoolLayers || image.spoolFull {
+		return reader, func() error { return nil }, nil
+	}
+	if image.spoolDir == "" {
+		image.spoolDir, err = ioutil.TempDir("", "LGM-layers-")
+		if err != nil {
+			return nil, nil, err
+		}
+		dir := image.spoolDir
+		utils.OnCleanUp(func() { os.RemoveAll(dir) })
+	}
+
+	file, err := os.Create(filepath.Join(image.spoolDir, fmt.Sprintf("%d.tar", len(image.spooledTars))))
+	if err != nil {
+		return nil, nil, err
+	}
+	image.spooledTars[name] = file.Name()
+	writer := &spoolWriter{image: image, file: file}
+	done = func() error {
+		// the tar reader stops at the end of the archive, the padding after it is copied as well
+		_, err := io.Copy(writer, reader)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil && image.spoolFull {
+			err = image.dropSpool()
+		}
+		return err
+	}
+	return io.TeeReader(reader, writer), done, nil
+}
+
+// spoolWriter 将图层tar写入保存的文件并计入已保存的字节数，超过SpoolLimit之后丢弃写入的内容。
+type spoolWriter struct {
+	image *dockerImageAnalyzer
+	file  *os.File
+}
+
+func (writer *spoolWriter) Write(data []byte) (int, error) {
+	if writer.image.spoolFull {
+		return len(data), nil
+	}
+	writer.image.spooledBytes += uint64(len(data))
+	if SpoolLimit > 0 && writer.image.spooledBytes > SpoolLimit {
+		// the layer is still parsed, only the copy is dropped
+		writer.image.spoolFull = true
+		return len(data), nil
+	}
+	return writer.file.Write(data)
+}
+
+// dropSpool 删除已保存的图层tar（超过SpoolLimit时），之后的图层不再保存。
+func (image *dockerImageAnalyzer) dropSpool() error {
+	logrus.Infof("the image layers exceed the spool limit of %d bytes, the spooled layers are removed", SpoolLimit)
+	image.spooledTars = make(map[string]string)
+	return os.RemoveAll(image.spoolDir)
+}
+
+// layerFiles 返回按给定图层顺序读取保存的图层的LayerFiles，没有保存图层时返回nil。
+func (image *dockerImageAnalyzer) layerFiles(tarNames []string) LayerFiles {
+	if len(image.spooledTars) == 0 {
+		return nil
+	}
+	layers := &spooledLayers{tarPaths: make([]string, len(tarNames))}
+	for idx, name := range tarNames {
+		layers.tarPaths[idx] = image.spooledTars[name]
+	}
+	return layers
+}
+
+// ReadFile 在图层的tar中查找给定路径的文件（硬链接读取其目标）并返回其前limit个字节。
+func (layers *spooledLayers) ReadFile(layerIdx int, filePath string, limit int64) ([]byte, error) {
+	file, err := layers.open(layerIdx)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	target := cleanTarPath(filePath)
+	for depth := 0; depth < maxLinkDepth; depth++ {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		// the file is seekable, so the tar reader skips the contents of the other files
+		reader := tar.NewReader(file)
+		header, err := findTarEntry(reader, target)
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeLink {
+			target = cleanTarPath(header.Linkname)
+			continue
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			return nil, fmt.Errorf("%s is not a regular file", filePath)
+		}
+		return ioutil.ReadAll(io.LimitReader(reader, limit))
+	}
+	return nil, fmt.Errorf("too many links: %s", filePath)
+}
+
+// Walk 依次将图层tar中的每个条目交给fn。
+func (layers *spooledLayers) Walk(layerIdx int, fn func(header *tar.Header, reader io.Reader) error) error {
+	file, err := layers.open(layerIdx)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(header, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// open 打开给定图层保存的tar。
+func (layers *spooledLayers) open(layerIdx int) (*os.File, error) {
+	if layerIdx < 0 || layerIdx >= len(layers.tarPaths) || layers.tarPaths[layerIdx] == "" {
+		return nil, fmt.Errorf("layer %d is not available", layerIdx)
+	}
+	return os.Open(layers.tarPaths[layerIdx])
+}
+
+// findTarEntry 将reader移动到给定路径的条目并返回其头部。
+func findTarEntry(reader *tar.Reader, target string) (*tar.Header, error) {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("path does not exist: %s", target)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if cleanTarPath(header.Name) == target {
+			return header, nil
+		}
+	}
+}
+
+// cleanTarPath 将tar中的条目名称（例如 "./etc/motd"）和树中的路径（例如 "/etc/motd"）统一为相同的形式。
+func cleanTarPath(name string) string {
+	return path.Clean("/" + name)
+}
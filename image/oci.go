@@ -0,0 +1,160 @@
+package image
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ArchiveFormat 是写入的镜像归档的格式
+type ArchiveFormat string
+
+const (
+	// DockerArchive 是docker save的格式（manifest.json、<diff id>/layer.tar 和 <config digest>.json）
+	DockerArchive ArchiveFormat = "docker"
+	// OCIArchive 是OCI镜像布局（oci-layout、index.json 和 blobs/sha256），另外写入manifest.json，以便docker load也可以加载
+	OCIArchive ArchiveFormat = "oci"
+)
+
+// ArchiveFormats 是支持的镜像归档格式的名称
+var ArchiveFormats = []string{string(DockerArchive), string(OCIArchive)}
+
+// ParseArchiveFormat 解析镜像归档格式的名称（docker或oci，不区分大小写）。
+func ParseArchiveFormat(name string) (ArchiveFormat, error) {
+	format := ArchiveFormat(strings.ToLower(strings.TrimSpace(name)))
+	switch format {
+	case DockerArchive, OCIArchive:
+		return format, nil
+	}
+	return "", fmt.Errorf("must be one of %s", strings.Join(ArchiveFormats, ", "))
+}
+
+const (
+	ociLayoutVersion     = "1.0.0"
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociConfigMediaType   = "application/vnd.oci.image.config.v1+json"
+	ociLayerMediaType    = "application/vnd.oci.image.layer.v1.tar"
+	// ociRefNameAnnotation 是标签的名称（OCI），ociImageNameAnnotation 是完整的镜像名称（containerd和docker load使用）
+	ociRefNameAnnotation   = "org.opencontainers.image.ref.name"
+	ociImageNameAnnotation = "io.containerd.image.name"
+)
+
+// ociDescriptor 是OCI中指向一个blob的描述符
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// writeOCIArchive 将给定的图层tar（未压缩，diffIds是它们的摘要）、配置和标签写为OCI镜像布局的tar。
+func writeOCIArchive(out io.Writer, layerPaths, diffIds []string, config []byte, tags []string) error {
+	writer := tar.NewWriter(out)
+	layout, err := json.Marshal(map[string]string{"imageLayoutVersion": ociLayoutVersion})
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveEntry(writer, "oci-layout", layout); err != nil {
+		return err
+	}
+
+	manifest := ociManifest{SchemaVersion: 2, MediaType: ociManifestMediaType}
+	// the docker manifest lists the same blobs, so that docker load can read the archive as well
+	dockerManifest := dockerImageManifest{RepoTags: append([]string{}, tags...)}
+	written := make(map[string]bool)
+	for idx, layerPath := range layerPaths {
+		info, err := os.Stat(layerPath)
+		if err != nil {
+			return err
+		}
+		name := blobPath(diffIds[idx])
+		manifest.Layers = append(manifest.Layers, ociDescriptor{MediaType: ociLayerMediaType, Digest: diffIds[idx], Size: info.Size()})
+		dockerManifest.LayerTarPaths = append(dockerManifest.LayerTarPaths, name)
+		if written[name] {
+			continue
+		}
+		written[name] = true
+		if err := writeArchiveFile(writer, name, layerPath); err != nil {
+			return err
+		}
+	}
+
+	manifest.Config, err = writeBlob(writer, ociConfigMediaType, config)
+	if err != nil {
+		return err
+	}
+	dockerManifest.ConfigPath = blobPath(manifest.Config.Digest)
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	manifestDescriptor, err := writeBlob(writer, ociManifestMediaType, manifestBytes)
+	if err != nil {
+		return err
+	}
+
+	// every tag refers to the same manifest
+	index := ociIndex{SchemaVersion: 2, MediaType: ociIndexMediaType}
+	for _, tag := range tags {
+		descriptor := manifestDescriptor
+		descriptor.Annotations = map[string]string{ociImageNameAnnotation: tag, ociRefNameAnnotation: tagName(tag)}
+		index.Manifests = append(index.Manifests, descriptor)
+	}
+	if len(tags) == 0 {
+		index.Manifests = append(index.Manifests, manifestDescriptor)
+	}
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveEntry(writer, "index.json", indexBytes); err != nil {
+		return err
+	}
+	dockerManifestBytes, err := json.Marshal([]dockerImageManifest{dockerManifest})
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveEntry(writer, "manifest.json", dockerManifestBytes); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// writeBlob 将给定的内容写为blob，返回它的描述符。
+func writeBlob(writer *tar.Writer, mediaType string, data []byte) (ociDescriptor, error) {
+	sum := sha256.Sum256(data)
+	descriptor := ociDescriptor{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))}
+	return descriptor, writeArchiveEntry(writer, blobPath(descriptor.Digest), data)
+}
+
+// blobPath 返回给定摘要（"sha256:..."）的blob在OCI镜像布局中的路径。
+func blobPath(digest string) string {
+	return "blobs/sha256/" + strings.TrimPrefix(digest, "sha256:")
+}
+
+// tagName 返回镜像名称中的标签（"ubuntu:22.04" 中的 "22.04"，没有标签时为 "latest"）。
+func tagName(image string) string {
+	if pos := strings.LastIndex(image, ":"); pos > strings.LastIndex(image, "/") {
+		return image[pos+1:]
+	}
+	return "latest"
+}
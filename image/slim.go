@@ -0,0 +1,361 @@
+package image
+
+import (
+	"LGM/filetree"
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SlimArchive 将删除了部分文件的镜像写为docker-archive或OCI镜像布局（两者都可以用docker load加载）。
+type SlimArchive interface {
+	// WriteSlim 从镜像的各层中删除与规则匹配的文件，并将新的镜像（标签为tags）以给定的格式写入out
+	WriteSlim(out io.Writer, format ArchiveFormat, rules *filetree.RemovalRules, tags []string) (*SlimResult, error)
+}
+
+// SlimResult 是写入精简镜像的结果
+type SlimResult struct {
+	// RemovedEntries 是从各层中删除的条目数（包括目录和不再需要的whiteout）
+	RemovedEntries int
+	// ChangedLayers 是被重写的层数，其余的层原样写入（docker load时与原镜像共享）
+	ChangedLayers int
+	// OriginalSize 和 Size 是原镜像和新镜像各层tar的大小之和
+	OriginalSize int64
+	Size         int64
+	Tags         []string
+}
+
+// spooledArchive 使用解析时保存的图层tar和镜像的配置写入精简或合并了图层的镜像
+type spooledArchive struct {
+	layers *spooledLayers
+	config []byte
+}
+
+// LoadRemovalRules 读取slim规则文件（YAML，remove列出要删除的路径通配模式）。
+func LoadRemovalRules(file string) (*filetree.RemovalRules, error) {
+	rules := viper.New()
+	rules.SetConfigFile(file)
+	if err := rules.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return filetree.NewRemovalRules(rules.GetStringSlice("remove"))
+}
+
+// SaveRemovalRules 将规则写入slim规则文件。
+func SaveRemovalRules(file string, rules *filetree.RemovalRules) error {
+	saved := viper.New()
+	saved.Set("remove", rules.Patterns())
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return saved.WriteConfigAs(file)
+}
+
+// SlimTags 返回精简镜像的默认标签：原镜像的每个标签加上 "-slim" 后缀（例如 ubuntu:latest-slim）。
+func SlimTags(repoTags []string) []string {
+	return suffixTags(repoTags, "-slim")
+}
+
+// suffixTags 返回给原镜像的每个标签加上给定后缀后的标签。
+func suffixTags(repoTags []string, suffix string) []string {
+	var tags []string
+	for _, tag := range repoTags {
+		tags = append(tags, tag+suffix)
+	}
+	return tags
+}
+
+// spooledArchive 返回写入精简或合并了图层的镜像的spooledArchive，没有保存图层时返回nil。
+func (image *dockerImageAnalyzer) spooledArchive(manifest dockerImageManifest) *spooledArchive {
+	layers, ok := image.layerFiles(manifest.LayerTarPaths).(*spooledLayers)
+	if !ok {
+		return nil
+	}
+	return &spooledArchive{layers: layers, config: image.jsonFiles[manifest.ConfigPath]}
+}
+
+// WriteSlim 依次重写各层（没有变化的层原样保留），然后写入新的配置（更新diff_ids并在历史记录中添加一条说明）和清单。
+func (archive *spooledArchive) WriteSlim(out io.Writer, format ArchiveFormat, rules *filetree.RemovalRules, tags []string) (*SlimResult, error) {
+	config, rootFs, err := archive.parseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	workDir, err := ioutil.TempDir("", "LGM-slim-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	result := &SlimResult{Tags: tags}
+	layerPaths := make([]string, len(archive.layers.tarPaths))
+	for idx, tarPath := range archive.layers.tarPaths {
+		info, err := os.Stat(tarPath)
+		if err != nil {
+			return nil, err
+		}
+		result.OriginalSize += info.Size()
+
+		rewritten := filepath.Join(workDir, fmt.Sprintf("%d.tar", idx))
+		removed, digest, err := rewriteLayer(tarPath, rewritten, rules, workDir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot rewrite layer %d: %v", idx, err)
+		}
+		if removed == 0 {
+			layerPaths[idx] = tarPath
+		} else {
+			layerPaths[idx] = rewritten
+			rootFs.DiffIds[idx] = digest
+			result.RemovedEntries += removed
+			result.ChangedLayers++
+		}
+		if info, err = os.Stat(layerPaths[idx]); err != nil {
+			return nil, err
+		}
+		result.Size += info.Size()
+	}
+
+	// an image without changes keeps its config, and so its id
+	configBytes := archive.config
+	if result.ChangedLayers > 0 {
+		if configBytes, err = slimConfig(config, rootFs, rules, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, writeImage(out, format, layerPaths, rootFs.DiffIds, configBytes, tags)
+}
+
+// parseConfig 解析镜像的配置，返回其各部分和rootfs（diff_ids必须与图层一一对应）。
+func (archive *spooledArchive) parseConfig() (map[string]json.RawMessage, dockerRootFs, error) {
+	var config map[string]json.RawMessage
+	var rootFs dockerRootFs
+	if err := json.Unmarshal(archive.config, &config); err != nil {
+		return nil, rootFs, fmt.Errorf("cannot parse the image config: %v", err)
+	}
+	if err := json.Unmarshal(config["rootfs"], &rootFs); err != nil {
+		return nil, rootFs, fmt.Errorf("cannot parse the image config: %v", err)
+	}
+	if len(rootFs.DiffIds) != len(archive.layers.tarPaths) {
+		return nil, rootFs, fmt.Errorf("the image config lists %d layers, the manifest %d", len(rootFs.DiffIds), len(archive.layers.tarPaths))
+	}
+	return config, rootFs, nil
+}
+
+// writeImage 将给定的图层tar（diffIds是它们的diff id）、配置和清单写为给定格式的镜像归档。
+func writeImage(out io.Writer, format ArchiveFormat, layerPaths, diffIds []string, config []byte, tags []string) error {
+	if format == OCIArchive {
+		return writeOCIArchive(out, layerPaths, diffIds, config, tags)
+	}
+	return writeDockerArchive(out, layerPaths, diffIds, config, tags)
+}
+
+// writeDockerArchive 将给定的图层tar、配置和清单写为docker-archive。
+func writeDockerArchive(out io.Writer, layerPaths, diffIds []string, config []byte, tags []string) error {
+	// the names follow docker save: <diff id>/layer.tar and <config digest>.json
+	writer := tar.NewWriter(out)
+	manifest := dockerImageManifest{RepoTags: append([]string{}, tags...)}
+	written := make(map[string]bool)
+	for idx, layerPath := range layerPaths {
+		name := strings.TrimPrefix(diffIds[idx], "sha256:") + "/layer.tar"
+		manifest.LayerTarPaths = append(manifest.LayerTarPaths, name)
+		if written[name] {
+			continue
+		}
+		written[name] = true
+		if err := writeArchiveFile(writer, name, layerPath); err != nil {
+			return err
+		}
+	}
+	configDigest := sha256.Sum256(config)
+	manifest.ConfigPath = hex.EncodeToString(configDigest[:]) + ".json"
+	if err := writeArchiveEntry(writer, manifest.ConfigPath, config); err != nil {
+		return err
+	}
+	manifestBytes, err := json.Marshal([]dockerImageManifest{manifest})
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveEntry(writer, "manifest.json", manifestBytes); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// slimConfig 返回更新了diff_ids并在历史记录中添加了一条说明（不对应任何层）的镜像配置。
+func slimConfig(config map[string]json.RawMessage, rootFs dockerRootFs, rules *filetree.RemovalRules, result *SlimResult) ([]byte, error) {
+	var err error
+	if config["rootfs"], err = json.Marshal(rootFs); err != nil {
+		return nil, err
+	}
+	var history []json.RawMessage
+	if raw, ok := config["history"]; ok {
+		if err := json.Unmarshal(raw, &history); err != nil {
+			return nil, fmt.Errorf("cannot parse the image history: %v", err)
+		}
+	}
+	entry, err := json.Marshal(map[string]interface{}{
+		"created":     time.Now().UTC().Format(time.RFC3339Nano),
+		"created_by":  "LGM slim: remove " + strings.Join(rules.Patterns(), " "),
+		"comment":     fmt.Sprintf("%d entries removed from %d layers", result.RemovedEntries, result.ChangedLayers),
+		"empty_layer": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if config["history"], err = json.Marshal(append(history, entry)); err != nil {
+		return nil, err
+	}
+	return json.Marshal(config)
+}
+
+// rewriteLayer 将图层tar中不被规则删除的条目写入新的tar，返回删除的条目数和新tar的diff id（"sha256:..."）。
+// 保留的硬链接指向被删除的文件时，第一个硬链接改为包含该文件内容的普通文件，其余的硬链接指向它。
+// 指向被删除的路径的whiteout也一并删除，因为下层中已经没有要删除的内容了。
+func rewriteLayer(inPath, outPath string, rules *filetree.RemovalRules, workDir string) (int, string, error) {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer in.Close()
+
+	// the first pass finds the removed files that kept hard links still need
+	needed := make(map[string]bool)
+	reader := tar.NewReader(in)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		if header.Typeflag == tar.TypeLink && !rules.Matches(cleanTarPath(header.Name)) && rules.Matches(cleanTarPath(header.Linkname)) {
+			needed[cleanTarPath(header.Linkname)] = true
+		}
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return 0, "", err
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer out.Close()
+	digest := sha256.New()
+	writer := tar.NewWriter(io.MultiWriter(out, digest))
+
+	removed := 0
+	// saved keeps the contents of the removed files that hard links need, moved the kept entry that took their place
+	saved := make(map[string]string)
+	moved := make(map[string]string)
+	reader = tar.NewReader(in)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		entryPath := cleanTarPath(header.Name)
+		dir, name := path.Split(entryPath)
+		if strings.HasPrefix(name, ".wh.") && !strings.HasPrefix(name, ".wh..wh.") {
+			entryPath = path.Join(dir, strings.TrimPrefix(name, ".wh."))
+		}
+		if rules.Matches(entryPath) {
+			removed++
+			if needed[entryPath] && (header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA) {
+				saved[entryPath] = filepath.Join(workDir, fmt.Sprintf("link-%d", len(saved)))
+				if err := saveFile(saved[entryPath], reader); err != nil {
+					return 0, "", err
+				}
+			}
+			continue
+		}
+
+		var contents io.Reader = reader
+		if header.Typeflag == tar.TypeLink {
+			target := cleanTarPath(header.Linkname)
+			if name, ok := moved[target]; ok {
+				header.Linkname = name
+			} else if file, ok := saved[target]; ok {
+				data, err := os.Open(file)
+				if err != nil {
+					return 0, "", err
+				}
+				defer data.Close()
+				info, err := data.Stat()
+				if err != nil {
+					return 0, "", err
+				}
+				header.Typeflag = tar.TypeReg
+				header.Linkname = ""
+				header.Size = info.Size()
+				contents = data
+				moved[target] = header.Name
+			}
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			return 0, "", err
+		}
+		if _, err := io.Copy(writer, contents); err != nil {
+			return 0, "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return 0, "", err
+	}
+	return removed, "sha256:" + hex.EncodeToString(digest.Sum(nil)), out.Close()
+}
+
+// saveFile 将reader的内容写入给定的文件。
+func saveFile(file string, reader io.Reader) error {
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, reader)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeArchiveFile 将给定的文件作为名为name的条目写入镜像归档。
+func writeArchiveFile(writer *tar.Writer, name, file string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, in)
+	return err
+}
+
+// writeArchiveEntry 将给定的内容作为名为name的条目写入镜像归档。
+func writeArchiveEntry(writer *tar.Writer, name string, data []byte) error {
+	if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := writer.Write(data)
+	return err
+}
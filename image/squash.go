@@ -0,0 +1,284 @@
+package image
+
+import (
+	"LGM/filetree"
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SquashArchive 将连续的几层合并为一层后的镜像写为docker-archive（与docker save的格式相同，可以用docker load加载）。
+type SquashArchive interface {
+	// WriteSquashed 用合并后的层替换squashed合并的各层，并将新的镜像（标签为tags）写入out
+	WriteSquashed(out io.Writer, squashed *filetree.SquashedLayer, tags []string) (*SquashResult, error)
+}
+
+// SquashResult 是写入合并了图层的镜像的结果
+type SquashResult struct {
+	// Entries 是合并后的层中的条目数
+	Entries int
+	// OriginalSize 是合并的各层tar的大小之和，Size 是合并后的层tar的大小
+	OriginalSize int64
+	Size         int64
+	Tags         []string
+}
+
+// SquashEstimate 是将连续的几层合并为一层的预测结果（不需要写入镜像）
+type SquashEstimate struct {
+	Layer *filetree.SquashedLayer
+	// LayersSize 是合并的各层的大小之和，Size 是合并后的层的大小
+	LayersSize uint64
+	Size       uint64
+	// 合并前后镜像的大小、效率得分和可能浪费的空间
+	ImageSize            uint64
+	ProjectedImageSize   uint64
+	Efficiency           float64
+	ProjectedEfficiency  float64
+	WastedBytes          uint64
+	ProjectedWastedBytes uint64
+}
+
+// EstimateSquash 预测将第lower到第upper层（RefTrees中的索引）合并为一层后的镜像：被之后的层覆盖或删除的文件不再占用空间。
+func EstimateSquash(analysis *AnalysisResult, lower, upper int) (*SquashEstimate, error) {
+	squashed, err := filetree.SquashTrees(analysis.RefTrees, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+	estimate := &SquashEstimate{
+		Layer:       squashed,
+		Size:        squashed.Tree.FileSize,
+		ImageSize:   analysis.SizeBytes,
+		Efficiency:  analysis.Efficiency,
+		WastedBytes: analysis.WastedBytes,
+	}
+	for idx := lower; idx <= upper; idx++ {
+		estimate.LayersSize += analysis.RefTrees[idx].FileSize
+	}
+	estimate.ProjectedImageSize = estimate.ImageSize - estimate.LayersSize + estimate.Size
+
+	trees := append([]*filetree.FileTree{}, analysis.RefTrees[:lower]...)
+	trees = append(trees, squashed.Tree)
+	trees = append(trees, analysis.RefTrees[upper+1:]...)
+	efficiency, inefficiencies := filetree.Efficiency(trees)
+	estimate.ProjectedEfficiency = efficiency
+	for _, data := range inefficiencies {
+		estimate.ProjectedWastedBytes += uint64(data.CumulativeSize)
+	}
+	return estimate, nil
+}
+
+// SquashTags 返回合并了图层的镜像的默认标签：原镜像的每个标签加上 "-squashed" 后缀。
+func SquashTags(repoTags []string) []string {
+	return suffixTags(repoTags, "-squashed")
+}
+
+// WriteSquashed 写入合并后的层（其余的层原样保留），然后写入新的配置（更新diff_ids，合并的各层的历史记录只保留最后一条）和清单。
+func (archive *spooledArchive) WriteSquashed(out io.Writer, squashed *filetree.SquashedLayer, tags []string) (*SquashResult, error) {
+	config, rootFs, err := archive.parseConfig()
+	if err != nil {
+		return nil, err
+	}
+	if squashed.Upper >= len(rootFs.DiffIds) {
+		return nil, fmt.Errorf("invalid layer range %d-%d (the image has %d layers)", squashed.Lower, squashed.Upper, len(rootFs.DiffIds))
+	}
+
+	workDir, err := ioutil.TempDir("", "LGM-squash-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	result := &SquashResult{Tags: tags}
+	for idx := squashed.Lower; idx <= squashed.Upper; idx++ {
+		info, err := os.Stat(archive.layers.tarPaths[idx])
+		if err != nil {
+			return nil, err
+		}
+		result.OriginalSize += info.Size()
+	}
+	squashedPath := filepath.Join(workDir, "squashed.tar")
+	digest, err := archive.writeSquashedLayer(squashedPath, squashed, workDir, result)
+	if err != nil {
+		return nil, fmt.Errorf("cannot write the squashed layer: %v", err)
+	}
+	info, err := os.Stat(squashedPath)
+	if err != nil {
+		return nil, err
+	}
+	result.Size = info.Size()
+
+	var layerPaths, diffIds []string
+	layerPaths = append(layerPaths, archive.layers.tarPaths[:squashed.Lower]...)
+	layerPaths = append(layerPaths, squashedPath)
+	layerPaths = append(layerPaths, archive.layers.tarPaths[squashed.Upper+1:]...)
+	diffIds = append(diffIds, rootFs.DiffIds[:squashed.Lower]...)
+	diffIds = append(diffIds, digest)
+	diffIds = append(diffIds, rootFs.DiffIds[squashed.Upper+1:]...)
+	rootFs.DiffIds = diffIds
+
+	configBytes, err := squashConfig(config, rootFs, squashed)
+	if err != nil {
+		return nil, err
+	}
+	return result, writeImage(out, DockerArchive, layerPaths, diffIds, configBytes, tags)
+}
+
+// writeSquashedLayer 依次读取合并的各层，将合并后仍然存在的条目写入新的tar，返回其diff id（"sha256:..."）。
+// 硬链接的目标被之后的层替换或删除时，第一个硬链接改为包含原来内容的普通文件，同一层中的其余硬链接指向它。
+func (archive *spooledArchive) writeSquashedLayer(outPath string, squashed *filetree.SquashedLayer, workDir string, result *SquashResult) (string, error) {
+	// the hard links that lose their target, by layer: a link only refers to a file of its own layer
+	needed := make(map[int]map[string]bool)
+	err := squashed.Tree.VisitDepthChildFirst(func(node *filetree.FileNode) error {
+		info := node.Data.FileInfo
+		if info.TypeFlag != tar.TypeLink {
+			return nil
+		}
+		layerIdx := squashed.Source(node.Path())
+		target := cleanTarPath(info.LinkName)
+		if layerIdx >= 0 && squashed.Source(target) != layerIdx {
+			if needed[layerIdx] == nil {
+				needed[layerIdx] = make(map[string]bool)
+			}
+			needed[layerIdx][target] = true
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	digest := sha256.New()
+	writer := tar.NewWriter(io.MultiWriter(out, digest))
+
+	var opaqueDirs []string
+	for layerIdx := squashed.Lower; layerIdx <= squashed.Upper; layerIdx++ {
+		// saved keeps the contents of the link targets of this layer, moved the link that took their place
+		saved := make(map[string]string)
+		moved := make(map[string]string)
+		err := archive.layers.Walk(layerIdx, func(header *tar.Header, reader io.Reader) error {
+			entryPath := cleanTarPath(header.Name)
+			if path.Base(entryPath) == ".wh..wh..opq" {
+				// the markers of the opaque directories are written at the end
+				return nil
+			}
+			var contents io.Reader = reader
+			if needed[layerIdx][entryPath] && (header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA) {
+				saved[entryPath] = filepath.Join(workDir, fmt.Sprintf("link-%d-%d", layerIdx, len(saved)))
+				if err := saveFile(saved[entryPath], reader); err != nil {
+					return err
+				}
+				data, err := os.Open(saved[entryPath])
+				if err != nil {
+					return err
+				}
+				defer data.Close()
+				contents = data
+			}
+			if squashed.Source(entryPath) != layerIdx {
+				return nil
+			}
+
+			if header.Typeflag == tar.TypeLink {
+				target := cleanTarPath(header.Linkname)
+				if name, ok := moved[target]; ok {
+					header.Linkname = name
+				} else if file, ok := saved[target]; ok && squashed.Source(target) != layerIdx {
+					data, err := os.Open(file)
+					if err != nil {
+						return err
+					}
+					defer data.Close()
+					info, err := data.Stat()
+					if err != nil {
+						return err
+					}
+					header.Typeflag = tar.TypeReg
+					header.Linkname = ""
+					header.Size = info.Size()
+					contents = data
+					moved[target] = header.Name
+				}
+			}
+			if err := writer.WriteHeader(header); err != nil {
+				return err
+			}
+			result.Entries++
+			_, err := io.Copy(writer, contents)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = squashed.Tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+		if node.Opaque {
+			opaqueDirs = append(opaqueDirs, strings.TrimPrefix(node.Path(), "/"))
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range opaqueDirs {
+		if err := writer.WriteHeader(&tar.Header{Name: dir + "/.wh..wh..opq", Typeflag: tar.TypeReg}); err != nil {
+			return "", err
+		}
+		result.Entries++
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(digest.Sum(nil)), out.Close()
+}
+
+// squashConfig 返回更新了diff_ids的镜像配置。历史记录与图层一一对应时，合并的各层中除最后一层以外的记录标记为不对应任何层（empty_layer）。
+func squashConfig(config map[string]json.RawMessage, rootFs dockerRootFs, squashed *filetree.SquashedLayer) ([]byte, error) {
+	var err error
+	if config["rootfs"], err = json.Marshal(rootFs); err != nil {
+		return nil, err
+	}
+	var history []map[string]interface{}
+	if raw, ok := config["history"]; ok {
+		if err := json.Unmarshal(raw, &history); err != nil {
+			return nil, fmt.Errorf("cannot parse the image history: %v", err)
+		}
+	}
+	var layerEntries []map[string]interface{}
+	for _, entry := range history {
+		if empty, _ := entry["empty_layer"].(bool); !empty {
+			layerEntries = append(layerEntries, entry)
+		}
+	}
+	layerCount := len(rootFs.DiffIds) + squashed.Upper - squashed.Lower
+	if len(layerEntries) != layerCount {
+		// without a history entry for every layer there is nothing to merge reliably
+		return json.Marshal(config)
+	}
+	for idx := squashed.Lower; idx < squashed.Upper; idx++ {
+		layerEntries[idx]["empty_layer"] = true
+	}
+	last := layerEntries[squashed.Upper]
+	comment := fmt.Sprintf("LGM squash: layers %d-%d merged into this layer", squashed.Lower, squashed.Upper)
+	if previous, _ := last["comment"].(string); previous != "" {
+		comment = previous + "; " + comment
+	}
+	last["comment"] = comment
+	if config["history"], err = json.Marshal(history); err != nil {
+		return nil, err
+	}
+	return json.Marshal(config)
+}
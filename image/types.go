@@ -1,9 +1,12 @@
 package image
 
 import (
+	"LGM/dockerfile"
 	"LGM/filetree"
 	"github.com/docker/docker/client"
 	"io"
+	"sort"
+	"time"
 )
 
 type Parser interface {
@@ -21,6 +24,12 @@ type Layer interface {
 	Index() int
 	Command() string
 	Size() uint64
+	Created() time.Time
+	Author() string
+	FileCount() int
+	WastedBytes() uint64
+	// Instruction 返回产生该层的Dockerfile指令（见MapDockerfile），没有对应的指令时返回nil
+	Instruction() *dockerfile.Instruction
 	Tree() *filetree.FileTree
 	String() string
 }
@@ -34,6 +43,64 @@ type AnalysisResult struct {
 	WastedUserPercent float64 // WastedUserPercent = wasted-bytes/user-size-bytes
 	WastedBytes       uint64
 	Inefficiencies    filetree.EfficiencySlice
+	Config            ImageConfig
+	// LayerFiles 读取各层中的文件内容，解析时没有保存图层（见SpoolLayers）时为nil
+	LayerFiles LayerFiles
+	// Slim 写入删除了部分文件的镜像，解析时没有保存图层时为nil
+	Slim SlimArchive
+	// Squash 写入将连续的几层合并为一层的镜像，解析时没有保存图层时为nil
+	Squash SquashArchive
+	// RepoTags 是镜像的标签（manifest.json中的RepoTags）
+	RepoTags []string
+	// History 是镜像的历史记录（按时间顺序，包括empty_layer的记录）
+	History []HistoryEntry
+	// Dockerfile 是与历史记录对齐的Dockerfile（见MapDockerfile），没有时为nil
+	Dockerfile *dockerfile.Dockerfile
+}
+
+// ImageConfig 是镜像的运行配置（镜像配置文件的config部分，字段名与Docker相同）以及OCI注解
+type ImageConfig struct {
+	Env          []string
+	Entrypoint   []string
+	Cmd          []string
+	User         string
+	WorkingDir   string
+	ExposedPorts map[string]struct{}
+	Volumes      map[string]struct{}
+	Labels       map[string]string
+	Healthcheck  *HealthConfig
+	StopSignal   string
+	// Annotations 来自OCI镜像索引（index.json），不在镜像配置文件中
+	Annotations map[string]string `json:"-"`
+}
+
+// SortedExposedPorts 按字母顺序返回镜像暴露的端口（例如 "80/tcp"）。
+func (config ImageConfig) SortedExposedPorts() []string {
+	return sortedKeys(config.ExposedPorts)
+}
+
+// SortedVolumes 按字母顺序返回镜像的卷。
+func (config ImageConfig) SortedVolumes() []string {
+	return sortedKeys(config.Volumes)
+}
+
+// sortedKeys 按字母顺序返回集合的元素。
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// HealthConfig 是镜像的健康检查配置（时间间隔在配置文件中以纳秒表示）
+type HealthConfig struct {
+	Test        []string
+	Interval    time.Duration
+	Timeout     time.Duration
+	StartPeriod time.Duration
+	Retries     int
 }
 
 type dockerImageAnalyzer struct {
@@ -43,6 +110,12 @@ type dockerImageAnalyzer struct {
 	trees     []*filetree.FileTree
 	layerMap  map[string]*filetree.FileTree
 	layers    []*dockerLayer
+	// spoolDir 是保存图层tar的临时目录，spooledTars 是各图层tar（按名称）保存的文件
+	spoolDir    string
+	spooledTars map[string]string
+	// spooledBytes 是已保存的字节数，spoolFull 指示超过了SpoolLimit（已保存的图层被删除，之后的图层不再保存）
+	spooledBytes uint64
+	spoolFull    bool
 }
 
 // dockerImageHistoryEntry 表示Docker镜像历史记录条目
@@ -69,6 +142,15 @@ type dockerRootFs struct {
 type dockerImageConfig struct {
 	History []dockerImageHistoryEntry `json:"history"`
 	RootFs  dockerRootFs              `json:"rootfs"`
+	Config  ImageConfig               `json:"config"`
+}
+
+// ociImageIndex 是OCI镜像布局中的index.json（较新的docker save也会写入），这里只用到注解
+type ociImageIndex struct {
+	Annotations map[string]string `json:"annotations"`
+	Manifests   []struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"manifests"`
 }
 
 // dockerLayer 表示Docker镜像层和元数据
@@ -77,4 +159,9 @@ type dockerLayer struct {
 	history dockerImageHistoryEntry
 	index   int
 	tree    *filetree.FileTree
+	// historyIdx 是该层在历史记录中的索引（没有对应的记录时为-1），instruction 是对应的Dockerfile指令
+	historyIdx  int
+	instruction *dockerfile.Instruction
+	// wastedBytes 是此层中属于低效文件（在多个层中重复或被之后的层删除）的字节数
+	wastedBytes uint64
 }
\ No newline at end of file
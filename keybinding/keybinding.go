@@ -1,10 +1,13 @@
 package keybinding
 
 import (
+	"LGM/theme"
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 var translate = map[string]string{
@@ -36,6 +39,21 @@ var display = map[string]string{
 	"Ctrl":       "^",
 }
 
+// arrowGlyph 返回方向键的显示文字（取决于当前的字形集），其他按键返回空字符串
+func arrowGlyph(token string) string {
+	switch token {
+	case "ArrowUp":
+		return theme.Glyphs.ArrowUp
+	case "ArrowDown":
+		return theme.Glyphs.ArrowDown
+	case "ArrowLeft":
+		return theme.Glyphs.ArrowLeft
+	case "ArrowRight":
+		return theme.Glyphs.ArrowRight
+	}
+	return ""
+}
+
 var supportedKeybindings = map[string]gocui.Key{
 	"KeyF1":             gocui.KeyF1,
 	"KeyF2":             gocui.KeyF2,
@@ -108,19 +126,50 @@ var supportedKeybindings = map[string]gocui.Key{
 }
 
 type Key struct {
-	Value    gocui.Key
+	// Value 是gocui.Key（特殊键或组合键）或rune（单个可打印字符，区分大小写）
+	Value    interface{}
 	Modifier gocui.Modifier
 	Tokens   []string
+	// Prefix 是多键序列中需要在此按键之前依次按下的按键（例如 "g g" 中的第一个 g），单键绑定时为空
+	Prefix []Key
 }
 
+// modifierSeparator 匹配连接修饰键的 "+" 及其两侧的空白（例如 "ctrl + a"）
+var modifierSeparator = regexp.MustCompile(`\s*\+\s*`)
+
+// Parse 解析一个按键绑定。"+" 连接修饰键（例如 "ctrl+a"、"alt+x"），以空白分隔的多个按键表示需要依次按下的序列（例如 "g g"、"z c"）。
 func Parse(input string) (Key, error) {
+	steps := strings.Fields(modifierSeparator.ReplaceAllString(input, "+"))
+	if len(steps) == 0 {
+		return Key{}, fmt.Errorf("empty keybinding")
+	}
+
+	var prefix []Key
+	for _, step := range steps[:len(steps)-1] {
+		key, err := parseStroke(step)
+		if err != nil {
+			return key, err
+		}
+		prefix = append(prefix, key)
+	}
+
+	key, err := parseStroke(steps[len(steps)-1])
+	key.Prefix = prefix
+	return key, err
+}
+
+// parseStroke 解析单个按键（可以带修饰键），例如 "ctrl+a"、"pgup" 或 "G"。
+func parseStroke(input string) (Key, error) {
 	f := func(c rune) bool { return unicode.IsSpace(c) || c == '+' }
 	tokens := strings.FieldsFunc(input, f)
-	var normalizedTokens []string
+	var normalizedTokens, plainTokens []string
 	var modifier = gocui.ModNone
 
 	for _, token := range tokens {
 		normalized := strings.ToLower(token)
+		if normalized != "alt" {
+			plainTokens = append(plainTokens, token)
+		}
 
 		if value, exists := translate[normalized]; exists {
 			normalized = value
@@ -141,19 +190,31 @@ func Parse(input string) (Key, error) {
 		normalizedTokens = append(normalizedTokens, normalized)
 	}
 
+	// a single printable character (e.g. "/", "n" or "N") is bound as a rune
+	if len(plainTokens) == 1 && utf8.RuneCountInString(plainTokens[0]) == 1 {
+		ch, _ := utf8.DecodeRuneInString(plainTokens[0])
+		if unicode.IsPrint(ch) {
+			return Key{Value: ch, Modifier: modifier, Tokens: plainTokens}, nil
+		}
+	}
+
 	lookup := "Key" + strings.Join(normalizedTokens, "")
 
 	if key, exists := supportedKeybindings[lookup]; exists {
-		return Key{key, modifier, normalizedTokens}, nil
+		return Key{Value: key, Modifier: modifier, Tokens: normalizedTokens}, nil
 	}
 
 	if modifier != gocui.ModNone {
-		return Key{0, modifier, normalizedTokens}, fmt.Errorf("unsupported keybinding: %s (+%+v)", lookup, modifier)
+		return Key{Value: gocui.Key(0), Modifier: modifier, Tokens: normalizedTokens}, fmt.Errorf("unsupported keybinding: %s (+%+v)", lookup, modifier)
 	}
-	return Key{0, modifier, normalizedTokens}, fmt.Errorf("unsupported keybinding: %s", lookup)
+	return Key{Value: gocui.Key(0), Modifier: modifier, Tokens: normalizedTokens}, fmt.Errorf("unsupported keybinding: %s", lookup)
 }
 
+// ParseAll 解析以逗号分隔的多个按键绑定。空字符串表示该操作没有绑定任何按键。
 func ParseAll(input string) ([]Key, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
 	ret := make([]Key, 0)
 	for _, value := range strings.Split(input, ",") {
 		key, err := Parse(value)
@@ -168,15 +229,42 @@ func ParseAll(input string) ([]Key, error) {
 	return ret, nil
 }
 
+// String 返回按键的显示文字。多键序列中的按键都是单个字符时按vim的习惯连在一起显示（例如 "gg"），否则以空格分隔。
 func (key Key) String() string {
+	if len(key.Prefix) == 0 {
+		return key.strokeString()
+	}
+
+	steps := make([]string, 0, len(key.Prefix)+1)
+	compact := true
+	for _, step := range append(key.Prefix, key) {
+		display := step.strokeString()
+		steps = append(steps, display)
+		if utf8.RuneCountInString(display) != 1 {
+			compact = false
+		}
+	}
+	if compact {
+		return strings.Join(steps, "")
+	}
+	return strings.Join(steps, " ")
+}
+
+// strokeString 返回单个按键（不包括序列的前缀）的显示文字。
+func (key Key) strokeString() string {
 	displayTokens := make([]string, 0)
 	prefix := ""
+	if key.Modifier == gocui.ModAlt {
+		prefix = "Alt+"
+	}
 	for _, token := range key.Tokens {
 		if token == "Ctrl" {
-			prefix = "^"
+			prefix += "^"
 			continue
 		}
-		if value, exists := display[token]; exists {
+		if glyph := arrowGlyph(token); glyph != "" {
+			token = glyph
+		} else if value, exists := display[token]; exists {
 			token = value
 		}
 		displayTokens = append(displayTokens, token)
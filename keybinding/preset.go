@@ -0,0 +1,51 @@
+package keybinding
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// Presets 是内置的按键方案（配置项 keybinding.preset）。方案中的按键作为默认值使用，配置文件中显式设置的按键仍然优先。
+var Presets = map[string]map[string]string{
+	"default": {},
+	"vim": {
+		"keybinding.quit":                    "ctrl+c, q",
+		"keybinding.cursor-up":               "up, k",
+		"keybinding.cursor-down":             "down, j",
+		"keybinding.cursor-left":             "left, h",
+		"keybinding.cursor-right":            "right, l",
+		"keybinding.goto-top":                "home, g g",
+		"keybinding.goto-bottom":             "end, G",
+		"keybinding.toggle-collapse-dir":     "space, z a",
+		"keybinding.collapse-dir":            "z c",
+		"keybinding.expand-dir":              "z o",
+		"keybinding.toggle-collapse-all-dir": "ctrl+space, z A",
+	},
+}
+
+// PresetNames 按字母顺序返回所有内置按键方案的名称。
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyPreset 将给定按键方案中的按键设置为viper的默认值。空字符串表示默认方案。
+func ApplyPreset(name string) error {
+	if name == "" {
+		name = "default"
+	}
+	preset, exists := Presets[name]
+	if !exists {
+		return fmt.Errorf("unknown keybinding preset '%s' (available: %v)", name, PresetNames())
+	}
+	for config, keys := range preset {
+		viper.SetDefault(config, keys)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package keybinding
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// 窗格名称，用于对按键操作进行分组（帮助窗口及"LGM keys"命令按此顺序显示）
+const (
+	ViewGlobal   = "Global"
+	ViewLayer    = "Layers"
+	ViewFileTree = "Filetree"
+	ViewDetails  = "Details"
+	ViewConfig   = "Image Config"
+	ViewViewer   = "File Viewer"
+	ViewSearch   = "Search"
+	ViewHelp     = "Help"
+)
+
+// Binding 描述一个按键操作：所在的窗格、说明以及按键的来源。
+// Config 是可配置操作的配置项名称（例如 "keybinding.quit"）；不可配置的操作（例如方向键）使用 Fixed 中的固定按键。
+// View 和 Help 是英文原文，帮助窗口通过i18n消息目录显示其译文，修改时需要同时更新消息目录。
+type Binding struct {
+	View   string
+	Config string
+	Fixed  string
+	Help   string
+}
+
+// Registry 列出TUI中的所有按键操作。控制器从同样的配置项解析按键，因此这里列出的就是实际生效的按键。
+var Registry = []Binding{
+	{View: ViewGlobal, Config: "keybinding.quit", Help: "Quit"},
+	{View: ViewGlobal, Config: "keybinding.toggle-view", Help: "Switch view"},
+	{View: ViewGlobal, Config: "keybinding.filter-files", Help: "Show/hide the file tree filter"},
+	{View: ViewGlobal, Config: "keybinding.help", Help: "Show/hide this help (not in the filter and search bars)"},
+	{View: ViewGlobal, Config: "keybinding.narrow-left-column", Help: "Narrow the layer and details panes"},
+	{View: ViewGlobal, Config: "keybinding.widen-left-column", Help: "Widen the layer and details panes"},
+	{View: ViewGlobal, Config: "keybinding.shrink-details-pane", Help: "Shrink the details pane"},
+	{View: ViewGlobal, Config: "keybinding.grow-details-pane", Help: "Grow the details pane"},
+	{View: ViewGlobal, Config: "keybinding.maximize-pane", Help: "Maximize/restore the selected pane"},
+	{View: ViewGlobal, Config: "keybinding.reset-layout", Help: "Restore the default pane sizes"},
+
+	{View: ViewLayer, Config: "keybinding.cursor-up", Help: "Select the previous layer"},
+	{View: ViewLayer, Config: "keybinding.cursor-left", Help: "Select the previous layer"},
+	{View: ViewLayer, Config: "keybinding.cursor-down", Help: "Select the next layer"},
+	{View: ViewLayer, Config: "keybinding.cursor-right", Help: "Select the next layer"},
+	{View: ViewLayer, Config: "keybinding.goto-top", Help: "Select the first layer"},
+	{View: ViewLayer, Config: "keybinding.goto-bottom", Help: "Select the last layer"},
+	{View: ViewLayer, Config: "keybinding.page-up", Help: "Previous page"},
+	{View: ViewLayer, Config: "keybinding.page-down", Help: "Next page"},
+	{View: ViewLayer, Config: "keybinding.compare-layer", Help: "Show the changes of the selected layer"},
+	{View: ViewLayer, Config: "keybinding.compare-all", Help: "Show the aggregated changes up to the selected layer"},
+	{View: ViewLayer, Config: "keybinding.compare-mark", Help: "Mark the selected layer as a bound of the compare range"},
+	{View: ViewLayer, Config: "keybinding.squash-preview", Help: "Show/hide what merging the compared layers would save"},
+	{View: ViewLayer, Config: "keybinding.squash-write", Help: "Write the image with the compared layers squashed"},
+
+	{View: ViewFileTree, Config: "keybinding.cursor-up", Help: "Move the cursor up"},
+	{View: ViewFileTree, Config: "keybinding.cursor-down", Help: "Move the cursor down"},
+	{View: ViewFileTree, Config: "keybinding.cursor-left", Help: "Go to the parent directory"},
+	{View: ViewFileTree, Config: "keybinding.cursor-right", Help: "Expand and enter the directory"},
+	{View: ViewFileTree, Config: "keybinding.goto-top", Help: "Go to the first file"},
+	{View: ViewFileTree, Config: "keybinding.goto-bottom", Help: "Go to the last file"},
+	{View: ViewFileTree, Config: "keybinding.page-up", Help: "Previous page"},
+	{View: ViewFileTree, Config: "keybinding.page-down", Help: "Next page"},
+	{View: ViewFileTree, Config: "keybinding.toggle-collapse-dir", Help: "Collapse/expand the selected directory"},
+	{View: ViewFileTree, Config: "keybinding.collapse-dir", Help: "Collapse the selected directory"},
+	{View: ViewFileTree, Config: "keybinding.expand-dir", Help: "Expand the selected directory"},
+	{View: ViewFileTree, Config: "keybinding.toggle-collapse-all-dir", Help: "Collapse/expand all directories"},
+	{View: ViewFileTree, Config: "keybinding.toggle-filetree-attributes", Help: "Show/hide the file attributes"},
+	{View: ViewFileTree, Config: "keybinding.toggle-added-files", Help: "Show/hide added files"},
+	{View: ViewFileTree, Config: "keybinding.toggle-removed-files", Help: "Show/hide removed files"},
+	{View: ViewFileTree, Config: "keybinding.toggle-modified-files", Help: "Show/hide modified files"},
+	{View: ViewFileTree, Config: "keybinding.toggle-unchanged-files", Help: "Show/hide unmodified files"},
+	{View: ViewFileTree, Config: "keybinding.sort-files", Help: "Sort by name, size or change type"},
+	{View: ViewFileTree, Config: "keybinding.search", Help: "Search the file tree"},
+	{View: ViewFileTree, Config: "keybinding.search-next", Help: "Go to the next search match"},
+	{View: ViewFileTree, Config: "keybinding.search-prev", Help: "Go to the previous search match"},
+	{View: ViewFileTree, Config: "keybinding.view-file", Help: "View the selected file (or double click it)"},
+	{View: ViewFileTree, Config: "keybinding.extract-file", Help: "Extract the selected file or directory"},
+	{View: ViewFileTree, Config: "keybinding.mark-removal", Help: "Mark/unmark the selected file or directory for removal"},
+
+	{View: ViewDetails, Config: "keybinding.cursor-up", Help: "Select the previous inefficiency"},
+	{View: ViewDetails, Config: "keybinding.cursor-down", Help: "Select the next inefficiency"},
+	{View: ViewDetails, Config: "keybinding.goto-top", Help: "Select the first inefficiency"},
+	{View: ViewDetails, Config: "keybinding.goto-bottom", Help: "Select the last inefficiency"},
+	{View: ViewDetails, Fixed: "enter", Help: "Show the selected file in the file tree"},
+	{View: ViewDetails, Config: "keybinding.page-up", Help: "Previous page"},
+	{View: ViewDetails, Config: "keybinding.page-down", Help: "Next page"},
+	{View: ViewDetails, Config: "keybinding.sort-inefficiencies", Help: "Sort by wasted space or count"},
+
+	{View: ViewConfig, Config: "keybinding.cursor-up", Help: "Scroll up"},
+	{View: ViewConfig, Config: "keybinding.cursor-down", Help: "Scroll down"},
+	{View: ViewConfig, Config: "keybinding.goto-top", Help: "Go to the first row"},
+	{View: ViewConfig, Config: "keybinding.goto-bottom", Help: "Go to the last row"},
+	{View: ViewConfig, Config: "keybinding.page-up", Help: "Previous page"},
+	{View: ViewConfig, Config: "keybinding.page-down", Help: "Next page"},
+
+	{View: ViewViewer, Config: "keybinding.cursor-up", Help: "Scroll up"},
+	{View: ViewViewer, Config: "keybinding.cursor-down", Help: "Scroll down"},
+	{View: ViewViewer, Config: "keybinding.cursor-left", Help: "Scroll left"},
+	{View: ViewViewer, Config: "keybinding.cursor-right", Help: "Scroll right"},
+	{View: ViewViewer, Config: "keybinding.goto-top", Help: "Go to the first row"},
+	{View: ViewViewer, Config: "keybinding.goto-bottom", Help: "Go to the last row"},
+	{View: ViewViewer, Config: "keybinding.page-up", Help: "Previous page"},
+	{View: ViewViewer, Config: "keybinding.page-down", Help: "Next page"},
+	{View: ViewViewer, Config: "keybinding.viewer-toggle-diff", Help: "Switch between the contents and the diff"},
+	{View: ViewViewer, Config: "keybinding.viewer-toggle-hex", Help: "Switch between the text and the hex dump"},
+	{View: ViewViewer, Config: "keybinding.view-file", Help: "Close the file viewer"},
+	{View: ViewViewer, Fixed: "esc", Help: "Close the file viewer"},
+
+	{View: ViewSearch, Fixed: "enter", Help: "Keep the search matches and return to the file tree"},
+	{View: ViewSearch, Fixed: "esc", Help: "Cancel the search"},
+
+	{View: ViewHelp, Config: "keybinding.cursor-up", Help: "Scroll up"},
+	{View: ViewHelp, Config: "keybinding.cursor-down", Help: "Scroll down"},
+	{View: ViewHelp, Fixed: "esc", Help: "Close this help"},
+}
+
+// Source 返回按键操作的按键定义：配置项的当前值（包括用户的覆盖）或固定的按键。
+func (binding Binding) Source() string {
+	if binding.Config == "" {
+		return binding.Fixed
+	}
+	return viper.GetString(binding.Config)
+}
+
+// Keys 解析按键操作当前生效的按键。
+func (binding Binding) Keys() ([]Key, error) {
+	return ParseAll(binding.Source())
+}
+
+// Views 按显示顺序返回注册表中出现的所有窗格名称。
+func Views() []string {
+	var views []string
+	seen := make(map[string]bool)
+	for _, binding := range Registry {
+		if !seen[binding.View] {
+			seen[binding.View] = true
+			views = append(views, binding.View)
+		}
+	}
+	return views
+}
+
+// Describe 返回按键操作的显示文字（例如 "^F, ^/"）。按键无效时返回配置的原始值并附带说明。
+func (binding Binding) Describe() string {
+	keys, err := binding.Keys()
+	if err != nil {
+		return "invalid: " + strings.TrimSpace(binding.Source())
+	}
+	if len(keys) == 0 {
+		return "-"
+	}
+	names := make([]string, len(keys))
+	for idx, key := range keys {
+		names[idx] = key.String()
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,235 @@
+package keybinding
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// stroke 是一次按键事件，与gocui匹配按键时使用的字段一致。
+type stroke struct {
+	key gocui.Key
+	ch  rune
+	mod gocui.Modifier
+}
+
+// strokes 返回按下此绑定需要依次产生的按键事件。
+// 注意：TUI以InputEsc模式读取输入（以便单独的Esc可用），此时Alt+x会以Esc和x两个事件到达，因此Alt组合键按两步序列处理。
+func (key Key) strokes() []stroke {
+	var result []stroke
+	for _, step := range append(append([]Key{}, key.Prefix...), key) {
+		mod := step.Modifier
+		if mod == gocui.ModAlt {
+			result = append(result, stroke{key: gocui.KeyEsc})
+			mod = gocui.ModNone
+		}
+		switch value := step.Value.(type) {
+		case rune:
+			result = append(result, stroke{ch: value, mod: mod})
+		case gocui.Key:
+			result = append(result, stroke{key: value, mod: mod})
+		}
+	}
+	return result
+}
+
+// sequenceNode 是按键序列前缀树中的一个节点。handlers 是在此处结束的绑定的处理函数，children 是可以继续按下的按键。
+type sequenceNode struct {
+	handlers []func() error
+	children map[stroke]*sequenceNode
+}
+
+func newSequenceNode() *sequenceNode {
+	return &sequenceNode{children: make(map[stroke]*sequenceNode)}
+}
+
+// run 依次调用在此节点结束的所有绑定的处理函数。
+func (node *sequenceNode) run() error {
+	for _, handler := range node.handlers {
+		if err := handler(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dispatcher 将按键事件（包括多键序列）分派给处理函数。每个窗格有一棵按键序列的前缀树和一个等待中的序列状态：
+// 按下序列的前缀后等待下一个按键；如果在timeout内没有按下能继续序列的按键，则执行在前缀处结束的绑定（如果有）并重置状态。
+type Dispatcher struct {
+	gui     *gocui.Gui
+	timeout time.Duration
+
+	roots      map[string]*sequenceNode
+	pending    map[string]*sequenceNode
+	generation map[string]int
+	bound      map[string]map[stroke]bool
+}
+
+// NewDispatcher 创建附加到给定gui的按键分派器。
+func NewDispatcher(gui *gocui.Gui, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		gui:        gui,
+		timeout:    timeout,
+		roots:      make(map[string]*sequenceNode),
+		pending:    make(map[string]*sequenceNode),
+		generation: make(map[string]int),
+		bound:      make(map[string]map[stroke]bool),
+	}
+}
+
+// Bind 将给定的按键绑定到指定窗格（空字符串表示所有窗格）的处理函数。
+func (dispatcher *Dispatcher) Bind(view string, keys []Key, handler func() error) error {
+	if dispatcher.roots[view] == nil {
+		dispatcher.roots[view] = newSequenceNode()
+		dispatcher.bound[view] = make(map[stroke]bool)
+	}
+
+	for _, key := range keys {
+		node := dispatcher.roots[view]
+		for _, step := range key.strokes() {
+			if node.children[step] == nil {
+				node.children[step] = newSequenceNode()
+			}
+			node = node.children[step]
+
+			if err := dispatcher.bindStroke(view, step); err != nil {
+				return err
+			}
+		}
+		node.handlers = append(node.handlers, handler)
+	}
+	return nil
+}
+
+// bindStroke 在gocui中为给定窗格的按键注册分派函数（每个按键只注册一次）。
+func (dispatcher *Dispatcher) bindStroke(view string, step stroke) error {
+	if dispatcher.bound[view][step] {
+		return nil
+	}
+	dispatcher.bound[view][step] = true
+
+	var key interface{} = step.key
+	if step.ch != 0 {
+		key = step.ch
+	}
+	return dispatcher.gui.SetKeybinding(view, key, step.mod, func(g *gocui.Gui, v *gocui.View) error {
+		return dispatcher.press(v, view, step)
+	})
+}
+
+// press 推进给定窗格的按键序列状态机。
+func (dispatcher *Dispatcher) press(v *gocui.View, view string, step stroke) error {
+	// plain characters and Esc that are bound for all panes must still reach the editable panes (e.g. the filter bar)
+	if view == "" && dispatcher.pending[view] == nil && v != nil && v.Editable && v.Editor != nil {
+		if (step.ch != 0 && step.mod == gocui.ModNone) || step.key == gocui.KeyEsc {
+			v.Editor.Edit(v, step.key, step.ch, step.mod)
+			return nil
+		}
+	}
+
+	var next *sequenceNode
+	if pending := dispatcher.pending[view]; pending != nil {
+		next = pending.children[step]
+	}
+	if next == nil {
+		// not a continuation of the pending sequence: start over with this key
+		next = dispatcher.roots[view].children[step]
+	}
+	delete(dispatcher.pending, view)
+	dispatcher.generation[view]++
+
+	if next == nil {
+		return nil
+	}
+	if len(next.children) == 0 {
+		return next.run()
+	}
+
+	// a prefix of a longer sequence: wait for the next key (or the timeout)
+	dispatcher.pending[view] = next
+	generation := dispatcher.generation[view]
+	time.AfterFunc(dispatcher.timeout, func() {
+		dispatcher.gui.Update(func(*gocui.Gui) error {
+			if dispatcher.generation[view] != generation {
+				return nil
+			}
+			delete(dispatcher.pending, view)
+			return next.run()
+		})
+	})
+	return nil
+}
+
+// Conflict 描述同一窗格中绑定到相同按键（或按键序列）的两个操作。
+type Conflict struct {
+	View  string
+	Key   string
+	First Binding
+	Other Binding
+}
+
+func (conflict Conflict) Error() string {
+	return fmt.Sprintf("%s: %s is bound to both '%s' (%s) and '%s' (%s)", conflict.View, conflict.Key, conflict.First.Help, conflict.First.name(), conflict.Other.Help, conflict.Other.name())
+}
+
+// name 返回按键操作的配置项名称，不可配置的操作返回其固定按键。
+func (binding Binding) name() string {
+	if binding.Config == "" {
+		return "fixed " + binding.Fixed
+	}
+	return binding.Config
+}
+
+// Validate 检查注册表中所有按键操作的当前配置，返回无法解析的按键以及同一窗格中相互冲突的绑定。
+// Global窗格中的绑定在所有窗格中都生效，因此也会与各窗格中的绑定进行比较。
+func Validate() []error {
+	var errs []error
+
+	type boundKey struct {
+		binding Binding
+		key     Key
+		strokes string
+	}
+	byView := make(map[string][]boundKey)
+	for _, binding := range Registry {
+		keys, err := binding.Keys()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, key := range keys {
+			byView[binding.View] = append(byView[binding.View], boundKey{binding, key, strokesId(key.strokes())})
+		}
+	}
+
+	for _, view := range Views() {
+		candidates := byView[view]
+		if view != ViewGlobal {
+			candidates = append(append([]boundKey{}, byView[ViewGlobal]...), candidates...)
+		}
+		for i := 0; i < len(candidates); i++ {
+			for j := i + 1; j < len(candidates); j++ {
+				first, other := candidates[i], candidates[j]
+				if first.strokes != other.strokes || first.binding == other.binding {
+					continue
+				}
+				// conflicts between two global bindings are reported only once
+				if view != ViewGlobal && first.binding.View == ViewGlobal && other.binding.View == ViewGlobal {
+					continue
+				}
+				errs = append(errs, Conflict{View: view, Key: other.key.String(), First: first.binding, Other: other.binding})
+			}
+		}
+	}
+	return errs
+}
+
+// strokesId 返回按键事件序列的唯一表示，用于比较两个绑定是否相同（例如 "tab" 与 "ctrl+i" 是同一个按键）。
+func strokesId(strokes []stroke) string {
+	id := make([]rune, 0, len(strokes)*3)
+	for _, step := range strokes {
+		id = append(id, rune(step.key), step.ch, rune(step.mod))
+	}
+	return string(id)
+}
@@ -0,0 +1,292 @@
+package linter
+
+import (
+	"LGM/dockerfile"
+	"LGM/i18n"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severity 是规则的严重程度，Off 表示不检查该规则
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Info    Severity = "info"
+	Off     Severity = "off"
+)
+
+// severityRanks 用于比较严重程度（见AtLeast）
+var severityRanks = map[Severity]int{Off: 0, Info: 1, Warning: 2, Error: 3}
+
+// ParseSeverity 解析严重程度的名称（error、warning、info或off，不区分大小写）。
+func ParseSeverity(name string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := severityRanks[severity]; !ok {
+		return "", fmt.Errorf("unknown severity '%s' (available: error, warning, info, off)", name)
+	}
+	return severity, nil
+}
+
+// AtLeast 返回严重程度是否不低于other。
+func (severity Severity) AtLeast(other Severity) bool {
+	return severityRanks[severity] >= severityRanks[other]
+}
+
+// Rule 是一条检查规则：ID（用于配置严重程度和lgm-ignore注释）、默认的严重程度和说明
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	check       func(file *dockerfile.Dockerfile) []Finding
+}
+
+// Finding 是规则发现的一个问题，Line 和 EndLine 是相关指令的第一行和最后一行
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Line     int
+	EndLine  int
+	Message  string
+	// instruction 是问题所在的指令，它之前的注释可以忽略该问题
+	instruction *dockerfile.Instruction
+}
+
+// Rules 是所有的检查规则
+var Rules = []Rule{
+	{ID: "base-unpinned", Severity: Warning, Description: "Base image without a version tag or digest", check: checkBaseUnpinned},
+	{ID: "base-latest", Severity: Warning, Description: "Base image with the latest tag", check: checkBaseLatest},
+	{ID: "add-url", Severity: Warning, Description: "ADD downloading a remote URL without --checksum", check: checkAddURL},
+	{ID: "missing-user", Severity: Warning, Description: "Final stage running as root (no USER or USER root)", check: checkMissingUser},
+	{ID: "apt-cleanup", Severity: Info, Description: "apt-get install without removing /var/lib/apt/lists in the same RUN", check: checkAptCleanup},
+	{ID: "sudo", Severity: Warning, Description: "sudo in RUN", check: checkSudo},
+	{ID: "multiple-cmd", Severity: Warning, Description: "More than one CMD in a stage (only the last one takes effect)", check: checkMultipleCmd},
+	{ID: "shell-entrypoint", Severity: Warning, Description: "ENTRYPOINT in shell form (the process does not receive signals)", check: checkShellEntrypoint},
+}
+
+var (
+	aptInstallPattern = regexp.MustCompile(`\bapt(-get)?\s+(-\S+\s+)*install\b`)
+	sudoPattern       = regexp.MustCompile(`(^|[;&|(\s])sudo\s`)
+	// argReferencePattern 匹配变量引用："${NAME}"、"${NAME:-default}"（第1、2组）或 "$NAME"（第3组）
+	argReferencePattern = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)(:?[-+][^}]*)?\}|([A-Za-z_][A-Za-z0-9_]*))`)
+)
+
+// ignorePrefix 开始忽略问题的注释（见ignored）
+const ignorePrefix = "lgm-ignore"
+
+// Lint 按所有规则检查Dockerfile，severities 覆盖规则默认的严重程度（可以为nil）。
+// 严重程度为Off的规则以及被指令之前的 "# lgm-ignore" 注释忽略的问题不返回，结果按行排列。
+func Lint(file *dockerfile.Dockerfile, severities map[string]Severity) []Finding {
+	var findings []Finding
+	for _, rule := range Rules {
+		severity := rule.Severity
+		if override, ok := severities[rule.ID]; ok {
+			severity = override
+		}
+		if severity == Off {
+			continue
+		}
+		for _, finding := range rule.check(file) {
+			if ignored(finding.instruction, rule.ID) {
+				continue
+			}
+			finding.Rule, finding.Severity = rule.ID, severity
+			findings = append(findings, finding)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// ignored 返回指令之前的注释是否忽略给定的规则："# lgm-ignore" 忽略所有规则，"# lgm-ignore: sudo, apt-cleanup" 只忽略列出的规则。
+func ignored(instruction *dockerfile.Instruction, rule string) bool {
+	for _, comment := range instruction.Comments {
+		if !strings.HasPrefix(comment, ignorePrefix) {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(comment, ignorePrefix))
+		if rest == "" {
+			return true
+		}
+		if !strings.HasPrefix(rest, ":") {
+			// e.g. "lgm-ignored", which is not the ignore comment
+			continue
+		}
+		for _, id := range strings.FieldsFunc(rest[1:], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if id == rule {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newFinding(instruction *dockerfile.Instruction, message string) Finding {
+	return Finding{Line: instruction.Line, EndLine: instruction.EndLine, Message: message, instruction: instruction}
+}
+
+// baseImages 对以镜像（而不是之前的阶段或scratch）为基础的每个阶段调用visit，参数为基础镜像的名称、标签（没有时为空）以及是否指定了摘要。
+// 标签由构建参数给出（FROM中的变量没有默认值）的阶段跳过。
+func baseImages(file *dockerfile.Dockerfile, visit func(stage *dockerfile.Stage, name, tag string, digest bool)) {
+	args := file.GlobalArgs()
+	for _, stage := range file.Stages {
+		if file.Parent(stage) != nil || strings.EqualFold(stage.Base, "scratch") {
+			continue
+		}
+		raw := strings.Fields(stage.From.Args)[0]
+		name, tag, digest := stage.Base, "", false
+		if pos := strings.Index(name, "@"); pos >= 0 {
+			name, digest = name[:pos], true
+		}
+		if pos := strings.LastIndex(name, ":"); pos > strings.LastIndex(name, "/") {
+			name, tag = name[:pos], name[pos+1:]
+		}
+		if !digest && tag == "" && unsetArg(raw, args) {
+			continue
+		}
+		visit(stage, name, tag, digest)
+	}
+}
+
+// unsetArg 返回文本是否引用了没有默认值的全局ARG（"${NAME:-default}" 这样自带默认值的引用除外）。
+func unsetArg(text string, args map[string]string) bool {
+	for _, match := range argReferencePattern.FindAllStringSubmatch(text, -1) {
+		if strings.HasPrefix(strings.TrimPrefix(match[2], ":"), "-") {
+			continue
+		}
+		if args[match[1]+match[3]] == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func checkBaseUnpinned(file *dockerfile.Dockerfile) []Finding {
+	var findings []Finding
+	baseImages(file, func(stage *dockerfile.Stage, name, tag string, digest bool) {
+		if !digest && tag == "" {
+			findings = append(findings, newFinding(stage.From, i18n.T("Pin the base image %s to a version tag or digest", name)))
+		}
+	})
+	return findings
+}
+
+func checkBaseLatest(file *dockerfile.Dockerfile) []Finding {
+	var findings []Finding
+	baseImages(file, func(stage *dockerfile.Stage, name, tag string, digest bool) {
+		if !digest && tag == "latest" {
+			findings = append(findings, newFinding(stage.From, i18n.T("Use a version tag instead of latest for the base image %s", name)))
+		}
+	})
+	return findings
+}
+
+func checkAddURL(file *dockerfile.Dockerfile) []Finding {
+	var findings []Finding
+	for _, instruction := range file.Instructions {
+		if instruction.Cmd != "ADD" {
+			continue
+		}
+		if _, ok := instruction.Flag("checksum"); ok {
+			continue
+		}
+		sources := instruction.JSON
+		if sources == nil {
+			sources = strings.Fields(instruction.Args)
+		}
+		for idx, source := range sources {
+			if idx < len(sources)-1 && (strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")) {
+				findings = append(findings, newFinding(instruction, i18n.T("ADD does not verify the download of %s, use ADD --checksum or download it in a RUN", source)))
+			}
+		}
+	}
+	return findings
+}
+
+func checkMissingUser(file *dockerfile.Dockerfile) []Finding {
+	final := file.Stages[len(file.Stages)-1]
+	var user *dockerfile.Instruction
+	for _, instruction := range file.Chain(final) {
+		if instruction.Cmd == "USER" {
+			user = instruction
+		}
+	}
+	if user == nil {
+		return []Finding{newFinding(final.From, i18n.T("The final stage runs as root, add a USER instruction"))}
+	}
+	name := strings.SplitN(strings.TrimSpace(user.Args), ":", 2)[0]
+	if name == "root" || name == "0" {
+		return []Finding{newFinding(user, i18n.T("The final stage runs as root (USER %s)", strings.TrimSpace(user.Args)))}
+	}
+	return nil
+}
+
+func checkAptCleanup(file *dockerfile.Dockerfile) []Finding {
+	var findings []Finding
+	for _, instruction := range file.Instructions {
+		if instruction.Cmd != "RUN" {
+			continue
+		}
+		text := script(instruction)
+		if !aptInstallPattern.MatchString(text) || strings.Contains(text, "/var/lib/apt/lists") {
+			continue
+		}
+		if mount, ok := instruction.Flag("mount"); ok && strings.Contains(mount, "type=cache") && strings.Contains(mount, "/var/lib/apt") {
+			// the lists are kept in a cache mount, not in the layer
+			continue
+		}
+		findings = append(findings, newFinding(instruction, i18n.T("Remove /var/lib/apt/lists in the same RUN as apt-get install")))
+	}
+	return findings
+}
+
+func checkSudo(file *dockerfile.Dockerfile) []Finding {
+	var findings []Finding
+	for _, instruction := range file.Instructions {
+		if instruction.Cmd == "RUN" && sudoPattern.MatchString(script(instruction)) {
+			findings = append(findings, newFinding(instruction, i18n.T("Do not use sudo, switch users with USER instead")))
+		}
+	}
+	return findings
+}
+
+func checkMultipleCmd(file *dockerfile.Dockerfile) []Finding {
+	var findings []Finding
+	for _, stage := range file.Stages {
+		var last *dockerfile.Instruction
+		for _, instruction := range stage.Instructions {
+			if instruction.Cmd != "CMD" {
+				continue
+			}
+			if last != nil {
+				findings = append(findings, newFinding(last, i18n.T("This CMD is replaced by the CMD at line %d", instruction.Line)))
+			}
+			last = instruction
+		}
+	}
+	return findings
+}
+
+func checkShellEntrypoint(file *dockerfile.Dockerfile) []Finding {
+	var findings []Finding
+	for _, instruction := range file.Instructions {
+		if instruction.Cmd == "ENTRYPOINT" && instruction.JSON == nil {
+			findings = append(findings, newFinding(instruction, i18n.T("Use the exec form (a JSON array) for ENTRYPOINT so that the process receives signals")))
+		}
+	}
+	return findings
+}
+
+// script 返回RUN执行的命令，包括heredoc的内容。
+func script(instruction *dockerfile.Instruction) string {
+	text := instruction.Args
+	for _, heredoc := range instruction.Heredocs {
+		text += "\n" + heredoc.Content
+	}
+	return text
+}
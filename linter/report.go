@@ -0,0 +1,176 @@
+package linter
+
+import (
+	"LGM/i18n"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// Formats 是支持的输出格式
+var Formats = []string{"text", "json", "sarif"}
+
+// Write 以给定的格式（见Formats）输出检查path的结果。
+func Write(out io.Writer, format, path string, findings []Finding) error {
+	switch format {
+	case "text":
+		return writeText(out, path, findings)
+	case "json":
+		return writeJSON(out, path, findings)
+	case "sarif":
+		return writeSARIF(out, path, findings)
+	}
+	return fmt.Errorf("unknown format '%s' (available: text, json, sarif)", format)
+}
+
+// writeText 每行输出一个问题（"Dockerfile:3: warning [base-latest] ..."），最后是各严重程度的问题数。
+func writeText(out io.Writer, path string, findings []Finding) error {
+	counts := make(map[Severity]int)
+	for _, finding := range findings {
+		counts[finding.Severity]++
+		if _, err := fmt.Fprintf(out, "%s:%d: %s [%s] %s\n", path, finding.Line, finding.Severity, finding.Rule, finding.Message); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(out, i18n.T("%d problems (%d errors, %d warnings, %d infos)", len(findings), counts[Error], counts[Warning], counts[Info]))
+	return err
+}
+
+type jsonReport struct {
+	File     string        `json:"file"`
+	Findings []jsonFinding `json:"findings"`
+}
+
+type jsonFinding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line"`
+	EndLine  int      `json:"endLine"`
+	Message  string   `json:"message"`
+}
+
+func writeJSON(out io.Writer, path string, findings []Finding) error {
+	report := jsonReport{File: path, Findings: make([]jsonFinding, 0, len(findings))}
+	for _, finding := range findings {
+		report.Findings = append(report.Findings, jsonFinding{
+			Rule:     finding.Rule,
+			Severity: finding.Severity,
+			Line:     finding.Line,
+			EndLine:  finding.EndLine,
+			Message:  finding.Message,
+		})
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// SARIF 2.1.0 的日志（只包括用到的属性），代码扫描工具（例如GitHub code scanning）可以读取
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// sarifLevel 返回严重程度对应的SARIF级别（info对应note）。
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	case Off:
+		return "none"
+	}
+	return "note"
+}
+
+func writeSARIF(out io.Writer, path string, findings []Finding) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "LGM", Rules: make([]sarifRule, len(Rules))}},
+		Results: make([]sarifResult, 0, len(findings)),
+	}
+	ruleIndex := make(map[string]int, len(Rules))
+	for idx, rule := range Rules {
+		ruleIndex[rule.ID] = idx
+		run.Tool.Driver.Rules[idx] = sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.Severity)},
+		}
+	}
+	for _, finding := range findings {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    finding.Rule,
+			RuleIndex: ruleIndex[finding.Rule],
+			Level:     sarifLevel(finding.Severity),
+			Message:   sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(path)},
+				Region:           sarifRegion{StartLine: finding.Line, EndLine: finding.EndLine},
+			}}},
+		})
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
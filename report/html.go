@@ -0,0 +1,236 @@
+package report
+
+import (
+	"LGM/dockerfile"
+	"LGM/filetree"
+	"LGM/i18n"
+	"LGM/image"
+	"LGM/shell"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"html"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// layerColumns 是报告的图层表中的列（映射了Dockerfile时在command列之前加入line列）
+var layerColumns = []string{"id", "created", "size", "files", "wasted", "command"}
+
+// page 是HTML报告模板的数据
+type page struct {
+	Language   string
+	Tags       []string
+	Dockerfile string
+	Size       string
+	Efficiency int
+	Wasted     string
+	Columns    []image.LayerColumn
+	Layers     []layerSection
+	// Inefficiencies 是浪费空间的文件（最大的在前）
+	Inefficiencies []inefficiency
+	Treemap        template.HTML
+	Generated      string
+}
+
+// layerSection 是一个图层在图层表中的一行以及它的命令和变化的文件
+type layerSection struct {
+	Index   int
+	Values  []string
+	Command template.HTML
+	Tree    template.HTML
+	// Hidden 是超过上限（maxEntries）而没有显示的文件和目录数
+	Hidden int
+}
+
+type inefficiency struct {
+	Count int
+	Size  string
+	Path  string
+}
+
+// WriteHTML 将分析结果写为一个独立的HTML页面：镜像的统计信息、图层表、每一层变化的文件（可以展开的文件树）、浪费空间的文件以及最终文件系统的大小树图。
+// 页面不引用任何外部资源（样式和树图都嵌入在页面中，也不需要JavaScript）。cache 用于构建各层的比较树，
+// 每一层最多显示maxEntries个文件和目录。
+func WriteHTML(out io.Writer, analysis *image.AnalysisResult, cache *filetree.TreeCache, maxEntries int) error {
+	columns, err := image.ParseLayerColumns(layerColumns)
+	if err != nil {
+		return err
+	}
+	data := page{
+		Language:   i18n.Language(),
+		Tags:       analysis.RepoTags,
+		Size:       humanize.Bytes(analysis.SizeBytes),
+		Efficiency: int(100.0 * analysis.Efficiency),
+		Wasted:     humanize.Bytes(analysis.WastedBytes),
+		Generated:  time.Now().Format(image.CreatedFormat),
+	}
+	if analysis.Dockerfile != nil {
+		data.Dockerfile = analysis.Dockerfile.Path
+		columns = image.WithLineColumn(columns)
+	}
+	data.Columns = columns
+
+	// the layers of the analysis are in reverse order, the report lists the first layer first
+	layers := append([]image.Layer{}, analysis.Layers...)
+	sort.Slice(layers, func(i, j int) bool {
+		return layers[i].Index() < layers[j].Index()
+	})
+	for _, layer := range layers {
+		section := layerSection{Index: layer.Index(), Values: make([]string, len(columns))}
+		for idx, column := range columns {
+			section.Values[idx] = column.Value(layer, analysis.SizeBytes)
+		}
+		section.Command = highlight(layer.Command())
+
+		writer := treeWriter{remaining: maxEntries}
+		if layer.Index() == 0 {
+			// everything in the first layer is added
+			writer.added = true
+			writer.writeChildren(analysis.RefTrees[0].Root, 0)
+		} else {
+			writer.writeChildren(cache.Get(0, layer.Index()-1, layer.Index(), layer.Index()).Root, 0)
+		}
+		section.Tree = template.HTML(writer.out.String())
+		section.Hidden = writer.hidden
+		data.Layers = append(data.Layers, section)
+	}
+
+	// the inefficiencies are sorted ascending, the largest is listed first
+	for idx := len(analysis.Inefficiencies) - 1; idx >= 0; idx-- {
+		fileData := analysis.Inefficiencies[idx]
+		data.Inefficiencies = append(data.Inefficiencies, inefficiency{
+			Count: len(fileData.Nodes),
+			Size:  humanize.Bytes(uint64(fileData.CumulativeSize)),
+			Path:  fileData.Path,
+		})
+	}
+
+	if len(analysis.RefTrees) > 0 {
+		data.Treemap = template.HTML(treemap(filetree.StackTreeRange(analysis.RefTrees, 0, len(analysis.RefTrees)-1)))
+	}
+
+	tmpl, err := template.New("report").Funcs(template.FuncMap{"T": i18n.T}).Parse(pageTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, data)
+}
+
+// syntaxClasses 是命令中各类Token的CSS类（与详情窗格的语法高亮相同的分类）
+var syntaxClasses = map[shell.TokenKind]string{
+	shell.Command:  "sh-command",
+	shell.Option:   "sh-option",
+	shell.String:   "sh-string",
+	shell.Variable: "sh-variable",
+	shell.Operator: "sh-operator",
+	shell.Comment:  "sh-comment",
+}
+
+// highlight 返回语法高亮的命令（与详情窗格相同，由 "&&" 等连接的命令分为多行），指令的关键字（例如 "RUN"）单独高亮。
+func highlight(command string) template.HTML {
+	keyword, script := "", command
+	if pos := strings.IndexByte(command, ' '); pos > 0 && dockerfile.IsInstruction(command[:pos]) && command[:pos] == strings.ToUpper(command[:pos]) {
+		keyword, script = command[:pos], command[pos+1:]
+	}
+	isShell := keyword == "" || keyword == "RUN" || keyword == "CMD" || keyword == "ENTRYPOINT"
+
+	var out strings.Builder
+	if keyword != "" {
+		fmt.Fprintf(&out, `<span class="sh-keyword">%s</span>`, keyword)
+	}
+	for idx, tokens := range shell.Split(script) {
+		switch {
+		case idx > 0:
+			out.WriteString("\n")
+		case keyword != "":
+			out.WriteString(" ")
+		}
+		for _, token := range tokens {
+			kind := token.Kind
+			if kind == shell.Command && !isShell {
+				kind = shell.Word
+			}
+			if class, ok := syntaxClasses[kind]; ok {
+				fmt.Fprintf(&out, `<span class="%s">%s</span>`, class, html.EscapeString(token.Text))
+			} else {
+				out.WriteString(html.EscapeString(token.Text))
+			}
+		}
+	}
+	return template.HTML(out.String())
+}
+
+// diffClasses 是各种变化的CSS类
+var diffClasses = map[filetree.DiffType]string{
+	filetree.Added:   "added",
+	filetree.Removed: "removed",
+	filetree.Changed: "modified",
+}
+
+// treeWriter 将一层中变化的文件输出为嵌套的 <details> 元素（目录可以展开），最多输出remaining个文件和目录。
+type treeWriter struct {
+	out strings.Builder
+	// added 指示所有的文件都是新增的（第一层），否则只输出比较树中变化的文件
+	added     bool
+	remaining int
+	hidden    int
+}
+
+// changed 返回子节点中需要输出的节点（按名称排列）。
+func (writer *treeWriter) changed(node *filetree.FileNode) []*filetree.FileNode {
+	var children []*filetree.FileNode
+	for _, child := range node.Children {
+		if child.IsWhiteout() || (!writer.added && child.Data.DiffType == filetree.Unchanged) {
+			continue
+		}
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+	return children
+}
+
+func (writer *treeWriter) writeChildren(node *filetree.FileNode, depth int) {
+	for _, child := range writer.changed(node) {
+		if writer.remaining <= 0 {
+			writer.hidden++
+			writer.countHidden(child)
+			continue
+		}
+		writer.remaining--
+
+		diffType := child.Data.DiffType
+		if writer.added {
+			diffType = filetree.Added
+		}
+		name := html.EscapeString(child.Name)
+		if child.Data.FileInfo.LinkName != "" {
+			name += " → " + html.EscapeString(child.Data.FileInfo.LinkName)
+		}
+		size := humanize.Bytes(uint64(child.CumulativeSize()))
+
+		if len(writer.changed(child)) == 0 {
+			fmt.Fprintf(&writer.out, `<div class="entry %s">%s <span class="size">%s</span></div>`, diffClasses[diffType], name, size)
+			continue
+		}
+		open := ""
+		if depth == 0 {
+			open = " open"
+		}
+		fmt.Fprintf(&writer.out, `<details class="%s"%s><summary>%s/ <span class="size">%s</span></summary>`, diffClasses[diffType], open, name, size)
+		writer.writeChildren(child, depth+1)
+		writer.out.WriteString(`</details>`)
+	}
+}
+
+// countHidden 计入超过上限的节点的子项。
+func (writer *treeWriter) countHidden(node *filetree.FileNode) {
+	for _, child := range writer.changed(node) {
+		writer.hidden++
+		writer.countHidden(child)
+	}
+}
@@ -0,0 +1,103 @@
+package report
+
+// pageTemplate 是HTML报告的模板（html/template），样式嵌入在页面中，不引用外部资源
+const pageTemplate = `<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{T "Image report"}}{{range $idx, $tag := .Tags}}{{if eq $idx 0}} – {{$tag}}{{end}}{{end}}</title>
+<style>
+body { margin: 0 auto; max-width: 1280px; padding: 1em 2em; font: 14px/1.45 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; background: #fff; }
+h1 { font-size: 1.6em; margin-bottom: .4em; }
+h2 { font-size: 1.25em; margin-top: 2em; border-bottom: 1px solid #ddd; padding-bottom: .2em; }
+h3 { font-size: 1em; margin: 1.5em 0 .4em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .25em .6em; border-bottom: 1px solid #eee; vertical-align: top; }
+th { background: #f6f6f6; }
+td.number, th.number { text-align: right; white-space: nowrap; }
+dl.stats { display: grid; grid-template-columns: max-content auto; gap: .2em 1em; }
+dl.stats dt { font-weight: bold; }
+dl.stats dd { margin: 0; }
+code, pre, .tree { font-family: SFMono-Regular, Menlo, Consolas, monospace; font-size: 13px; }
+pre { background: #f6f8fa; padding: .6em .8em; overflow-x: auto; margin: .4em 0; }
+.tree { border-left: 2px solid #eee; padding-left: .5em; }
+.tree details, .tree .entry { margin-left: 1.2em; }
+.tree > details, .tree > .entry { margin-left: 0; }
+.tree summary { cursor: pointer; }
+.size { color: #888; }
+.added > summary, .entry.added, .legend .added { color: #1a7f37; }
+.removed > summary, .entry.removed, .legend .removed { color: #cf222e; text-decoration: line-through; }
+.modified > summary, .entry.modified, .legend .modified { color: #9a6700; }
+.legend span { margin-right: 1.2em; }
+.note { color: #888; font-style: italic; }
+.sh-keyword { color: #8250df; font-weight: bold; }
+.sh-command { color: #0550ae; font-weight: bold; }
+.sh-option { color: #953800; }
+.sh-string { color: #0a3069; }
+.sh-variable { color: #8250df; }
+.sh-operator { color: #cf222e; }
+.sh-comment { color: #6e7781; font-style: italic; }
+svg.treemap { width: 100%; height: auto; border: 1px solid #ddd; }
+svg.treemap rect { stroke: #fff; stroke-width: 1; }
+svg.treemap text { font: 11px sans-serif; fill: #222; pointer-events: none; }
+footer { margin-top: 3em; color: #888; font-size: .9em; }
+</style>
+</head>
+<body>
+<h1>{{T "Image report"}}</h1>
+<dl class="stats">
+{{- if .Tags}}
+<dt>{{T "Image:"}}</dt><dd>{{range $idx, $tag := .Tags}}{{if $idx}}, {{end}}<code>{{$tag}}</code>{{end}}</dd>
+{{- end}}
+{{- if .Dockerfile}}
+<dt>{{T "Dockerfile:"}}</dt><dd><code>{{.Dockerfile}}</code></dd>
+{{- end}}
+<dt>{{T "Total Image size:"}}</dt><dd>{{.Size}}</dd>
+<dt>{{T "Layers"}}</dt><dd>{{len .Layers}}</dd>
+<dt>{{T "Image efficiency score:"}}</dt><dd>{{.Efficiency}} %</dd>
+<dt>{{T "Potential wasted space:"}}</dt><dd>{{.Wasted}}</dd>
+</dl>
+
+<h2>{{T "Layers"}}</h2>
+<table>
+<tr><th class="number">#</th>{{range .Columns}}<th{{if .RightAlign}} class="number"{{end}}>{{T .Title}}</th>{{end}}</tr>
+{{- range .Layers}}
+<tr><td class="number"><a href="#layer-{{.Index}}">{{.Index}}</a></td>{{range $idx, $value := .Values}}<td{{if (index $.Columns $idx).RightAlign}} class="number"{{end}}>{{$value}}</td>{{end}}</tr>
+{{- end}}
+</table>
+
+<h2>{{T "File changes by layer"}}</h2>
+<p class="legend"><span class="added">{{T "Added"}}</span><span class="removed">{{T "Removed"}}</span><span class="modified">{{T "Modified"}}</span></p>
+{{- range .Layers}}
+<h3 id="layer-{{.Index}}">{{T "Layer %d" .Index}}</h3>
+<pre>{{.Command}}</pre>
+{{- if .Tree}}
+<div class="tree">{{.Tree}}</div>
+{{- else if not .Hidden}}
+<p class="note">{{T "No file changes."}}</p>
+{{- end}}
+{{- if .Hidden}}
+<p class="note">{{T "%d more entries not shown" .Hidden}}</p>
+{{- end}}
+{{- end}}
+
+<h2>{{T "Inefficient files"}}</h2>
+{{- if .Inefficiencies}}
+<table>
+<tr><th class="number">{{T "Count"}}</th><th class="number">{{T "Total Space"}}</th><th>{{T "Path"}}</th></tr>
+{{- range .Inefficiencies}}
+<tr><td class="number">{{.Count}}</td><td class="number">{{.Size}}</td><td><code>{{.Path}}</code></td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="note">{{T "No inefficient files."}}</p>
+{{- end}}
+
+<h2>{{T "Size treemap"}}</h2>
+{{.Treemap}}
+
+<footer>{{T "Generated by LGM on %s" .Generated}}</footer>
+</body>
+</html>
+`
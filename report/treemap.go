@@ -0,0 +1,167 @@
+package report
+
+import (
+	"LGM/filetree"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"html"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// treemapWidth 和 treemapHeight 是树图SVG的坐标范围（显示时按页面宽度缩放）
+	treemapWidth  = 1200
+	treemapHeight = 600
+	// treemapDepth 是树图中显示的目录层数
+	treemapDepth = 4
+	// treemapMinSide 是显示的矩形的最小边长，更小的文件和目录只计入其父目录
+	treemapMinSide = 3
+	// treemapLabelHeight 是目录名称占用的高度（目录足够大时名称显示在子项之上）
+	treemapLabelHeight = 14
+)
+
+// rect 是树图中的一个矩形
+type rect struct {
+	x, y, w, h float64
+}
+
+// treemap 返回镜像最终的文件系统（所有层叠加之后）的大小树图（SVG）：每个文件或目录是一个面积与其大小成比例的矩形，
+// 鼠标悬停时显示路径和大小。同一个顶层目录中的矩形颜色相同，越深的目录颜色越深。
+func treemap(tree *filetree.FileTree) string {
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg class="treemap" viewBox="0 0 %d %d">`, treemapWidth, treemapHeight)
+	children := sizedChildren(tree.Root)
+	cells := squarify(childSizes(children), rect{0, 0, treemapWidth, treemapHeight})
+	for idx, child := range children {
+		hue := 360 * float64(idx) / float64(len(children))
+		renderTreemapNode(&svg, child, cells[idx], 0, hue)
+	}
+	svg.WriteString(`</svg>`)
+	return svg.String()
+}
+
+// renderTreemapNode 输出一个节点的矩形，目录足够大时在其中输出子项的矩形。
+func renderTreemapNode(svg *strings.Builder, node *filetree.FileNode, area rect, depth int, hue float64) {
+	if area.w < treemapMinSide || area.h < treemapMinSide {
+		return
+	}
+	lightness := 72 - 10*depth
+	fmt.Fprintf(svg, `<g><title>%s (%s)</title><rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="hsl(%.0f,55%%,%d%%)"/>`,
+		html.EscapeString(node.Path()), humanize.Bytes(uint64(node.CumulativeSize())), area.x, area.y, area.w, area.h, hue, lightness)
+
+	inner := rect{area.x + 1, area.y + 1, area.w - 2, area.h - 2}
+	showLabel := area.w > 40 && area.h > 2*treemapLabelHeight
+	if showLabel {
+		label := node.Name
+		if maxRunes := int(area.w / 7); len([]rune(label)) > maxRunes {
+			label = string([]rune(label)[:maxRunes-1]) + "…"
+		}
+		fmt.Fprintf(svg, `<text x="%.1f" y="%.1f">%s</text>`, area.x+3, area.y+11, html.EscapeString(label))
+		inner.y += treemapLabelHeight - 1
+		inner.h -= treemapLabelHeight - 1
+	}
+	svg.WriteString(`</g>`)
+
+	if depth+1 >= treemapDepth || !node.Data.FileInfo.IsDir || inner.w < treemapMinSide || inner.h < treemapMinSide {
+		return
+	}
+	children := sizedChildren(node)
+	if len(children) == 0 {
+		return
+	}
+	for idx, cell := range squarify(childSizes(children), inner) {
+		renderTreemapNode(svg, children[idx], cell, depth+1, hue)
+	}
+}
+
+// sizedChildren 返回大小不为0的子节点，按大小从大到小排列。
+func sizedChildren(node *filetree.FileNode) []*filetree.FileNode {
+	var children []*filetree.FileNode
+	for _, child := range node.Children {
+		if !child.IsWhiteout() && child.CumulativeSize() > 0 {
+			children = append(children, child)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].CumulativeSize() != children[j].CumulativeSize() {
+			return children[i].CumulativeSize() > children[j].CumulativeSize()
+		}
+		return children[i].Name < children[j].Name
+	})
+	return children
+}
+
+func childSizes(children []*filetree.FileNode) []float64 {
+	sizes := make([]float64, len(children))
+	for idx, child := range children {
+		sizes[idx] = float64(child.CumulativeSize())
+	}
+	return sizes
+}
+
+// squarify 按squarified算法将矩形分为面积与sizes（从大到小排列）成比例的矩形，使矩形尽量接近正方形。
+func squarify(sizes []float64, area rect) []rect {
+	result := make([]rect, 0, len(sizes))
+	var total float64
+	for _, size := range sizes {
+		total += size
+	}
+	if total <= 0 || area.w <= 0 || area.h <= 0 {
+		return append(result, make([]rect, len(sizes))...)
+	}
+	areas := make([]float64, len(sizes))
+	for idx, size := range sizes {
+		areas[idx] = size / total * area.w * area.h
+	}
+
+	for len(areas) > 0 {
+		side := math.Min(area.w, area.h)
+		count := 1
+		for count < len(areas) && worstRatio(areas[:count+1], side) <= worstRatio(areas[:count], side) {
+			count++
+		}
+		var sum float64
+		for _, cell := range areas[:count] {
+			sum += cell
+		}
+		// the row is placed along the shorter side, the rest of the rectangle is filled with the remaining cells
+		if area.w >= area.h {
+			width := sum / area.h
+			y := area.y
+			for _, cell := range areas[:count] {
+				result = append(result, rect{area.x, y, width, cell / width})
+				y += cell / width
+			}
+			area.x += width
+			area.w -= width
+		} else {
+			height := sum / area.w
+			x := area.x
+			for _, cell := range areas[:count] {
+				result = append(result, rect{x, area.y, cell / height, height})
+				x += cell / height
+			}
+			area.y += height
+			area.h -= height
+		}
+		areas = areas[count:]
+	}
+	return result
+}
+
+// worstRatio 返回沿长度为side的边排列一行面积为row的矩形时最大的长宽比。
+func worstRatio(row []float64, side float64) float64 {
+	var sum float64
+	smallest, largest := math.Inf(1), 0.0
+	for _, cell := range row {
+		sum += cell
+		smallest = math.Min(smallest, cell)
+		largest = math.Max(largest, cell)
+	}
+	if sum == 0 || smallest == 0 {
+		return math.Inf(1)
+	}
+	return math.Max(side*side*largest/(sum*sum), sum*sum/(side*side*smallest))
+}
@@ -0,0 +1,35 @@
+package runtime
+
+import (
+	"LGM/advisor"
+	"LGM/i18n"
+	"LGM/image"
+	"LGM/utils"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"io/ioutil"
+)
+
+// minSaving 返回建议至少需要节省的字节数（配置项 advice.min-saving）。
+func minSaving() uint64 {
+	minSaving, err := humanize.ParseBytes(viper.GetString("advice.min-saving"))
+	if err != nil {
+		logrus.Errorf("invalid config value: 'advice.min-saving': %v", err)
+		minSaving = 100 * humanize.KByte
+	}
+	return minSaving
+}
+
+// writeAdvice 将修改Dockerfile的建议（见advisor.Advise）以Markdown格式写入给定的文件。
+func writeAdvice(analysis *image.AnalysisResult, path string) {
+	fmt.Println(title(i18n.T("Writing suggestions to '%s'...", path)))
+	suggestions := advisor.Advise(analysis, minSaving())
+	if err := ioutil.WriteFile(path, []byte(advisor.Markdown(suggestions, analysis)), 0644); err != nil {
+		fmt.Println(i18n.T("cannot write the suggestions: %v", err))
+		utils.Exit(1)
+	}
+	saving, perBuild := advisor.TotalSaving(suggestions)
+	fmt.Println(i18n.T("%d suggestions, estimated saving %s (%s per rebuild)", len(suggestions), humanize.Bytes(saving), humanize.Bytes(perBuild)))
+}
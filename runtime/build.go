@@ -0,0 +1,35 @@
+package runtime
+
+import (
+	"LGM/i18n"
+	"LGM/utils"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// runBuild 使用给定的参数运行docker build（输出显示在当前终端中），返回构建的镜像ID。构建失败时退出程序。
+func runBuild(buildArgs []string) string {
+	iidFile, err := ioutil.TempFile("", "LGM-*.iid")
+	if err != nil {
+		fmt.Println(i18n.T("cannot build image: %v", err))
+		utils.Exit(1)
+	}
+	iidFile.Close()
+	defer os.Remove(iidFile.Name())
+
+	// docker writes the id of the built image to the iid file
+	allArgs := append([]string{"--iidfile", iidFile.Name()}, buildArgs...)
+	if err := utils.RunDockerCmd("build", allArgs...); err != nil {
+		fmt.Println(i18n.T("cannot build image: %v", err))
+		utils.Exit(1)
+	}
+
+	imageId, err := ioutil.ReadFile(iidFile.Name())
+	if err != nil {
+		fmt.Println(i18n.T("cannot build image: %v", err))
+		utils.Exit(1)
+	}
+	return strings.TrimSpace(string(imageId))
+}
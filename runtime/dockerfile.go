@@ -0,0 +1,26 @@
+package runtime
+
+import (
+	"LGM/dockerfile"
+	"LGM/i18n"
+	"LGM/image"
+	"LGM/utils"
+	"fmt"
+)
+
+// mapDockerfile 解析给定的Dockerfile，并将构建target阶段（为空时为最后一个阶段）的指令与镜像的历史记录对齐（见image.MapDockerfile）。
+func mapDockerfile(analysis *image.AnalysisResult, path, target string) {
+	fmt.Println(title(i18n.T("Mapping Dockerfile '%s'...", path)))
+	file, err := dockerfile.ParseFile(path)
+	if err != nil {
+		fmt.Println(i18n.T("cannot parse the Dockerfile: %v", err))
+		utils.Exit(1)
+	}
+	stage := file.Stage(target)
+	if stage == nil {
+		fmt.Println(i18n.T("unknown build stage '%s'", target))
+		utils.Exit(1)
+	}
+	mapped := image.MapDockerfile(analysis, file, target)
+	fmt.Println(i18n.T("%d of %d instructions found in the image history", mapped, len(file.Chain(stage))))
+}
@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"LGM/dockerfile"
+	"LGM/image"
+	"encoding/json"
+	"io/ioutil"
+)
+
+// newExport 将分析结果转换为导出文件（--json）的内容。
+func newExport(analysis *image.AnalysisResult) *export {
+	data := export{}
+	data.Layer = make([]exportLayer, len(analysis.Layers))
+	data.Image.InefficientFiles = make([]inefficientFiles, len(analysis.Inefficiencies))
+
+	// export layers in order
+	for idx, layer := range analysis.Layers {
+		data.Layer[idx] = exportLayer{
+			Index:     layer.Index(),
+			DigestID:  layer.Id(),
+			SizeBytes: layer.Size(),
+			Command:   layer.Command(),
+		}
+		data.Layer[idx].Instruction = newExportInstruction(layer.Instruction())
+	}
+
+	data.History = make([]exportHistory, len(analysis.History))
+	for idx, entry := range analysis.History {
+		data.History[idx] = exportHistory{
+			CreatedBy:   entry.CreatedBy,
+			EmptyLayer:  entry.EmptyLayer,
+			Layer:       entry.Layer,
+			Instruction: newExportInstruction(entry.Instruction),
+		}
+	}
+	if analysis.Dockerfile != nil {
+		data.Dockerfile = analysis.Dockerfile.Path
+	}
+
+	// add file references
+	data.Image.SizeBytes = analysis.SizeBytes
+	data.Image.EfficiencyScore = analysis.Efficiency
+	data.Image.InefficientBytes = analysis.WastedBytes
+
+	// the inefficiencies are sorted ascending, the largest is exported first
+	for idx := 0; idx < len(analysis.Inefficiencies); idx++ {
+		fileData := analysis.Inefficiencies[len(analysis.Inefficiencies)-1-idx]
+
+		data.Image.InefficientFiles[idx] = inefficientFiles{
+			Count:     len(fileData.Nodes),
+			SizeBytes: uint64(fileData.CumulativeSize),
+			File:      fileData.Path,
+		}
+	}
+
+	data.Image.Config = newExportConfig(analysis.Config)
+
+	return &data
+}
+
+// newExportInstruction 转换Dockerfile指令，没有对应的指令时返回nil。
+func newExportInstruction(instruction *dockerfile.Instruction) *exportInstruction {
+	if instruction == nil {
+		return nil
+	}
+	return &exportInstruction{
+		Line:    instruction.Line,
+		EndLine: instruction.EndLine,
+		Stage:   instruction.Stage,
+		Text:    instruction.Original,
+	}
+}
+
+// newExportConfig 转换镜像的运行配置，端口和卷按字母顺序排列。
+func newExportConfig(config image.ImageConfig) exportConfig {
+	result := exportConfig{
+		Env:          config.Env,
+		Entrypoint:   config.Entrypoint,
+		Cmd:          config.Cmd,
+		User:         config.User,
+		WorkingDir:   config.WorkingDir,
+		ExposedPorts: config.SortedExposedPorts(),
+		Volumes:      config.SortedVolumes(),
+		Labels:       config.Labels,
+		StopSignal:   config.StopSignal,
+		Annotations:  config.Annotations,
+	}
+	if health := config.Healthcheck; health != nil {
+		result.Healthcheck = &exportHealthcheck{
+			Test:        health.Test,
+			Interval:    health.Interval.String(),
+			Timeout:     health.Timeout.String(),
+			StartPeriod: health.StartPeriod.String(),
+			Retries:     health.Retries,
+		}
+	}
+	return result
+}
+
+func (exp *export) marshal() ([]byte, error) {
+	return json.MarshalIndent(&exp, "", "  ")
+}
+
+// toFile 将导出内容以JSON格式写入给定的文件。
+func (exp *export) toFile(exportFilePath string) error {
+	payload, err := exp.marshal()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(exportFilePath, payload, 0644)
+}
@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"LGM/i18n"
+	"LGM/image"
+	"LGM/utils"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"os"
+)
+
+// Extract 分析给定的镜像，并将其中的文件或目录提取到输出目录中（见image.Extract），不启动TUI。
+// 进度信息和结果写入标准错误，与find命令一致。
+func Extract(options Options) {
+	// the contents are read from the spooled layer tars
+	image.SpoolLayers = true
+	analyzer := fetchImage(options.ImageId, os.Stderr)
+	fmt.Fprintln(os.Stderr, title(i18n.T("Analyzing image...")))
+	result, err := analyzer.Analyze()
+	if err != nil {
+		fmt.Println(i18n.T("cannot analyze image: %v", err))
+		utils.Exit(1)
+	}
+
+	layerIdx := options.ExtractLayer
+	if layerIdx < 0 {
+		layerIdx = len(result.RefTrees) - 1
+	}
+	if layerIdx >= len(result.RefTrees) {
+		fmt.Println(i18n.T("invalid layer %d: the image has %d layers", options.ExtractLayer, len(result.RefTrees)))
+		utils.Exit(1)
+	}
+
+	fmt.Fprintln(os.Stderr, title(i18n.T("Extracting %s from layer %d...", options.ExtractPath, layerIdx)))
+	extracted, err := image.Extract(result.LayerFiles, result.RefTrees, layerIdx, options.ExtractOnlyLayer, options.ExtractPath, options.ExtractOutputDir)
+	if err != nil {
+		fmt.Println(i18n.T("cannot extract files: %v", err))
+		utils.Exit(1)
+	}
+
+	for _, skipped := range extracted.Skipped {
+		fmt.Fprintln(os.Stderr, i18n.T("skipped %s", skipped))
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("%d files (%s) extracted to %s", extracted.Files, humanize.Bytes(uint64(extracted.Bytes)), extracted.Target))
+}
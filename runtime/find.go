@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"LGM/filetree"
+	"LGM/i18n"
+	"LGM/utils"
+	"fmt"
+	"os"
+)
+
+// Find 分析给定的镜像，并列出每一层的变更中满足查询条件（见filetree.Query）的所有文件，不启动TUI。
+// 查询包含diff条件时也列出没有变化的文件，由diff条件选择变更类型。
+// 结果写入标准输出，进度信息写入标准错误，以便结果可以直接交给其他程序处理。
+func Find(options Options) {
+	// 在获取镜像之前检查查询，避免无效的查询白白等待
+	query, err := filetree.ParseQuery(options.Query)
+	if err != nil {
+		fmt.Println(i18n.T("cannot parse query: %v", err))
+		utils.Exit(1)
+	}
+
+	analyzer := fetchImage(options.ImageId, os.Stderr)
+	fmt.Fprintln(os.Stderr, title(i18n.T("Analyzing image...")))
+	result, err := analyzer.Analyze()
+	if err != nil {
+		fmt.Println(i18n.T("cannot analyze image: %v", err))
+		utils.Exit(1)
+	}
+
+	// only the changes are listed, unless the query selects the change types itself (e.g. diff:unchanged)
+	withUnchanged := query.HasField("diff")
+	cache := newTreeCache(result.RefTrees)
+	matches := 0
+	for layerIdx := range result.RefTrees {
+		tree := findLayerTree(cache, result.RefTrees, layerIdx)
+
+		err = tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			if (node.Data.DiffType == filetree.Unchanged && !withUnchanged) || !query.Match(node) {
+				return nil
+			}
+			matches++
+			fmt.Printf("%5d  %-9s %s%s\n", layerIdx, node.Data.DiffType, node.MetadataString(), node.Path())
+			return nil
+		}, nil)
+		if err != nil {
+			fmt.Println(i18n.T("cannot search layer %d: %v", layerIdx, err))
+			utils.Exit(1)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, i18n.T("%d matching files", matches))
+}
+
+// findLayerTree 返回给定层相对于其下所有层的变更树。基础层中的所有文件都视为新增的文件。
+func findLayerTree(cache *filetree.TreeCache, refTrees []*filetree.FileTree, layerIdx int) *filetree.FileTree {
+	if layerIdx > 0 {
+		return cache.Get(0, layerIdx-1, layerIdx, layerIdx)
+	}
+
+	tree := refTrees[0].Copy()
+	err := tree.VisitDepthChildFirst(func(node *filetree.FileNode) error {
+		node.Data.DiffType = filetree.Added
+		return nil
+	}, nil)
+	if err != nil {
+		fmt.Println(i18n.T("cannot search layer %d: %v", layerIdx, err))
+		utils.Exit(1)
+	}
+	return tree
+}
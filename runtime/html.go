@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"LGM/i18n"
+	"LGM/image"
+	"LGM/report"
+	"LGM/utils"
+	"bufio"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"os"
+)
+
+// maxTreeEntries 返回HTML报告中每一层最多显示的文件和目录数（配置项 html.max-tree-entries）。
+func maxTreeEntries() int {
+	maxEntries := viper.GetInt("html.max-tree-entries")
+	if maxEntries <= 0 {
+		logrus.Errorf("invalid config value: 'html.max-tree-entries': must be greater than 0, got %d", maxEntries)
+		maxEntries = 5000
+	}
+	return maxEntries
+}
+
+// writeHTML 将HTML报告（见report.WriteHTML）写入给定的文件。
+func writeHTML(analysis *image.AnalysisResult, path string) {
+	fmt.Println(title(i18n.T("Writing HTML report to '%s'...", path)))
+	err := writeHTMLFile(analysis, path)
+	if err != nil {
+		fmt.Println(i18n.T("cannot write the HTML report: %v", err))
+		utils.Exit(1)
+	}
+}
+
+func writeHTMLFile(analysis *image.AnalysisResult, path string) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	out := bufio.NewWriter(file)
+	if err = report.WriteHTML(out, analysis, newTreeCache(analysis.RefTrees), maxTreeEntries()); err != nil {
+		return err
+	}
+	return out.Flush()
+}
@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"LGM/dockerfile"
+	"LGM/i18n"
+	"LGM/linter"
+	"LGM/utils"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+)
+
+// Lint 按规则检查Dockerfile（options.Dockerfile），以options.LintFormat格式写入options.OutputFile（为空时写入标准输出）。
+// 存在严重程度不低于lint.fail-on的问题时以1退出。
+func Lint(options Options) {
+	file, err := dockerfile.ParseFile(options.Dockerfile)
+	if err != nil {
+		fmt.Println(i18n.T("cannot parse the Dockerfile: %v", err))
+		utils.Exit(1)
+	}
+	findings := linter.Lint(file, lintSeverities())
+
+	var out io.Writer = os.Stdout
+	var output *os.File
+	if options.OutputFile != "" {
+		output, err = os.Create(options.OutputFile)
+		if err != nil {
+			fmt.Println(i18n.T("cannot write the lint report: %v", err))
+			utils.Exit(1)
+		}
+		out = output
+	}
+	err = linter.Write(out, options.LintFormat, options.Dockerfile, findings)
+	if output != nil {
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Println(i18n.T("cannot write the lint report: %v", err))
+		utils.Exit(1)
+	}
+	if lintFailed(findings) {
+		utils.Exit(1)
+	}
+}
+
+// lintBeforeBuild 在构建之前检查Dockerfile（配置项 lint.before-build），存在严重程度不低于lint.fail-on的问题时不构建。
+func lintBeforeBuild(path string) {
+	fmt.Println(title(i18n.T("Linting Dockerfile '%s'...", path)))
+	file, err := dockerfile.ParseFile(path)
+	if err != nil {
+		fmt.Println(i18n.T("cannot parse the Dockerfile: %v", err))
+		utils.Exit(1)
+	}
+	findings := linter.Lint(file, lintSeverities())
+	linter.Write(os.Stdout, "text", path, findings)
+	if lintFailed(findings) {
+		fmt.Println(i18n.T("the Dockerfile has problems of severity %s or higher, the image is not built", lintFailOn()))
+		utils.Exit(1)
+	}
+}
+
+// lintSeverities 返回配置中各规则的严重程度（lint.rules.<规则>），无效的值使用规则默认的严重程度。
+func lintSeverities() map[string]linter.Severity {
+	severities := make(map[string]linter.Severity)
+	for _, rule := range linter.Rules {
+		key := "lint.rules." + rule.ID
+		if !viper.IsSet(key) {
+			continue
+		}
+		severity, err := linter.ParseSeverity(viper.GetString(key))
+		if err != nil {
+			logrus.Errorf("invalid config value: '%s': %v", key, err)
+			continue
+		}
+		severities[rule.ID] = severity
+	}
+	return severities
+}
+
+// lintFailOn 返回使检查失败的最低严重程度（配置项 lint.fail-on，off表示从不失败）。
+func lintFailOn() linter.Severity {
+	failOn, err := linter.ParseSeverity(viper.GetString("lint.fail-on"))
+	if err != nil {
+		logrus.Errorf("invalid config value: 'lint.fail-on': %v", err)
+		failOn = linter.Error
+	}
+	return failOn
+}
+
+// lintFailed 返回是否存在严重程度不低于lint.fail-on的问题。
+func lintFailed(findings []linter.Finding) bool {
+	failOn := lintFailOn()
+	if failOn == linter.Off {
+		return false
+	}
+	for _, finding := range findings {
+		if finding.Severity.AtLeast(failOn) {
+			return true
+		}
+	}
+	return false
+}
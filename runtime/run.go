@@ -2,76 +2,131 @@ package runtime
 
 import (
 	"LGM/filetree"
+	"LGM/i18n"
 	"LGM/image"
 	"LGM/ui"
 	"LGM/utils"
 	"fmt"
+	"github.com/dustin/go-humanize"
 	"github.com/logrusorgru/aurora"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"io"
+	"os"
 )
 
 func title(s string) string {
 	return aurora.Bold(s).String()
 }
 
+// fetchImage 获取并解析给定的镜像，进度信息写入out。出现错误时退出程序。
+func fetchImage(imageId string, out io.Writer) image.Analyzer {
+	analyzer := image.GetAnalyzer(imageId)
+	fmt.Fprintln(out, title(i18n.T("Fetching image..."))+" "+i18n.T("(this can take a while with large images)"))
+	reader, err := analyzer.Fetch()
+	if err != nil {
+		fmt.Println(i18n.T("cannot fetch image: %v", err))
+		utils.Exit(1)
+	}
+	defer reader.Close()
+
+	fmt.Fprintln(out, title(i18n.T("Parsing image...")))
+	err = analyzer.Parse(reader)
+	if err != nil {
+		fmt.Println(i18n.T("cannot parse image: %v", err))
+		utils.Exit(1)
+	}
+	return analyzer
+}
+
+// newTreeCache 为给定的图层树创建比较树缓存，内存上限来自配置（cache.memory-limit）。
+func newTreeCache(refTrees []*filetree.FileTree) *filetree.TreeCache {
+	cacheLimit, err := humanize.ParseBytes(viper.GetString("cache.memory-limit"))
+	if err != nil {
+		logrus.Errorf("invalid config value: 'cache.memory-limit': %v", err)
+		cacheLimit = 512 * humanize.MByte
+	}
+	return filetree.NewFileTreeCache(refTrees, cacheLimit)
+}
+
+// spoolLimit 返回保存的图层tar的总大小上限（配置项 viewer.spool-limit，0为不限制）。
+func spoolLimit() uint64 {
+	limit, err := humanize.ParseBytes(viper.GetString("viewer.spool-limit"))
+	if err != nil {
+		logrus.Errorf("invalid config value: 'viewer.spool-limit': %v", err)
+		limit = 2 * humanize.GByte
+	}
+	return limit
+}
+
 func Run(options Options) {
-	doExport := options.ExportFile != ""
+	// the exported files are written instead of running the TUI
+	doExport := options.ExportFile != "" || options.AdviceFile != "" || options.HtmlFile != ""
 
-	// Todo
+	// the build command passes the docker build arguments, the image is built before it is analyzed
 	doBuild := len(options.BuildArgs) > 0
 	//isCi, _ := strconv.ParseBool(os.Getenv("CI"))
 
-	// Todo
 	if doBuild {
-		fmt.Println(title("Buliding image..."))
-		//options.ImageId = runBuild(options.BuildArgs)
+		if viper.GetBool("lint.before-build") {
+			if options.Dockerfile != "" {
+				lintBeforeBuild(options.Dockerfile)
+			} else {
+				// e.g. a Dockerfile read from the standard input or a remote build context
+				fmt.Println(i18n.T("the Dockerfile is not a local file, it is built without linting"))
+			}
+		}
+		fmt.Println(title(i18n.T("Building image...")))
+		options.ImageId = runBuild(options.BuildArgs)
 	}
 
 	//对于一个已存在的镜像
 	//Fetching image... (this can take a while with large images)
 	//Parsing image...
 	//Analyzing image...
-	//Building cache...
 
-	analyzer := image.GetAnalyzer(options.ImageId)
-	fmt.Println(title("Fetching image...") + " (this can take a while with large images)")
-	reader, err := analyzer.Fetch()
-	if err != nil {
-		fmt.Printf("cannot fetch image: %v\n", err)
-		utils.Exit(1)
-	}
-	defer reader.Close()
-
-	// Todo Parsing
-	fmt.Println(title("Parsing image..."))
-	err = analyzer.Parse(reader)
-	if err != nil {
-		fmt.Printf("cannot parse image: %v\n", err)
-		utils.Exit(1)
-	}
+	// the file viewer reads the file contents from the spooled layers, an export does not need them
+	image.SpoolLayers = !doExport && viper.GetBool("viewer.spool-layers")
+	image.SpoolLimit = spoolLimit()
+	analyzer := fetchImage(options.ImageId, os.Stdout)
 
 	// Todo Analyze
 
 
-	if doExport {
-		fmt.Println(title(fmt.Sprintf("Analyzing image... (export to '%s')", options.ExportFile)))
+	if options.ExportFile != "" {
+		fmt.Println(title(i18n.T("Analyzing image... (export to '%s')", options.ExportFile)))
 	} else {
-		fmt.Println(title("Analyzing image..."))
+		fmt.Println(title(i18n.T("Analyzing image...")))
 	}
 
 	result, err := analyzer.Analyze()
 	if err != nil {
-		fmt.Printf("cannot analyze image: %v\n", err)
+		fmt.Println(i18n.T("cannot analyze image: %v", err))
 		utils.Exit(1)
 	}
+	if image.SpoolLayers && result.LayerFiles == nil {
+		fmt.Println(i18n.T("The image layers are larger than viewer.spool-limit (%s), they are not kept", humanize.Bytes(image.SpoolLimit)))
+	}
+
+	if options.Dockerfile != "" {
+		mapDockerfile(result, options.Dockerfile, options.Target)
+	}
+
+	if options.ExportFile != "" {
+		err = newExport(result).toFile(options.ExportFile)
+		if err != nil {
+			fmt.Println(i18n.T("cannot write export file: %v", err))
+			utils.Exit(1)
+		}
+	}
 
-	// Todo
-	//if doExport {
-	//	err = newExport(result).toFile(options.ExportFile)
-	//	if err != nil {
-	//		fmt.Printf("cannot write export file: %v\n", err)
-	//		utils.Exit(1)
-	//	}
-	//}
+	if options.AdviceFile != "" {
+		writeAdvice(result, options.AdviceFile)
+	}
+
+	if options.HtmlFile != "" {
+		writeHTML(result, options.HtmlFile)
+	}
 
 	//if isCi {
 	//
@@ -81,11 +136,8 @@ func Run(options Options) {
 		utils.Exit(0)
 	}
 
-	fmt.Println(title("Building cache..."))
-	cache := filetree.NewFileTreeCache(result.RefTrees)
-	cache.Build()
-
-	ui.Run(result, cache)
+	// 比较树在UI中按需构建，这里只创建缓存
+	ui.Run(result, newTreeCache(result.RefTrees), minSaving())
 
 
 }
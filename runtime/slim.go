@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"LGM/filetree"
+	"LGM/i18n"
+	"LGM/image"
+	"LGM/theme"
+	"LGM/utils"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"os"
+	"strings"
+)
+
+// Slim 分析给定的镜像，从各层中删除与规则文件（options.RulesFile，为空时不删除任何文件）匹配的文件，并将新的镜像写为可以用docker load加载的归档
+// （options.OutputFile，格式为options.ArchiveFormat）。
+// 进度信息和结果写入标准错误，与find命令一致。
+func Slim(options Options) {
+	// 在获取镜像之前读取规则，避免无效的规则白白等待
+	rules, err := filetree.NewRemovalRules(nil)
+	if options.RulesFile != "" {
+		rules, err = image.LoadRemovalRules(options.RulesFile)
+	}
+	if err != nil {
+		fmt.Println(i18n.T("cannot read the rules file: %v", err))
+		utils.Exit(1)
+	}
+
+	// the layers are rewritten from the spooled layer tars
+	image.SpoolLayers = true
+	analyzer := fetchImage(options.ImageId, os.Stderr)
+	fmt.Fprintln(os.Stderr, title(i18n.T("Analyzing image...")))
+	result, err := analyzer.Analyze()
+	if err != nil {
+		fmt.Println(i18n.T("cannot analyze image: %v", err))
+		utils.Exit(1)
+	}
+	if result.Slim == nil {
+		fmt.Println(i18n.T("cannot write the image: %v", i18n.T("the image layers are not available")))
+		utils.Exit(1)
+	}
+
+	tags := options.Tags
+	if len(tags) == 0 {
+		tags = result.RepoTags
+		if rules.Len() > 0 {
+			tags = image.SlimTags(result.RepoTags)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, title(i18n.T("Writing image to '%s'...", options.OutputFile)))
+	file, err := os.Create(options.OutputFile)
+	if err != nil {
+		fmt.Println(i18n.T("cannot write the image: %v", err))
+		utils.Exit(1)
+	}
+	slim, err := result.Slim.WriteSlim(file, options.ArchiveFormat, rules, tags)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(options.OutputFile)
+		fmt.Println(i18n.T("cannot write the image: %v", err))
+		utils.Exit(1)
+	}
+
+	fmt.Fprintln(os.Stderr, i18n.T("%d entries removed from %d layers", slim.RemovedEntries, slim.ChangedLayers))
+	saved := slim.OriginalSize - slim.Size
+	fmt.Fprintln(os.Stderr, i18n.T("Layer size: %s %s %s (%s saved)", humanize.Bytes(uint64(slim.OriginalSize)), theme.Glyphs.Range, humanize.Bytes(uint64(slim.Size)), humanize.Bytes(uint64(saved))))
+	if len(slim.Tags) > 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("Tags: %s", strings.Join(slim.Tags, ", ")))
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("Load it with: docker load -i %s", options.OutputFile))
+}
@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"LGM/i18n"
+	"LGM/image"
+	"LGM/theme"
+	"LGM/utils"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"os"
+	"strings"
+)
+
+// Squash 分析给定的镜像，预测将第options.SquashLower到第options.SquashUpper层合并为一层后的镜像大小和效率（见image.EstimateSquash），
+// options.OutputFile 不为空时还将合并后的镜像写为可以用docker load加载的归档。进度信息和结果写入标准错误，与find命令一致。
+func Squash(options Options) {
+	// only writing the image needs the layer contents
+	image.SpoolLayers = options.OutputFile != ""
+	analyzer := fetchImage(options.ImageId, os.Stderr)
+	fmt.Fprintln(os.Stderr, title(i18n.T("Analyzing image...")))
+	result, err := analyzer.Analyze()
+	if err != nil {
+		fmt.Println(i18n.T("cannot analyze image: %v", err))
+		utils.Exit(1)
+	}
+
+	lower, upper := options.SquashLower, options.SquashUpper
+	if upper < 0 {
+		upper = len(result.RefTrees) - 1
+	}
+	if lower < 0 || lower >= upper || upper >= len(result.RefTrees) {
+		fmt.Println(i18n.T("invalid layer range %d-%d: the image has %d layers", lower, upper, len(result.RefTrees)))
+		utils.Exit(1)
+	}
+
+	fmt.Fprintln(os.Stderr, title(i18n.T("Squashing layers %d-%d...", lower, upper)))
+	estimate, err := image.EstimateSquash(result, lower, upper)
+	if err != nil {
+		fmt.Println(i18n.T("cannot squash the layers: %v", err))
+		utils.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("Merged layer size: %s %s %s", humanize.Bytes(estimate.LayersSize), theme.Glyphs.Range, humanize.Bytes(estimate.Size)))
+	fmt.Fprintln(os.Stderr, i18n.T("Total image size: %s %s %s", humanize.Bytes(estimate.ImageSize), theme.Glyphs.Range, humanize.Bytes(estimate.ProjectedImageSize)))
+	fmt.Fprintln(os.Stderr, i18n.T("Potential wasted space: %s %s %s", humanize.Bytes(estimate.WastedBytes), theme.Glyphs.Range, humanize.Bytes(estimate.ProjectedWastedBytes)))
+	fmt.Fprintln(os.Stderr, i18n.T("Image efficiency score: %d %% %s %d %%", int(100.0*estimate.Efficiency), theme.Glyphs.Range, int(100.0*estimate.ProjectedEfficiency)))
+	if options.OutputFile == "" {
+		return
+	}
+
+	tags := options.Tags
+	if len(tags) == 0 {
+		tags = image.SquashTags(result.RepoTags)
+	}
+	fmt.Fprintln(os.Stderr, title(i18n.T("Writing image to '%s'...", options.OutputFile)))
+	file, err := os.Create(options.OutputFile)
+	if err != nil {
+		fmt.Println(i18n.T("cannot write the image: %v", err))
+		utils.Exit(1)
+	}
+	squashed, err := result.Squash.WriteSquashed(file, estimate.Layer, tags)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(options.OutputFile)
+		fmt.Println(i18n.T("cannot write the image: %v", err))
+		utils.Exit(1)
+	}
+
+	var saved int64
+	if squashed.Size < squashed.OriginalSize {
+		saved = squashed.OriginalSize - squashed.Size
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("Layer size: %s %s %s (%s saved)", humanize.Bytes(uint64(squashed.OriginalSize)), theme.Glyphs.Range, humanize.Bytes(uint64(squashed.Size)), humanize.Bytes(uint64(saved))))
+	if len(squashed.Tags) > 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("Tags: %s", strings.Join(squashed.Tags, ", ")))
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("Load it with: docker load -i %s", options.OutputFile))
+}
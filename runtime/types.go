@@ -1,22 +1,68 @@
 package runtime
 
+import "LGM/image"
+
 type Options struct {
 	ImageId      string
 	ExportFile   string
 	CiConfigFile string
 	BuildArgs    []string
+	Query        string
+	// ExtractPath 是extract命令提取的路径，ExtractLayer 是提取的图层（-1为最后一层），ExtractOnlyLayer 指示只提取该层自身包含的文件
+	ExtractPath      string
+	ExtractLayer     int
+	ExtractOnlyLayer bool
+	ExtractOutputDir string
+	// RulesFile 是slim规则文件（为空时不删除任何文件），OutputFile 是写入的镜像归档，Tags 是新镜像的标签（为空时使用默认的标签）
+	RulesFile  string
+	OutputFile string
+	Tags       []string
+	// ArchiveFormat 是slim和export命令写入的镜像归档的格式（docker-archive或OCI镜像布局）
+	ArchiveFormat image.ArchiveFormat
+	// SquashLower 和 SquashUpper 是squash命令合并的第一层和最后一层（-1为最后一层），合并的镜像写入OutputFile（为空时只预测结果）
+	SquashLower int
+	SquashUpper int
+	// Dockerfile 是与镜像的历史记录对齐的Dockerfile（为空时不对齐），Target 是构建的阶段（为空时为最后一个阶段）
+	Dockerfile string
+	Target     string
+	// AdviceFile 是写入修改Dockerfile的建议（Markdown）的文件，为空时不写入
+	AdviceFile string
+	// HtmlFile 是写入HTML报告的文件，为空时不写入
+	HtmlFile string
+	// LintFormat 是lint命令的输出格式（text、json或sarif），结果写入OutputFile（为空时写入标准输出）
+	LintFormat string
 }
 
 type export struct {
 	Layer []exportLayer `json:"layer"`
 	Image exportImage   `json:"image"`
+	// Dockerfile 是与历史记录对齐的Dockerfile的路径，History 是镜像的历史记录（包括empty_layer的记录）
+	Dockerfile string          `json:"dockerfile,omitempty"`
+	History    []exportHistory `json:"history"`
 }
 
 type exportLayer struct {
-	Index     int    `json:"index"`
-	DigestID  string `json:"digestId"`
-	SizeBytes uint64 `json:"sizeBytes"`
-	Command   string `json:"command"`
+	Index       int                `json:"index"`
+	DigestID    string             `json:"digestId"`
+	SizeBytes   uint64             `json:"sizeBytes"`
+	Command     string             `json:"command"`
+	Instruction *exportInstruction `json:"instruction,omitempty"`
+}
+
+// exportHistory 是镜像历史记录中的一条，Layer 是对应的层（empty_layer的记录为-1）
+type exportHistory struct {
+	CreatedBy   string             `json:"createdBy"`
+	EmptyLayer  bool               `json:"emptyLayer"`
+	Layer       int                `json:"layer"`
+	Instruction *exportInstruction `json:"instruction,omitempty"`
+}
+
+// exportInstruction 是产生图层或历史记录的Dockerfile指令，Text 是指令的原文（包括续行和heredoc）
+type exportInstruction struct {
+	Line    int    `json:"line"`
+	EndLine int    `json:"endLine"`
+	Stage   int    `json:"stage"`
+	Text    string `json:"text"`
 }
 
 type exportImage struct {
@@ -24,6 +70,31 @@ type exportImage struct {
 	InefficientBytes uint64             `json:"inefficientBytes"`
 	EfficiencyScore  float64            `json:"efficiencyScore"`
 	InefficientFiles []inefficientFiles `json:"inefficientFiles"`
+	Config           exportConfig       `json:"config"`
+}
+
+// exportConfig 是镜像的运行配置（见image.ImageConfig）
+type exportConfig struct {
+	Env          []string           `json:"env"`
+	Entrypoint   []string           `json:"entrypoint"`
+	Cmd          []string           `json:"cmd"`
+	User         string             `json:"user"`
+	WorkingDir   string             `json:"workingDir"`
+	ExposedPorts []string           `json:"exposedPorts"`
+	Volumes      []string           `json:"volumes"`
+	Labels       map[string]string  `json:"labels"`
+	Healthcheck  *exportHealthcheck `json:"healthcheck,omitempty"`
+	StopSignal   string             `json:"stopSignal"`
+	Annotations  map[string]string  `json:"annotations"`
+}
+
+// exportHealthcheck 是镜像的健康检查配置，时间间隔以Go的时长格式表示（例如 "30s"）
+type exportHealthcheck struct {
+	Test        []string `json:"test"`
+	Interval    string   `json:"interval"`
+	Timeout     string   `json:"timeout"`
+	StartPeriod string   `json:"startPeriod"`
+	Retries     int      `json:"retries"`
 }
 
 type inefficientFiles struct {
@@ -0,0 +1,369 @@
+package shell
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TokenKind 是shell命令中一段文本的类型（用于语法高亮）
+type TokenKind int
+
+const (
+	// Word 是普通的参数
+	Word TokenKind = iota
+	// Command 是简单命令的第一个单词（命令名称）
+	Command
+	// Option 是以 "-" 开头的参数
+	Option
+	// String 是引号中的文本（包括引号）
+	String
+	// Variable 是变量引用，例如 "$HOME" 或 "${VERSION}"
+	Variable
+	// Operator 是控制和重定向运算符，例如 "&&"、";"、"|"、">" 和 "$("
+	Operator
+	// Comment 是从 "#" 到行尾的注释
+	Comment
+	// Space 是空白（包括换行和续行）
+	Space
+)
+
+// Token 是shell命令中的一段文本，所有Token的文本连接起来就是原来的命令
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+// operators 是识别的运算符，较长的在前
+var operators = []string{"&&", "||", ";;", ">>", "<<", ">&", "<&", "&", "|", ";", ">", "<", "(", ")"}
+
+// shellKeywords 是摘要中不跟子命令的shell关键字（例如 "for f in ..." 中的 "f" 不是子命令）
+var shellKeywords = map[string]bool{"for": true, "while": true, "until": true, "if": true, "case": true, "select": true}
+
+// subcommandPattern 匹配摘要中保留的子命令（例如 "apt-get install" 中的 "install"）
+var subcommandPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Tokenize 将shell命令分为Token，识别引号、变量、运算符、注释以及每个简单命令的命令名称。
+// 这不是完整的shell语法分析，只用于显示，无法识别的文本作为Word。
+func Tokenize(script string) []Token {
+	var tokens []Token
+	add := func(kind TokenKind, text string) {
+		if text == "" {
+			return
+		}
+		if last := len(tokens) - 1; last >= 0 && tokens[last].Kind == kind && (kind == Space || kind == Word) {
+			tokens[last].Text += text
+			return
+		}
+		tokens = append(tokens, Token{Kind: kind, Text: text})
+	}
+
+	runes := []rune(script)
+	atCommand := true
+	for idx := 0; idx < len(runes); {
+		r := runes[idx]
+		switch {
+		case r == '\\' && idx+1 < len(runes) && runes[idx+1] == '\n':
+			add(Space, "\\\n")
+			idx += 2
+			continue
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if r == '\n' {
+				atCommand = true
+			}
+			add(Space, string(r))
+			idx++
+			continue
+		case r == '#':
+			end := idx
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			add(Comment, string(runes[idx:end]))
+			idx = end
+			continue
+		}
+		if operator := operatorAt(runes, idx); operator != "" {
+			add(Operator, operator)
+			idx += len([]rune(operator))
+			switch operator {
+			case ">", ">>", "<", ">&", "<&", "<<":
+				// the redirection target is not a command
+			default:
+				atCommand = true
+			}
+			continue
+		}
+
+		// a word: unquoted text, quoted strings and variables up to the next space or operator
+		start := len(tokens)
+		end := idx
+	word:
+		for end < len(runes) {
+			r := runes[end]
+			if r == ' ' || r == '\t' || r == '\n' || r == '\r' || (r != '$' && operatorAt(runes, end) != "") {
+				break
+			}
+			if r == '\\' && end+1 < len(runes) && runes[end+1] == '\n' {
+				// a line continuation ends the word
+				break
+			}
+			switch {
+			case r == '\\' && end+1 < len(runes):
+				add(Word, string(runes[end:end+2]))
+				end += 2
+			case r == '\'' || r == '"':
+				close := end + 1
+				for close < len(runes) && runes[close] != r {
+					if r == '"' && runes[close] == '\\' {
+						close++
+					}
+					close++
+				}
+				if close < len(runes) {
+					close++
+				}
+				if close > len(runes) {
+					close = len(runes)
+				}
+				add(String, string(runes[end:close]))
+				end = close
+			case r == '$' && end+1 < len(runes) && runes[end+1] == '(':
+				break word
+			case r == '$':
+				close := variableEnd(runes, end)
+				add(Variable, string(runes[end:close]))
+				end = close
+			default:
+				add(Word, string(r))
+				end++
+			}
+		}
+		if end == idx {
+			// "$(" starts a command substitution
+			add(Operator, "$(")
+			idx += 2
+			atCommand = true
+			continue
+		}
+		if first := tokens[start]; first.Kind == Word {
+			switch {
+			case atCommand && strings.Contains(first.Text, "=") && !strings.HasPrefix(first.Text, "="):
+				// a variable assignment before the command, e.g. "DEBIAN_FRONTEND=noninteractive apt-get"
+				idx = end
+				continue
+			case atCommand:
+				tokens[start].Kind = Command
+			case strings.HasPrefix(first.Text, "-"):
+				tokens[start].Kind = Option
+			}
+		}
+		atCommand = false
+		idx = end
+	}
+	return tokens
+}
+
+// operatorAt 返回从idx开始的运算符，不是运算符时返回空字符串。
+func operatorAt(runes []rune, idx int) string {
+	if runes[idx] == '$' && idx+1 < len(runes) && runes[idx+1] == '(' {
+		return ""
+	}
+	for _, operator := range operators {
+		if strings.HasPrefix(string(runes[idx:min(idx+len(operator), len(runes))]), operator) {
+			return operator
+		}
+	}
+	return ""
+}
+
+// variableEnd 返回从idx（"$"）开始的变量引用之后的位置。
+func variableEnd(runes []rune, idx int) int {
+	end := idx + 1
+	if end < len(runes) && runes[end] == '{' {
+		for end < len(runes) && runes[end] != '}' {
+			end++
+		}
+		if end < len(runes) {
+			end++
+		}
+		return end
+	}
+	if end < len(runes) && strings.ContainsRune("?$!#@*-0123456789", runes[end]) {
+		return end + 1
+	}
+	for end < len(runes) && (runes[end] == '_' || (runes[end] >= 'a' && runes[end] <= 'z') || (runes[end] >= 'A' && runes[end] <= 'Z') || (runes[end] >= '0' && runes[end] <= '9')) {
+		end++
+	}
+	return end
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// segment 是命令中由 "&&"、"||" 或 ";" 分隔的一部分，operator 是它之前的运算符（第一部分为空）
+type segment struct {
+	operator string
+	tokens   []Token
+}
+
+// segments 在顶层（括号、命令替换和复合命令之外）的 "&&"、"||" 和 ";" 处分割命令，去掉各部分两端的空白。
+func segments(tokens []Token) []segment {
+	var result []segment
+	current := segment{}
+	depth := 0
+	flush := func() {
+		current.tokens = trimSpace(current.tokens)
+		if len(current.tokens) > 0 {
+			result = append(result, current)
+		}
+	}
+	for _, token := range tokens {
+		if token.Kind == Command {
+			// the commands within a compound command (e.g. "for ...; do ...; done") are kept together
+			switch token.Text {
+			case "for", "while", "until", "if", "case", "select", "{":
+				depth++
+			case "done", "fi", "esac", "}":
+				if depth > 0 {
+					depth--
+				}
+			}
+		}
+		if token.Kind == Operator {
+			switch token.Text {
+			case "(", "$(":
+				depth++
+			case ")":
+				if depth > 0 {
+					depth--
+				}
+			case "&&", "||", ";":
+				if depth == 0 {
+					flush()
+					current = segment{operator: token.Text}
+					continue
+				}
+			}
+		}
+		current.tokens = append(current.tokens, token)
+	}
+	flush()
+	return result
+}
+
+// trimSpace 去掉两端的空白，并将中间连续的空白（包括续行）合并为一个空格。
+func trimSpace(tokens []Token) []Token {
+	var result []Token
+	for _, token := range tokens {
+		if token.Kind == Space {
+			if len(result) == 0 {
+				continue
+			}
+			token.Text = " "
+		}
+		result = append(result, token)
+	}
+	for len(result) > 0 && result[len(result)-1].Kind == Space {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
+// Split 将由 "&&"、"||" 和 ";" 连接的命令分为多行（与Dockerfile中的写法一致，除最后一行外以 " \" 结尾），返回每一行的Token。
+// 命令包含换行（例如heredoc）或注释时按原来的行返回。
+func Split(script string) [][]Token {
+	tokens := Tokenize(script)
+	if strings.Contains(script, "\n") {
+		return splitLines(tokens)
+	}
+	for _, token := range tokens {
+		if token.Kind == Comment {
+			return splitLines(tokens)
+		}
+	}
+
+	parts := segments(tokens)
+	lines := make([][]Token, len(parts))
+	for idx, part := range parts {
+		var line []Token
+		if idx > 0 {
+			line = append(line, Token{Kind: Space, Text: "    "}, Token{Kind: Operator, Text: part.operator}, Token{Kind: Space, Text: " "})
+		}
+		line = append(line, part.tokens...)
+		if idx < len(parts)-1 {
+			line = append(line, Token{Kind: Space, Text: " \\"})
+		}
+		lines[idx] = line
+	}
+	return lines
+}
+
+// splitLines 在换行处分割Token。
+func splitLines(tokens []Token) [][]Token {
+	lines := [][]Token{nil}
+	for _, token := range tokens {
+		parts := []string{token.Text}
+		if token.Kind == Space || token.Kind == String {
+			parts = strings.Split(token.Text, "\n")
+		}
+		for idx, part := range parts {
+			if idx > 0 {
+				lines = append(lines, nil)
+			}
+			if part != "" {
+				lines[len(lines)-1] = append(lines[len(lines)-1], Token{Kind: token.Kind, Text: part})
+			}
+		}
+	}
+	return lines
+}
+
+// Text 返回Token的文本。
+func Text(tokens []Token) string {
+	var text strings.Builder
+	for _, token := range tokens {
+		text.WriteString(token.Text)
+	}
+	return text.String()
+}
+
+// Summary 返回命令的简短摘要：只有一个命令时为该命令（连续的空白合并为一个空格），
+// 由 "&&"、"||" 或 ";" 连接的多个命令时为各命令的名称和子命令，例如 "apt-get update, apt-get install, rm"。
+func Summary(script string) string {
+	parts := segments(Tokenize(script))
+	if len(parts) == 0 {
+		return ""
+	}
+	if len(parts) == 1 {
+		return Text(parts[0].tokens)
+	}
+	var names []string
+	for _, part := range parts {
+		name := ""
+		for _, token := range part.tokens {
+			if name == "" {
+				if token.Kind == Command {
+					name = token.Text
+				}
+				continue
+			}
+			if token.Kind == Word && subcommandPattern.MatchString(token.Text) && !shellKeywords[name] {
+				name += " " + token.Text
+			}
+			if token.Kind != Space {
+				break
+			}
+		}
+		if name == "" {
+			name = Text(part.tokens)
+		}
+		if len(names) == 0 || names[len(names)-1] != name {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,222 @@
+package textdiff
+
+import (
+	"fmt"
+)
+
+// Op 是差异中一行的操作
+type Op int
+
+const (
+	// Equal 表示两个文本中都有的行
+	Equal Op = iota
+	// Delete 表示只在旧文本中的行
+	Delete
+	// Insert 表示只在新文本中的行
+	Insert
+)
+
+// Edit 是差异中的一行
+type Edit struct {
+	Op   Op
+	Line string
+}
+
+// MaxEdits 是Myers算法查找最短差异时允许的最多增删行数，超过时将两个文本中不同的部分整体视为删除和插入（避免大文件耗费过多的时间和内存）。
+var MaxEdits = 1000
+
+// Diff 按行比较两个文本，返回将a变为b的编辑序列（Myers算法，增删的行数最少）。
+func Diff(a, b []string) []Edit {
+	// the common prefix and suffix are usually most of a file, they are not handed to the O(ND) search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{Op: Equal, Line: line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Op: Equal, Line: line})
+	}
+	return edits
+}
+
+// myers 使用Myers的O(ND)算法查找最短的编辑序列，增删的行数超过MaxEdits时返回replaceAll的结果。
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceAll(a, b)
+	}
+	limit := n + m
+	if limit > MaxEdits {
+		limit = MaxEdits
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k (y = x - k)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace[d] keeps v for the diagonals -(d+1)..d+1 before step d, for the backtracking
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+// backtrack 从终点沿myers保存的各步状态回溯，按顺序返回编辑序列。
+func backtrack(trace [][]int, a, b []string) []Edit {
+	var reversed []Edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		// the furthest x of diagonal k before step d
+		prev := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev(k-1) < prev(k+1)) {
+			prevK = k + 1
+		}
+		prevX := prev(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Edit{Op: Equal, Line: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, Edit{Op: Insert, Line: b[y-1]})
+		} else {
+			reversed = append(reversed, Edit{Op: Delete, Line: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, Edit{Op: Equal, Line: a[x-1]})
+		x--
+		y--
+	}
+
+	edits := make([]Edit, len(reversed))
+	for idx, edit := range reversed {
+		edits[len(reversed)-1-idx] = edit
+	}
+	return edits
+}
+
+// replaceAll 返回删除a的所有行并插入b的所有行的编辑序列。
+func replaceAll(a, b []string) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, Edit{Op: Delete, Line: line})
+	}
+	for _, line := range b {
+		edits = append(edits, Edit{Op: Insert, Line: line})
+	}
+	return edits
+}
+
+// Unified 以统一格式（与 "diff -u" 相同）返回两个文本的差异：文件名的标题以及每处修改前后最多context行未修改的行，两个文本相同时返回nil。
+func Unified(a, b []string, fromName, toName string, context int) []string {
+	edits := Diff(a, b)
+
+	// the line numbers in a and b where each edit starts
+	aLines := make([]int, len(edits)+1)
+	bLines := make([]int, len(edits)+1)
+	for idx, edit := range edits {
+		aLines[idx+1], bLines[idx+1] = aLines[idx], bLines[idx]
+		if edit.Op != Insert {
+			aLines[idx+1]++
+		}
+		if edit.Op != Delete {
+			bLines[idx+1]++
+		}
+	}
+
+	var lines []string
+	for idx := 0; idx < len(edits); {
+		for idx < len(edits) && edits[idx].Op == Equal {
+			idx++
+		}
+		if idx == len(edits) {
+			break
+		}
+
+		// changes separated by at most 2*context unchanged lines share a hunk
+		start := idx - context
+		if start < 0 {
+			start = 0
+		}
+		end := idx
+		for {
+			for end < len(edits) && edits[end].Op != Equal {
+				end++
+			}
+			next := end
+			for next < len(edits) && edits[next].Op == Equal {
+				next++
+			}
+			if next < len(edits) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end += context
+			if end > len(edits) {
+				end = len(edits)
+			}
+			break
+		}
+
+		if lines == nil {
+			lines = append(lines, "--- "+fromName, "+++ "+toName)
+		}
+		lines = append(lines, fmt.Sprintf("@@ -%s +%s @@", hunkRange(aLines[start], aLines[end]), hunkRange(bLines[start], bLines[end])))
+		for _, edit := range edits[start:end] {
+			switch edit.Op {
+			case Equal:
+				lines = append(lines, " "+edit.Line)
+			case Delete:
+				lines = append(lines, "-"+edit.Line)
+			case Insert:
+				lines = append(lines, "+"+edit.Line)
+			}
+		}
+		idx = end
+	}
+	return lines
+}
+
+// hunkRange 返回hunk标题中的行范围（从1开始的首行及行数，与diff一样省略为1的行数，空范围的首行是之前的一行）。
+func hunkRange(start, end int) string {
+	count := end - start
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -0,0 +1,109 @@
+package theme
+
+import (
+	"fmt"
+	"sort"
+)
+
+// GlyphSet 包含界面中使用的所有特殊字符。窗格的边框由gocui绘制，不受字形集影响。
+type GlyphSet struct {
+	// file tree branches (each TreeSpace/TreeBranch is one indentation level)
+	TreeSpace     string
+	TreeBranch    string
+	TreeMiddle    string
+	TreeLast      string
+	TreeExpanded  string
+	TreeCollapsed string
+
+	// symlink target ("name → target") and compared layer range ("1 → 3")
+	Link  string
+	Range string
+
+	// marks the selected pane and the bounds of a layer range comparison
+	Bullet string
+	// separates the options in the status bar
+	Separator string
+	// fills the pane headers
+	Rule string
+	// marks the sorted column
+	SortDescending string
+	// the filled and empty parts of the layer size bar
+	BarFull  string
+	BarEmpty string
+
+	// key names shown in the status bar and the keybinding help
+	ArrowUp    string
+	ArrowDown  string
+	ArrowLeft  string
+	ArrowRight string
+	Enter      string
+}
+
+// GlyphSets 是可用的字形集（配置项 theme.glyphs）。
+var GlyphSets = map[string]GlyphSet{
+	"unicode": {
+		TreeSpace:      "    ",
+		TreeBranch:     "│   ",
+		TreeMiddle:     "├─",
+		TreeLast:       "└─",
+		TreeExpanded:   "─ ",
+		TreeCollapsed:  "⊕ ",
+		Link:           " → ",
+		Range:          "→",
+		Bullet:         "●",
+		Separator:      "▏",
+		Rule:           "─",
+		SortDescending: "▼",
+		BarFull:        "█",
+		BarEmpty:       "░",
+		ArrowUp:        "↑",
+		ArrowDown:      "↓",
+		ArrowLeft:      "←",
+		ArrowRight:     "→",
+		Enter:          "⏎",
+	},
+	// for terminals and log captures that mangle box-drawing characters
+	"ascii": {
+		TreeSpace:      "    ",
+		TreeBranch:     "|   ",
+		TreeMiddle:     "|-",
+		TreeLast:       "`-",
+		TreeExpanded:   "- ",
+		TreeCollapsed:  "+ ",
+		Link:           " -> ",
+		Range:          "->",
+		Bullet:         "*",
+		Separator:      "|",
+		Rule:           "-",
+		SortDescending: "v",
+		BarFull:        "#",
+		BarEmpty:       ".",
+		ArrowUp:        "Up",
+		ArrowDown:      "Down",
+		ArrowLeft:      "Left",
+		ArrowRight:     "Right",
+		Enter:          "Enter",
+	},
+}
+
+// Glyphs 是当前使用的字形集，由Apply设置。
+var Glyphs = GlyphSets["unicode"]
+
+// LookupGlyphs 返回给定名称的字形集。
+func LookupGlyphs(name string) (GlyphSet, error) {
+	glyphs, exists := GlyphSets[name]
+	if !exists {
+		return GlyphSet{}, fmt.Errorf("unknown glyph set '%s' (available: %v)", name, GlyphSetNames())
+	}
+	return glyphs, nil
+}
+
+// GlyphSetNames 按字母顺序返回所有字形集的名称。
+func GlyphSetNames() []string {
+	names := make([]string, 0, len(GlyphSets))
+	for name := range GlyphSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
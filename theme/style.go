@@ -0,0 +1,67 @@
+package theme
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// colorNames 是样式中可以使用的颜色（TUI通过gocui只能显示8种基本颜色），值为相对于黑色的偏移
+var colorNames = map[string]color.Attribute{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+}
+
+// attributeNames 是样式中可以使用的文字属性
+var attributeNames = map[string]color.Attribute{
+	"default":   color.Reset,
+	"bold":      color.Bold,
+	"underline": color.Underline,
+	"reverse":   color.ReverseVideo,
+}
+
+// ParseStyle 解析样式定义（例如 "fg:black bg:yellow bold"），返回对应的终端属性。
+// 样式由空格分隔的颜色和属性组成：颜色名称或 fg:<颜色> 设置前景色，bg:<颜色> 设置背景色；属性可以是 bold、underline、reverse 或 default（不设置任何属性）。
+func ParseStyle(spec string) ([]color.Attribute, error) {
+	var attributes []color.Attribute
+	for _, token := range strings.Fields(strings.ToLower(spec)) {
+		if attribute, exists := attributeNames[token]; exists {
+			attributes = append(attributes, attribute)
+			continue
+		}
+
+		base, name := color.FgBlack, token
+		if strings.HasPrefix(token, "fg:") {
+			name = strings.TrimPrefix(token, "fg:")
+		} else if strings.HasPrefix(token, "bg:") {
+			base, name = color.BgBlack, strings.TrimPrefix(token, "bg:")
+		}
+		offset, exists := colorNames[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown color or attribute '%s' in style '%s'", token, spec)
+		}
+		attributes = append(attributes, base+offset)
+	}
+	return attributes, nil
+}
+
+// isColor 确定属性是否为前景色或背景色
+func isColor(attribute color.Attribute) bool {
+	return (attribute >= color.FgBlack && attribute <= color.FgWhite) || (attribute >= color.BgBlack && attribute <= color.BgWhite)
+}
+
+// ColorNames 按ANSI顺序返回样式中可以使用的颜色名称。
+func ColorNames() []string {
+	names := make([]string, len(colorNames))
+	for name, offset := range colorNames {
+		names[offset] = name
+	}
+	return names
+}
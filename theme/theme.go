@@ -0,0 +1,255 @@
+package theme
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// 可以设置样式的界面元素
+const (
+	Selected              = "selected"
+	Header                = "header"
+	StatusSelected        = "status-selected"
+	StatusNormal          = "status-normal"
+	StatusControlSelected = "status-control-selected"
+	StatusControlNormal   = "status-control-normal"
+	StatusError           = "status-error"
+	CompareTop            = "compare-top"
+	CompareBottom         = "compare-bottom"
+	Added                 = "added"
+	Removed               = "removed"
+	Changed               = "changed"
+	Unchanged             = "unchanged"
+	SearchMatch           = "search-match"
+	MarkedForRemoval      = "marked-for-removal"
+	// 详细信息窗格中图层命令的语法高亮
+	SyntaxKeyword  = "syntax-keyword"
+	SyntaxCommand  = "syntax-command"
+	SyntaxOption   = "syntax-option"
+	SyntaxString   = "syntax-string"
+	SyntaxVariable = "syntax-variable"
+	SyntaxOperator = "syntax-operator"
+	SyntaxComment  = "syntax-comment"
+)
+
+// Elements 按显示顺序列出所有界面元素
+var Elements = []string{
+	Selected, Header,
+	StatusSelected, StatusNormal, StatusControlSelected, StatusControlNormal, StatusError,
+	CompareTop, CompareBottom,
+	Added, Removed, Changed, Unchanged, SearchMatch, MarkedForRemoval,
+	SyntaxKeyword, SyntaxCommand, SyntaxOption, SyntaxString, SyntaxVariable, SyntaxOperator, SyntaxComment,
+}
+
+// Themes 是内置的主题（配置项 theme.name）。配置文件中的主题（themes.<名称>.<元素>）未设置的元素使用default主题的样式。
+var Themes = map[string]map[string]string{
+	"default": {
+		Selected:              "reverse bold",
+		Header:                "bold",
+		StatusSelected:        "fg:white bg:magenta",
+		StatusNormal:          "reverse",
+		StatusControlSelected: "fg:white bg:magenta bold",
+		StatusControlNormal:   "reverse bold",
+		StatusError:           "fg:white bg:red bold",
+		CompareTop:            "bg:magenta",
+		CompareBottom:         "bg:green",
+		Added:                 "green",
+		Removed:               "red",
+		Changed:               "yellow",
+		Unchanged:             "default",
+		SearchMatch:           "fg:black bg:yellow",
+		MarkedForRemoval:      "fg:white bg:red",
+		SyntaxKeyword:         "magenta bold",
+		SyntaxCommand:         "bold",
+		SyntaxOption:          "cyan",
+		SyntaxString:          "yellow",
+		SyntaxVariable:        "green",
+		SyntaxOperator:        "magenta",
+		SyntaxComment:         "blue",
+	},
+	// for terminals with a light background, where yellow text is hard to read
+	"light": {
+		Selected:              "reverse bold",
+		Header:                "bold",
+		StatusSelected:        "fg:white bg:blue",
+		StatusNormal:          "reverse",
+		StatusControlSelected: "fg:white bg:blue bold",
+		StatusControlNormal:   "reverse bold",
+		StatusError:           "fg:white bg:red bold",
+		CompareTop:            "bg:blue",
+		CompareBottom:         "bg:green",
+		Added:                 "green",
+		Removed:               "red",
+		Changed:               "magenta",
+		Unchanged:             "default",
+		SearchMatch:           "fg:white bg:blue",
+		MarkedForRemoval:      "fg:white bg:red",
+		SyntaxKeyword:         "blue bold",
+		SyntaxCommand:         "bold",
+		SyntaxOption:          "cyan",
+		SyntaxString:          "magenta",
+		SyntaxVariable:        "green",
+		SyntaxOperator:        "red",
+		SyntaxComment:         "blue",
+	},
+	// attributes only, also used for the elements that would be left without any style when NO_COLOR is set
+	"monochrome": {
+		Selected:              "reverse bold",
+		Header:                "bold",
+		StatusSelected:        "bold",
+		StatusNormal:          "reverse",
+		StatusControlSelected: "bold underline",
+		StatusControlNormal:   "reverse bold",
+		StatusError:           "reverse bold underline",
+		CompareTop:            "reverse",
+		CompareBottom:         "underline",
+		Added:                 "bold",
+		Removed:               "underline",
+		Changed:               "bold underline",
+		Unchanged:             "default",
+		SearchMatch:           "reverse",
+		MarkedForRemoval:      "reverse underline",
+		SyntaxKeyword:         "bold underline",
+		SyntaxCommand:         "bold",
+		SyntaxOption:          "default",
+		SyntaxString:          "underline",
+		SyntaxVariable:        "underline",
+		SyntaxOperator:        "bold",
+		SyntaxComment:         "default",
+	},
+}
+
+// Theme 保存每个界面元素的终端样式。
+type Theme struct {
+	Name   string
+	styles map[string]*color.Color
+}
+
+// Current 是当前使用的主题，由Apply设置。
+var Current = mustNew("default")
+
+// New 创建给定名称的主题。custom 是配置文件中定义的主题（优先于同名的内置主题）；noColor 为true时去掉所有颜色，只保留文字属性。
+func New(name string, custom map[string]map[string]string, noColor bool) (*Theme, error) {
+	definition, exists := custom[name]
+	if !exists {
+		definition, exists = Themes[name]
+	}
+	if !exists {
+		return nil, fmt.Errorf("unknown theme '%s' (available: %v)", name, Names(custom))
+	}
+	for element := range definition {
+		if !IsElement(element) {
+			return nil, fmt.Errorf("theme '%s': unknown element '%s' (available: %v)", name, element, Elements)
+		}
+	}
+
+	theme := &Theme{Name: name, styles: make(map[string]*color.Color)}
+	for _, element := range Elements {
+		spec, exists := definition[element]
+		if !exists {
+			spec = Themes["default"][element]
+		}
+		attributes, err := ParseStyle(spec)
+		if err != nil {
+			return nil, fmt.Errorf("theme '%s', element '%s': %v", name, element, err)
+		}
+
+		if noColor {
+			attributes = withoutColors(attributes)
+			if len(attributes) == 0 {
+				attributes, _ = ParseStyle(Themes["monochrome"][element])
+			}
+		}
+
+		style := color.New(attributes...)
+		if noColor {
+			// fatih/color disables every attribute when NO_COLOR is set, but the remaining ones carry the meaning (e.g. the selected row)
+			style.EnableColor()
+		}
+		theme.styles[element] = style
+	}
+	return theme, nil
+}
+
+func mustNew(name string) *Theme {
+	theme, err := New(name, nil, false)
+	if err != nil {
+		panic(err)
+	}
+	return theme
+}
+
+// withoutColors 返回去掉前景色和背景色之后的属性
+func withoutColors(attributes []color.Attribute) []color.Attribute {
+	var result []color.Attribute
+	for _, attribute := range attributes {
+		if !isColor(attribute) {
+			result = append(result, attribute)
+		}
+	}
+	return result
+}
+
+// Color 返回界面元素的样式。
+func (theme *Theme) Color(element string) *color.Color {
+	return theme.styles[element]
+}
+
+// SprintFunc 返回以界面元素的样式格式化字符串的函数。
+func (theme *Theme) SprintFunc(element string) func(...interface{}) string {
+	return theme.styles[element].SprintFunc()
+}
+
+// IsElement 确定给定名称是否为可以设置样式的界面元素。
+func IsElement(element string) bool {
+	for _, name := range Elements {
+		if name == element {
+			return true
+		}
+	}
+	return false
+}
+
+// Names 按字母顺序返回所有可用主题（内置主题和配置文件中的主题）的名称。
+func Names(custom map[string]map[string]string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, themes := range []map[string]map[string]string{Themes, custom} {
+		for name := range themes {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NoColorRequested 确定是否设置了NO_COLOR环境变量（见 https://no-color.org）。与fatih/color一致，空值也算作已设置。
+func NoColorRequested() bool {
+	_, exists := os.LookupEnv("NO_COLOR")
+	return exists
+}
+
+// Apply 设置当前使用的主题和字形集。主题无效时使用default主题，字形集无效时保持原来的字形集，并返回错误。
+func Apply(name string, glyphs string, custom map[string]map[string]string) error {
+	glyphSet, glyphErr := LookupGlyphs(glyphs)
+	if glyphErr == nil {
+		Glyphs = glyphSet
+	}
+
+	theme, err := New(name, custom, NoColorRequested())
+	if err != nil {
+		theme, _ = New("default", nil, NoColorRequested())
+	}
+	Current = theme
+
+	if err != nil {
+		return err
+	}
+	return glyphErr
+}
@@ -0,0 +1,254 @@
+package ui
+
+import (
+	"LGM/image"
+	"LGM/keybinding"
+	"LGM/theme"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/lunixbochs/vtclean"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"sort"
+	"strings"
+)
+
+// ConfigController 包含用于显示镜像运行配置的窗格（左列底部）：环境变量、入口点、用户、端口、标签、卷、健康检查以及OCI注解。
+type ConfigController struct {
+	Name   string
+	gui    *gocui.Gui
+	view   *gocui.View
+	header *gocui.View
+	config image.ImageConfig
+	lines  []string
+
+	keybindingPageDown []keybinding.Key
+	keybindingPageUp   []keybinding.Key
+}
+
+// NewConfigController 创建附加到全局[gocui]屏幕对象的新视图对象。
+func NewConfigController(name string, gui *gocui.Gui, config image.ImageConfig) (controller *ConfigController) {
+	controller = new(ConfigController)
+
+	// populate main fields
+	controller.Name = name
+	controller.gui = gui
+	controller.config = config
+
+	var err error
+	controller.keybindingPageUp, err = keybinding.ParseAll(viper.GetString("keybinding.page-up"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingPageDown, err = keybinding.ParseAll(viper.GetString("keybinding.page-down"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	return controller
+}
+
+// Setup 在全局[gocui]视图对象的上下文中初始化UI关注点。
+func (controller *ConfigController) Setup(v *gocui.View, header *gocui.View) error {
+
+	// set controller options
+	controller.view = v
+	controller.view.Editable = false
+	controller.view.Wrap = false
+	controller.view.Frame = false
+
+	controller.header = header
+	controller.header.Editable = false
+	controller.header.Wrap = false
+	controller.header.Frame = false
+
+	// set keybindings
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.down, controller.CursorDown); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.up, controller.CursorUp); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.top, func() error { return controller.scrollTo(0) }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.bottom, func() error { return controller.scrollTo(len(controller.lines)) }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingPageUp, func() error { return controller.scroll(-controller.pageHeight()) }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingPageDown, func() error { return controller.scroll(controller.pageHeight()) }); err != nil {
+		return err
+	}
+
+	controller.Update()
+	return controller.Render()
+}
+
+// IsVisible 指示配置窗格当前是否已初始化。
+func (controller *ConfigController) IsVisible() bool {
+	if controller == nil {
+		return false
+	}
+	return true
+}
+
+// rows 返回窗格在给定的可用高度（与详细信息窗格共用的行数）下占用的行数（包括标题）：显示全部内容所需的行数，最多为可用高度的三分之一（最大化时显示全部内容）。
+func (controller *ConfigController) rows(available, headerRows int) int {
+	rows := len(controller.lines) + headerRows + 1
+	if rows > available/3 {
+		rows = available / 3
+	}
+	// the header and the row hidden under the status bar (see layout)
+	if rows < headerRows+1 {
+		rows = headerRows + 1
+	}
+	return rows
+}
+
+// pageHeight 返回窗格一页的行数。
+func (controller *ConfigController) pageHeight() int {
+	_, height := controller.view.Size()
+	return height
+}
+
+// CursorDown 向下滚动配置窗格。
+func (controller *ConfigController) CursorDown() error {
+	return controller.scroll(1)
+}
+
+// CursorUp 向上滚动配置窗格。
+func (controller *ConfigController) CursorUp() error {
+	return controller.scroll(-1)
+}
+
+// scroll 将窗格滚动给定的行数（为负时向上）。
+func (controller *ConfigController) scroll(step int) error {
+	_, oy := controller.view.Origin()
+	return controller.scrollTo(oy + step)
+}
+
+// scrollTo 将给定的行滚动到窗格顶部（最后一页的内容占满窗格）。
+func (controller *ConfigController) scrollTo(row int) error {
+	ox, _ := controller.view.Origin()
+	if last := len(controller.lines) - controller.pageHeight(); row > last {
+		row = last
+	}
+	if row < 0 {
+		row = 0
+	}
+	return controller.view.SetOrigin(ox, row)
+}
+
+// Update 根据镜像配置生成窗格的内容。
+func (controller *ConfigController) Update() error {
+	config := controller.config
+	controller.lines = controller.lines[:0]
+
+	field := func(label, value string) {
+		if value != "" {
+			controller.lines = append(controller.lines, Formatting.Header(tr(label))+value)
+		}
+	}
+	list := func(label string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		controller.lines = append(controller.lines, Formatting.Header(tr(label)))
+		for _, value := range values {
+			controller.lines = append(controller.lines, "  "+value)
+		}
+	}
+
+	field("User: ", config.User)
+	field("Working dir: ", config.WorkingDir)
+	field("Entrypoint: ", execForm(config.Entrypoint))
+	field("Cmd: ", execForm(config.Cmd))
+	field("Stop signal: ", config.StopSignal)
+	field("Exposed ports: ", strings.Join(config.SortedExposedPorts(), ", "))
+	field("Volumes: ", strings.Join(config.SortedVolumes(), ", "))
+	if health := config.Healthcheck; health != nil && len(health.Test) > 0 {
+		field("Healthcheck: ", strings.Join(health.Test, " "))
+		var options []string
+		if health.Interval > 0 {
+			options = append(options, tr("interval %s", health.Interval))
+		}
+		if health.Timeout > 0 {
+			options = append(options, tr("timeout %s", health.Timeout))
+		}
+		if health.StartPeriod > 0 {
+			options = append(options, tr("start period %s", health.StartPeriod))
+		}
+		if health.Retries > 0 {
+			options = append(options, tr("retries %d", health.Retries))
+		}
+		if len(options) > 0 {
+			controller.lines = append(controller.lines, "  "+strings.Join(options, ", "))
+		}
+	}
+	list("Env:", config.Env)
+	list("Labels:", keyValues(config.Labels))
+	list("Annotations:", keyValues(config.Annotations))
+
+	if len(controller.lines) == 0 {
+		controller.lines = append(controller.lines, tr("(no runtime configuration)"))
+	}
+	return nil
+}
+
+// Render 将状态对象刷新到屏幕。
+func (controller *ConfigController) Render() error {
+	title := tr("Image Config")
+
+	controller.gui.Update(func(g *gocui.Gui) error {
+		// the focus may have changed since Render was called (updates are not run in order), so it is checked here
+		if g.CurrentView() == controller.view {
+			title = theme.Glyphs.Bullet + " " + title
+		}
+		// update header
+		controller.header.Clear()
+		width, _ := controller.view.Size()
+		headerStr := fmt.Sprintf("[%s]%s", title, strings.Repeat(theme.Glyphs.Rule, width*2))
+		fmt.Fprintln(controller.header, Formatting.Header(vtclean.Clean(headerStr, false)))
+
+		// update contents
+		controller.view.Clear()
+		fmt.Fprint(controller.view, strings.Join(controller.lines, "\n"))
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp 指示选择当前窗格时用户可以采取的所有可能操作。
+func (controller *ConfigController) KeyHelp() string {
+	return renderStatusOption(theme.Glyphs.ArrowUp+"/"+theme.Glyphs.ArrowDown, tr("Scroll"), false)
+}
+
+// execForm 以JSON数组的形式返回exec形式的命令（与Dockerfile的写法相同），空命令返回空字符串。
+func execForm(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	var formatted bytes.Buffer
+	encoder := json.NewEncoder(&formatted)
+	// keep "&&" and redirections readable
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(args); err != nil {
+		return strings.Join(args, " ")
+	}
+	return strings.TrimSuffix(formatted.String(), "\n")
+}
+
+// keyValues 按键的字母顺序返回 "键=值" 形式的映射元素。
+func keyValues(values map[string]string) []string {
+	result := make([]string, 0, len(values))
+	for key, value := range values {
+		result = append(result, key+"="+value)
+	}
+	sort.Strings(result)
+	return result
+}
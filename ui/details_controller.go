@@ -1,13 +1,31 @@
 package ui
 
 import (
+	"LGM/advisor"
+	"LGM/dockerfile"
 	"LGM/filetree"
+	"LGM/image"
+	"LGM/keybinding"
+	"LGM/shell"
+	"LGM/theme"
+	"bytes"
 	"fmt"
 	"github.com/dustin/go-humanize"
 	"github.com/jroimartin/gocui"
 	"github.com/lunixbochs/vtclean"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
+)
+
+type InefficiencySortType int
+
+const (
+	SortBySize InefficiencySortType = iota
+	SortByCount
 )
 
 // DetailsController 保存用于填充左下窗格的UI对象和数据模型。 特别是显示图层详细信息和图像统计信息的窗格。
@@ -18,10 +36,26 @@ type DetailsController struct {
 	header         *gocui.View
 	efficiency     float64
 	inefficiencies filetree.EfficiencySlice
+	refTrees       []*filetree.FileTree
+	// suggestions 是修改Dockerfile的建议，显示涉及当前所选图层的建议（在后台计算，见advise）
+	suggestions []advisor.Suggestion
+
+	// 低效文件列表的显示状态：排序方式、排序后的列表、选中的行以及第一个可见行
+	SortMode         InefficiencySortType
+	sorted           filetree.EfficiencySlice
+	selectedIndex    int
+	reportLowerBound int
+	reportHeight     int
+	// reportRows 是每个可见的低效文件行在窗格中的起始行（长路径会换行），最后一项是列表结束的行
+	reportRows []int
+
+	keybindingSort     []keybinding.Key
+	keybindingPageDown []keybinding.Key
+	keybindingPageUp   []keybinding.Key
 }
 
 // NewDetailsController 创建附加到全局[gocui]屏幕对象的新视图对象。
-func NewDetailsController(name string, gui *gocui.Gui, efficiency float64, inefficiencies filetree.EfficiencySlice) (controller *DetailsController) {
+func NewDetailsController(name string, gui *gocui.Gui, efficiency float64, inefficiencies filetree.EfficiencySlice, refTrees []*filetree.FileTree) (controller *DetailsController) {
 	controller = new(DetailsController)
 
 	// populate main fields
@@ -29,6 +63,24 @@ func NewDetailsController(name string, gui *gocui.Gui, efficiency float64, ineff
 	controller.gui = gui
 	controller.efficiency = efficiency
 	controller.inefficiencies = inefficiencies
+	controller.refTrees = refTrees
+	controller.sortInefficiencies(SortBySize)
+
+	var err error
+	controller.keybindingSort, err = keybinding.ParseAll(viper.GetString("keybinding.sort-inefficiencies"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingPageUp, err = keybinding.ParseAll(viper.GetString("keybinding.page-up"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingPageDown, err = keybinding.ParseAll(viper.GetString("keybinding.page-down"))
+	if err != nil {
+		logrus.Error(err)
+	}
 
 	return controller
 }
@@ -49,10 +101,29 @@ func (controller *DetailsController) Setup(v *gocui.View, header *gocui.View) er
 	controller.header.Frame = false
 
 	// set keybindings
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowDown, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorDown() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.down, controller.CursorDown); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.up, controller.CursorUp); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.top, func() error { return controller.moveSelection(-len(controller.sorted)) }); err != nil {
 		return err
 	}
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowUp, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorUp() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.bottom, func() error { return controller.moveSelection(len(controller.sorted)) }); err != nil {
+		return err
+	}
+	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyEnter, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.showSelected() }); err != nil {
+		return err
+	}
+
+	if err := dispatcher.Bind(controller.Name, controller.keybindingPageUp, controller.PageUp); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingPageDown, controller.PageDown); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingSort, controller.toggleSort); err != nil {
 		return err
 	}
 
@@ -67,14 +138,212 @@ func (controller *DetailsController) IsVisible() bool {
 	return true
 }
 
-// CursorDown 在“详细信息”窗格中向下移动光标（当前指示为“无”）。
+// CursorDown 在低效文件列表中向下移动选择（根据需要滚动列表）。
 func (controller *DetailsController) CursorDown() error {
-	return CursorDown(controller.gui, controller.view)
+	return controller.moveSelection(1)
 }
 
-// CursorUp 在“详细信息”窗格中向上移动光标（当前指示为“无”）。
+// CursorUp 在低效文件列表中向上移动选择（根据需要滚动列表）。
 func (controller *DetailsController) CursorUp() error {
-	return CursorUp(controller.gui, controller.view)
+	return controller.moveSelection(-1)
+}
+
+// PageDown 将低效文件列表向下移动一页。
+func (controller *DetailsController) PageDown() error {
+	return controller.moveSelection(controller.reportHeight)
+}
+
+// PageUp 将低效文件列表向上移动一页。
+func (controller *DetailsController) PageUp() error {
+	return controller.moveSelection(-controller.reportHeight)
+}
+
+// moveSelection 将选中的行移动给定的步距，并保证选中的行在可见范围内。
+func (controller *DetailsController) moveSelection(step int) error {
+	if len(controller.sorted) == 0 {
+		return nil
+	}
+	controller.selectedIndex += step
+	if controller.selectedIndex < 0 {
+		controller.selectedIndex = 0
+	}
+	if controller.selectedIndex > len(controller.sorted)-1 {
+		controller.selectedIndex = len(controller.sorted) - 1
+	}
+	controller.scrollToSelection()
+	return controller.Render()
+}
+
+// scrollToSelection 调整第一个可见行，使选中的行位于列表的可见范围内。
+func (controller *DetailsController) scrollToSelection() {
+	height := controller.reportHeight
+	if height < 1 {
+		height = 1
+	}
+	if controller.selectedIndex < controller.reportLowerBound {
+		controller.reportLowerBound = controller.selectedIndex
+	}
+	if controller.selectedIndex >= controller.reportLowerBound+height {
+		controller.reportLowerBound = controller.selectedIndex - height + 1
+	}
+}
+
+// click 选中鼠标点击的低效文件（行相对于窗格顶部），双击时在文件树中显示该文件。
+func (controller *DetailsController) click(row int, double bool) error {
+	selected := -1
+	for idx := 0; idx+1 < len(controller.reportRows); idx++ {
+		if row >= controller.reportRows[idx] && row < controller.reportRows[idx+1] {
+			selected = controller.reportLowerBound + idx
+		}
+	}
+	if selected < 0 || selected >= len(controller.sorted) {
+		return nil
+	}
+	controller.selectedIndex = selected
+	if double {
+		return controller.showSelected()
+	}
+	return controller.Render()
+}
+
+// sortInefficiencies 按给定的方式（从大到小）对低效文件列表排序。
+func (controller *DetailsController) sortInefficiencies(sortMode InefficiencySortType) {
+	controller.SortMode = sortMode
+	controller.sorted = make(filetree.EfficiencySlice, len(controller.inefficiencies))
+	copy(controller.sorted, controller.inefficiencies)
+
+	sort.SliceStable(controller.sorted, func(i, j int) bool {
+		a, b := controller.sorted[i], controller.sorted[j]
+		if sortMode == SortByCount && len(a.Nodes) != len(b.Nodes) {
+			return len(a.Nodes) > len(b.Nodes)
+		}
+		return a.CumulativeSize > b.CumulativeSize
+	})
+}
+
+// toggleSort 在按大小和按数量排序之间切换低效文件列表。
+func (controller *DetailsController) toggleSort() error {
+	if controller.SortMode == SortBySize {
+		controller.sortInefficiencies(SortByCount)
+	} else {
+		controller.sortInefficiencies(SortBySize)
+	}
+	controller.selectedIndex = 0
+	controller.reportLowerBound = 0
+	// we need to render the changes to the status pane as well
+	Update()
+	Render()
+	return nil
+}
+
+// showSelected 将图层窗格移动到最后修改所选路径的图层，并在文件树中展开并选中该路径。
+func (controller *DetailsController) showSelected() error {
+	if len(controller.sorted) == 0 {
+		return nil
+	}
+	data := controller.sorted[controller.selectedIndex]
+
+	layerIdx := lastTouchedLayer(data, controller.refTrees)
+	if layerIdx >= 0 {
+		err := Controllers.Layer.selectLayer(layerIdx)
+		if err != nil {
+			logrus.Errorf("unable to select layer %d: %+v", layerIdx, err)
+		}
+	}
+
+	err := Controllers.Tree.selectPath(data.Path)
+	if err != nil {
+		logrus.Errorf("unable to select path %s: %+v", data.Path, err)
+		return nil
+	}
+
+	_, err = controller.gui.SetCurrentView(Controllers.Tree.Name)
+	Update()
+	Render()
+	return err
+}
+
+// lastTouchedLayer 返回最后添加、修改或删除给定路径的图层索引（找不到时返回-1）。
+func lastTouchedLayer(data *filetree.EfficiencyData, refTrees []*filetree.FileTree) int {
+	if len(data.Nodes) == 0 {
+		return -1
+	}
+	lastTree := data.Nodes[len(data.Nodes)-1].Tree
+	for idx, tree := range refTrees {
+		if tree == lastTree {
+			return idx
+		}
+	}
+	return -1
+}
+
+// wrappedLineCount 返回给定文本在给定宽度的视图中自动换行后占用的行数。
+func wrappedLineCount(text string, width int) int {
+	if width < 1 {
+		width = 1
+	}
+	var count int
+	for _, line := range strings.Split(text, "\n") {
+		length := utf8.RuneCountInString(vtclean.Clean(line, false))
+		// same as gocui: an empty line still takes up a row, and so does the (empty) rest of a line that fills the whole width
+		count += 1 + length/width
+	}
+	return count
+}
+
+// syntaxStyles 是命令中各类Token在主题中的样式（没有列出的类型不设置样式）
+var syntaxStyles = map[shell.TokenKind]string{
+	shell.Command:  theme.SyntaxCommand,
+	shell.Option:   theme.SyntaxOption,
+	shell.String:   theme.SyntaxString,
+	shell.Variable: theme.SyntaxVariable,
+	shell.Operator: theme.SyntaxOperator,
+	shell.Comment:  theme.SyntaxComment,
+}
+
+// highlightCommand 返回语法高亮的图层命令（见image.NormalizeCommand），由 "&&"、"||" 和 ";" 连接的命令分为多行。
+// 只有RUN、CMD和ENTRYPOINT的参数是shell命令，其他指令的第一个参数不作为命令名称高亮。
+func highlightCommand(command string) []string {
+	keyword, script := "", command
+	if pos := strings.IndexByte(command, ' '); pos > 0 && dockerfile.IsInstruction(command[:pos]) && command[:pos] == strings.ToUpper(command[:pos]) {
+		keyword, script = command[:pos], command[pos+1:]
+	}
+	isShell := keyword == "" || keyword == "RUN" || keyword == "CMD" || keyword == "ENTRYPOINT"
+
+	var lines []string
+	for idx, tokens := range shell.Split(script) {
+		var line strings.Builder
+		if idx == 0 && keyword != "" {
+			line.WriteString(theme.Current.Color(theme.SyntaxKeyword).Sprint(keyword) + " ")
+		}
+		for _, token := range tokens {
+			kind := token.Kind
+			if kind == shell.Command && !isShell {
+				kind = shell.Word
+			}
+			if style, ok := syntaxStyles[kind]; ok {
+				line.WriteString(theme.Current.Color(style).Sprint(token.Text))
+			} else {
+				line.WriteString(token.Text)
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	if len(lines) == 0 && keyword != "" {
+		lines = append(lines, theme.Current.Color(theme.SyntaxKeyword).Sprint(keyword))
+	}
+	return lines
+}
+
+// advise 在后台计算修改Dockerfile的建议（见advisor.Advise，只显示估计节省至少minSaving字节的建议），完成后刷新窗格。
+func (controller *DetailsController) advise(analysis *image.AnalysisResult, minSaving uint64) {
+	go func() {
+		suggestions := advisor.Advise(analysis, minSaving)
+		controller.gui.Update(func(g *gocui.Gui) error {
+			controller.suggestions = suggestions
+			return controller.Render()
+		})
+	}()
 }
 
 // Update 刷新状态对象以便将来进行渲染。
@@ -84,68 +353,144 @@ func (controller *DetailsController) Update() error {
 
 // Render 将状态对象刷新到屏幕。
 // 详细信息窗格报告：
-//	1.当前所选图层的命令字符串
+//	1.当前所选图层的命令字符串（以及对应的Dockerfile指令和修改建议）
 //	2.图像效率得分
 //	3.估计浪费的图像空间
-//	4.低效文件分配列表
+//	4.合并比较范围内的图层的预测（显示时）
+//	5.低效文件分配列表（可滚动、可选择）
 func (controller *DetailsController) Render() error {
 	currentLayer := Controllers.Layer.currentLayer()
 
 	var wastedSpace int64
-
-	template := "%5s  %12s  %-s\n"
-	inefficiencyReport := fmt.Sprintf(Formatting.Header(template), "Count", "Total Space", "Path")
-
-	height := 100
-	if controller.view != nil {
-		_, height = controller.view.Size()
-	}
-
-	for idx := 0; idx < len(controller.inefficiencies); idx++ {
-		data := controller.inefficiencies[len(controller.inefficiencies)-1-idx]
+	for _, data := range controller.inefficiencies {
 		wastedSpace += data.CumulativeSize
+	}
 
-		// todo: make this report scrollable
-		if idx < height {
-			inefficiencyReport += fmt.Sprintf(template, strconv.Itoa(len(data.Nodes)), humanize.Bytes(uint64(data.CumulativeSize)), data.Path)
-		}
+	template := "%5s  %12s  %-s"
+	countTitle, sizeTitle := tr("Count"), tr("Total Space")
+	if controller.SortMode == SortByCount {
+		countTitle += theme.Glyphs.SortDescending
+	} else {
+		sizeTitle += theme.Glyphs.SortDescending
 	}
 
-	imageSizeStr := fmt.Sprintf("%s %s", Formatting.Header("Total Image size:"), humanize.Bytes(Controllers.Layer.ImageSize))
-	effStr := fmt.Sprintf("%s %d %%", Formatting.Header("Image efficiency score:"), int(100.0*controller.efficiency))
-	wastedSpaceStr := fmt.Sprintf("%s %s", Formatting.Header("Potential wasted space:"), humanize.Bytes(uint64(wastedSpace)))
+	imageSizeStr := fmt.Sprintf("%s %s", Formatting.Header(tr("Total Image size:")), humanize.Bytes(Controllers.Layer.ImageSize))
+	effStr := fmt.Sprintf("%s %d %%", Formatting.Header(tr("Image efficiency score:")), int(100.0*controller.efficiency))
+	wastedSpaceStr := fmt.Sprintf("%s %s", Formatting.Header(tr("Potential wasted space:")), humanize.Bytes(uint64(wastedSpace)))
+
+	title := tr("Layer Details")
 
 	controller.gui.Update(func(g *gocui.Gui) error {
+		// the focus may have changed since Render was called (updates are not run in order), so it is checked here
+		if g.CurrentView() == controller.view {
+			title = theme.Glyphs.Bullet + " " + title
+		}
 		// update header
 		controller.header.Clear()
-		width, _ := controller.view.Size()
+		width, height := controller.view.Size()
 
-		layerHeaderStr := fmt.Sprintf("[Layer Details]%s", strings.Repeat("─", width-15))
-		imageHeaderStr := fmt.Sprintf("[Image Details]%s", strings.Repeat("─", width-15))
+		layerHeaderStr := fmt.Sprintf("[%s]%s", title, strings.Repeat(theme.Glyphs.Rule, width*2))
+		imageTitle := "[" + tr("Image Details") + "]"
+		imageHeaderStr := imageTitle + strings.Repeat(theme.Glyphs.Rule, width-utf8.RuneCountInString(imageTitle))
 
 		fmt.Fprintln(controller.header, Formatting.Header(vtclean.Clean(layerHeaderStr, false)))
 
 		// update contents
-		controller.view.Clear()
-		fmt.Fprintln(controller.view, Formatting.Header("Digest: ")+currentLayer.Id())
+		var details bytes.Buffer
+		fmt.Fprintln(&details, Formatting.Header(tr("Digest: "))+currentLayer.Id())
+		if created := currentLayer.Created(); !created.IsZero() {
+			fmt.Fprintln(&details, Formatting.Header(tr("Created: "))+created.Local().Format(image.CreatedFormat))
+		}
+		if author := currentLayer.Author(); author != "" {
+			fmt.Fprintln(&details, Formatting.Header(tr("Author: "))+author)
+		}
 		// TODO: add back in with controller model
 		// fmt.Fprintln(view.view, Formatting.Header("Tar ID: ")+currentLayer.TarId())
-		fmt.Fprintln(controller.view, Formatting.Header("Command:"))
-		fmt.Fprintln(controller.view, currentLayer.Command())
+		fmt.Fprintln(&details, Formatting.Header(tr("Command:")))
+		for _, line := range highlightCommand(currentLayer.Command()) {
+			fmt.Fprintln(&details, line)
+		}
+		if instructions := Controllers.Layer.analysis.LayerInstructions(currentLayer.Index()); instructions != nil {
+			// the Dockerfile instructions of the layer, including the ones without a layer of their own (e.g. ENV)
+			fmt.Fprintln(&details, Formatting.Header(tr("Dockerfile:"))+" "+Controllers.Layer.analysis.Dockerfile.Path)
+			for _, instruction := range instructions {
+				for offset, line := range strings.Split(instruction.Original, "\n") {
+					fmt.Fprintf(&details, "%4d  %s\n", instruction.Line+offset, line)
+				}
+			}
+		}
+		if suggestions := advisor.ForLayer(controller.suggestions, currentLayer.Index()); len(suggestions) > 0 {
+			fmt.Fprintln(&details, Formatting.Header(tr("Suggestions:")))
+			for _, suggestion := range suggestions {
+				fmt.Fprintln(&details, theme.Glyphs.Bullet+" "+suggestion.Message+" "+tr("(saves %s)", advisor.FormatSaving(suggestion)))
+				for _, line := range strings.Split(suggestion.Rewrite, "\n") {
+					fmt.Fprintln(&details, "    "+line)
+				}
+			}
+		}
+
+		fmt.Fprintln(&details, "\n"+Formatting.Header(vtclean.Clean(imageHeaderStr, false)))
+
+		fmt.Fprintln(&details, imageSizeStr)
+		fmt.Fprintln(&details, wastedSpaceStr)
+		fmt.Fprintln(&details, effStr)
+		if len(controller.suggestions) > 0 {
+			saving, _ := advisor.TotalSaving(controller.suggestions)
+			fmt.Fprintln(&details, Formatting.Header(tr("Dockerfile suggestions:"))+" "+tr("%d (estimated saving %s)", len(controller.suggestions), humanize.Bytes(saving)))
+		}
+		fmt.Fprintln(&details)
 
-		fmt.Fprintln(controller.view, "\n"+Formatting.Header(vtclean.Clean(imageHeaderStr, false)))
+		if squash := Controllers.Layer.squash; squash != nil {
+			// what merging the compared layers would change
+			squashTitle := "[" + tr("Squash %d %s %d (what-if)", squash.Layer.Lower, theme.Glyphs.Range, squash.Layer.Upper) + "]"
+			if ruleWidth := width - utf8.RuneCountInString(squashTitle); ruleWidth > 0 {
+				squashTitle += strings.Repeat(theme.Glyphs.Rule, ruleWidth)
+			}
+			arrow := " " + theme.Glyphs.Range + " "
+			fmt.Fprintln(&details, Formatting.Header(vtclean.Clean(squashTitle, false)))
+			fmt.Fprintln(&details, Formatting.Header(tr("Merged layer size:"))+" "+humanize.Bytes(squash.LayersSize)+arrow+humanize.Bytes(squash.Size))
+			fmt.Fprintln(&details, Formatting.Header(tr("Total Image size:"))+" "+humanize.Bytes(squash.ImageSize)+arrow+humanize.Bytes(squash.ProjectedImageSize))
+			fmt.Fprintln(&details, Formatting.Header(tr("Potential wasted space:"))+" "+humanize.Bytes(squash.WastedBytes)+arrow+humanize.Bytes(squash.ProjectedWastedBytes))
+			fmt.Fprintln(&details, Formatting.Header(tr("Image efficiency score:"))+fmt.Sprintf(" %d %%%s%d %%\n", int(100.0*squash.Efficiency), arrow, int(100.0*squash.ProjectedEfficiency)))
+		}
+
+		fmt.Fprintln(&details, Formatting.Header(fmt.Sprintf(template, countTitle, sizeTitle, tr("Path"))))
 
-		fmt.Fprintln(controller.view, imageSizeStr)
-		fmt.Fprintln(controller.view, wastedSpaceStr)
-		fmt.Fprintln(controller.view, effStr+"\n")
+		// the rest of the pane is used for the (scrollable) inefficiency report
+		detailsRows := wrappedLineCount(strings.TrimSuffix(details.String(), "\n"), width)
+		controller.reportHeight = height - detailsRows
+		if controller.reportHeight < 1 {
+			controller.reportHeight = 1
+		}
+		controller.scrollToSelection()
 
-		fmt.Fprintln(controller.view, inefficiencyReport)
+		controller.view.Clear()
+		fmt.Fprint(controller.view, details.String())
+		row := detailsRows
+		controller.reportRows = controller.reportRows[:0]
+		for idx := controller.reportLowerBound; idx < len(controller.sorted) && idx < controller.reportLowerBound+controller.reportHeight; idx++ {
+			data := controller.sorted[idx]
+			line := fmt.Sprintf(template, strconv.Itoa(len(data.Nodes)), humanize.Bytes(uint64(data.CumulativeSize)), data.Path)
+			controller.reportRows = append(controller.reportRows, row)
+			row += wrappedLineCount(line, width)
+			if idx == controller.selectedIndex && g.CurrentView() == controller.view {
+				fmt.Fprintln(controller.view, Formatting.Selected(line))
+			} else {
+				fmt.Fprintln(controller.view, line)
+			}
+		}
+		controller.reportRows = append(controller.reportRows, row)
 		return nil
 	})
 	return nil
 }
 
-// KeyHelp 表示用户在选择当前窗格时可以执行的所有操作（当前不执行任何操作）。
+// KeyHelp 表示用户在选择当前窗格时可以执行的所有操作。
 func (controller *DetailsController) KeyHelp() string {
-	return "TBD"
-}
\ No newline at end of file
+	sortTitle := tr("Sort by count")
+	if controller.SortMode == SortByCount {
+		sortTitle = tr("Sort by size")
+	}
+	return renderStatusOption(controller.keybindingSort[0].String(), sortTitle, false) +
+		renderStatusOption(theme.Glyphs.Enter, tr("Show in file tree"), false)
+}
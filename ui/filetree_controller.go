@@ -2,13 +2,16 @@ package ui
 
 import (
 	"LGM/filetree"
+	"LGM/i18n"
+	"LGM/image"
 	"LGM/keybinding"
+	"LGM/theme"
 	"fmt"
+	"github.com/dustin/go-humanize"
 	"github.com/jroimartin/gocui"
 	"github.com/lunixbochs/vtclean"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"regexp"
 	"strings"
 )
 
@@ -19,26 +22,38 @@ type FileTreeController struct {
 	view   *gocui.View
 	header *gocui.View
 	vm     *FileTreeViewModel
+	// files 读取图层中的文件内容，用于提取文件（解析时没有保存图层时为nil）
+	files image.LayerFiles
 
 	keybindingToggleCollapse    []keybinding.Key
 	keybindingToggleCollapseAll []keybinding.Key
+	keybindingCollapse          []keybinding.Key
+	keybindingExpand            []keybinding.Key
 	keybindingToggleAttributes  []keybinding.Key
 	keybindingToggleAdded       []keybinding.Key
 	keybindingToggleRemoved     []keybinding.Key
 	keybindingToggleModified    []keybinding.Key
 	keybindingToggleUnchanged   []keybinding.Key
+	keybindingSort              []keybinding.Key
+	keybindingSearch            []keybinding.Key
+	keybindingSearchNext        []keybinding.Key
+	keybindingSearchPrev        []keybinding.Key
+	keybindingViewFile          []keybinding.Key
+	keybindingExtractFile       []keybinding.Key
+	keybindingMarkRemoval       []keybinding.Key
 	keybindingPageDown          []keybinding.Key
 	keybindingPageUp            []keybinding.Key
 }
 
 // NewFileTreeController 创建一个附加全局[gocui]屏幕对象的新视图对象。
-func NewFileTreeController(name string, gui *gocui.Gui, tree *filetree.FileTree, refTrees []*filetree.FileTree, cache filetree.TreeCache) (controller *FileTreeController) {
+func NewFileTreeController(name string, gui *gocui.Gui, tree *filetree.FileTree, refTrees []*filetree.FileTree, cache *filetree.TreeCache, files image.LayerFiles) (controller *FileTreeController) {
 	controller = new(FileTreeController)
 
 	// populate main fields
 	controller.Name = name
 	controller.gui = gui
 	controller.vm = NewFileTreeViewModel(tree, refTrees, cache)
+	controller.files = files
 
 	var err error
 	controller.keybindingToggleCollapse, err = keybinding.ParseAll(viper.GetString("keybinding.toggle-collapse-dir"))
@@ -51,6 +66,16 @@ func NewFileTreeController(name string, gui *gocui.Gui, tree *filetree.FileTree,
 		logrus.Error(err)
 	}
 
+	controller.keybindingCollapse, err = keybinding.ParseAll(viper.GetString("keybinding.collapse-dir"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingExpand, err = keybinding.ParseAll(viper.GetString("keybinding.expand-dir"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
 	controller.keybindingToggleAttributes, err = keybinding.ParseAll(viper.GetString("keybinding.toggle-filetree-attributes"))
 	if err != nil {
 		logrus.Error(err)
@@ -76,6 +101,41 @@ func NewFileTreeController(name string, gui *gocui.Gui, tree *filetree.FileTree,
 		logrus.Error(err)
 	}
 
+	controller.keybindingSort, err = keybinding.ParseAll(viper.GetString("keybinding.sort-files"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingSearch, err = keybinding.ParseAll(viper.GetString("keybinding.search"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingSearchNext, err = keybinding.ParseAll(viper.GetString("keybinding.search-next"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingSearchPrev, err = keybinding.ParseAll(viper.GetString("keybinding.search-prev"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingViewFile, err = keybinding.ParseAll(viper.GetString("keybinding.view-file"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingExtractFile, err = keybinding.ParseAll(viper.GetString("keybinding.extract-file"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingMarkRemoval, err = keybinding.ParseAll(viper.GetString("keybinding.mark-removal"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
 	controller.keybindingPageUp, err = keybinding.ParseAll(viper.GetString("keybinding.page-up"))
 	if err != nil {
 		logrus.Error(err)
@@ -104,63 +164,79 @@ func (controller *FileTreeController) Setup(v *gocui.View, header *gocui.View) e
 	controller.header.Frame = false
 
 	// set keybindings
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowDown, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorDown() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.down, controller.CursorDown); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.up, controller.CursorUp); err != nil {
 		return err
 	}
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowUp, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorUp() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.left, controller.CursorLeft); err != nil {
 		return err
 	}
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowLeft, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorLeft() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.right, controller.CursorRight); err != nil {
 		return err
 	}
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowRight, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorRight() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.top, controller.CursorTop); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.bottom, controller.CursorBottom); err != nil {
 		return err
 	}
 
-	for _, key := range controller.keybindingPageUp {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.PageUp() }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingPageUp, controller.PageUp); err != nil {
+		return err
 	}
-	for _, key := range controller.keybindingPageDown {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.PageDown() }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingPageDown, controller.PageDown); err != nil {
+		return err
 	}
-	for _, key := range controller.keybindingToggleCollapse {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.toggleCollapse() }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingToggleCollapse, controller.toggleCollapse); err != nil {
+		return err
 	}
-	for _, key := range controller.keybindingToggleCollapseAll {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.toggleCollapseAll() }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingCollapse, func() error { return controller.setCollapse(true) }); err != nil {
+		return err
 	}
-	for _, key := range controller.keybindingToggleAttributes {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.toggleAttributes() }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingExpand, func() error { return controller.setCollapse(false) }); err != nil {
+		return err
 	}
-	for _, key := range controller.keybindingToggleAdded {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.toggleShowDiffType(filetree.Added) }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingToggleCollapseAll, controller.toggleCollapseAll); err != nil {
+		return err
 	}
-	for _, key := range controller.keybindingToggleRemoved {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.toggleShowDiffType(filetree.Removed) }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingToggleAttributes, controller.toggleAttributes); err != nil {
+		return err
 	}
-	for _, key := range controller.keybindingToggleModified {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.toggleShowDiffType(filetree.Changed) }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingToggleAdded, func() error { return controller.toggleShowDiffType(filetree.Added) }); err != nil {
+		return err
 	}
-	for _, key := range controller.keybindingToggleUnchanged {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.toggleShowDiffType(filetree.Unchanged) }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingToggleRemoved, func() error { return controller.toggleShowDiffType(filetree.Removed) }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingToggleModified, func() error { return controller.toggleShowDiffType(filetree.Changed) }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingToggleUnchanged, func() error { return controller.toggleShowDiffType(filetree.Unchanged) }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingSort, controller.cycleSortOrder); err != nil {
+		return err
+	}
+
+	if err := dispatcher.Bind(controller.Name, controller.keybindingSearch, func() error { return Controllers.Search.open() }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingSearchNext, func() error { return controller.searchNext(true) }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingSearchPrev, func() error { return controller.searchNext(false) }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingViewFile, controller.viewFile); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingExtractFile, controller.extractFile); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingMarkRemoval, controller.toggleMark); err != nil {
+		return err
 	}
 
 	_, height := controller.view.Size()
@@ -197,6 +273,11 @@ func (controller *FileTreeController) setTreeByLayer(bottomTreeStart, bottomTree
 	return controller.Render()
 }
 
+// prefetchTreeByLayer 在后台构建指示的图像层的比较树，以便之后的setTreeByLayer可以直接使用缓存。
+func (controller *FileTreeController) prefetchTreeByLayer(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) {
+	controller.vm.cache.Prefetch(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop)
+}
+
 // CursorDown 向下移动光标并呈现视图。
 // 注意：我们不能使用gocui缓冲区，因为任何状态更改都需要将整个树写入缓冲区。
 // 相反，我们保持树形字符串的上限和下限以进行渲染，并且仅将此范围刷新到视图缓冲区中。 当树大小很大时，这会快得多。
@@ -219,7 +300,7 @@ func (controller *FileTreeController) CursorUp() error {
 
 // CursorLeft 将光标向上移动，直到我们到达父节点或树的顶部
 func (controller *FileTreeController) CursorLeft() error {
-	err := controller.vm.CursorLeft(filterRegex())
+	err := controller.vm.CursorLeft()
 	if err != nil {
 		return err
 	}
@@ -229,7 +310,7 @@ func (controller *FileTreeController) CursorLeft() error {
 
 // CursorRight 如果需要，可以进入扩展目录的目录
 func (controller *FileTreeController) CursorRight() error {
-	err := controller.vm.CursorRight(filterRegex())
+	err := controller.vm.CursorRight()
 	if err != nil {
 		return err
 	}
@@ -237,6 +318,18 @@ func (controller *FileTreeController) CursorRight() error {
 	return controller.Render()
 }
 
+// CursorTop 将光标移动到文件树的第一行。
+func (controller *FileTreeController) CursorTop() error {
+	controller.vm.CursorTop()
+	return controller.Render()
+}
+
+// CursorBottom 将光标移动到文件树的最后一行。
+func (controller *FileTreeController) CursorBottom() error {
+	controller.vm.CursorBottom()
+	return controller.Render()
+}
+
 // PageDown 移动到下一页，将光标置于顶部
 func (controller *FileTreeController) PageDown() error {
 	err := controller.vm.PageDown()
@@ -257,12 +350,112 @@ func (controller *FileTreeController) PageUp() error {
 
 // getAbsPositionNode 确定所选屏幕光标在文件树中的位置，返回所选的FileNode。
 func (controller *FileTreeController) getAbsPositionNode() (node *filetree.FileNode) {
-	return controller.vm.getAbsPositionNode(filterRegex())
+	return controller.vm.getAbsPositionNode()
+}
+
+// selectPath 展开给定路径的父目录并将光标移动到该路径。
+func (controller *FileTreeController) selectPath(path string) error {
+	node, err := controller.vm.expandPath(path)
+	if err != nil {
+		return err
+	}
+	controller.Update()
+	err = controller.vm.selectNode(node)
+	if err != nil {
+		return err
+	}
+	return controller.Render()
+}
+
+// click 将光标移动到鼠标点击的行（相对于窗格顶部），双击目录时折叠/展开该目录，双击文件时在查看器中打开该文件。
+func (controller *FileTreeController) click(row int, double bool) error {
+	if !controller.vm.selectRow(row) {
+		return nil
+	}
+	if double {
+		if node := controller.getAbsPositionNode(); node != nil && !node.Data.FileInfo.IsDir {
+			return controller.viewFile()
+		}
+		return controller.toggleCollapse()
+	}
+	return controller.Render()
+}
+
+// viewFile 在查看器中打开选中的文件（目录不能打开），查看器显示与文件树相同的比较图层中的版本。
+func (controller *FileTreeController) viewFile() error {
+	node := controller.getAbsPositionNode()
+	if node == nil || node.Data.FileInfo.IsDir {
+		return nil
+	}
+	_, bottomTreeStop, topTreeStart, topTreeStop := Controllers.Layer.getCompareIndexes()
+	return Controllers.Viewer.open(node.Path(), bottomTreeStop, topTreeStart, topTreeStop)
+}
+
+// extractFile 将选中的文件或目录提取到配置的目录（extract.output-dir）中，提取的是文件树所示的比较图层（叠加到其最上层）中的版本，结果显示在状态栏中。
+func (controller *FileTreeController) extractFile() error {
+	node := controller.getAbsPositionNode()
+	if node == nil {
+		return nil
+	}
+	if controller.files == nil {
+		return Controllers.Status.showMessage(tr("File contents are not available (enable viewer.spool-layers or raise viewer.spool-limit to keep the image layers)"), true)
+	}
+	_, _, _, topTreeStop := Controllers.Layer.getCompareIndexes()
+	result, err := image.Extract(controller.files, controller.vm.RefTrees, topTreeStop, false, node.Path(), viper.GetString("extract.output-dir"))
+	if err != nil {
+		return Controllers.Status.showMessage(tr("cannot extract files: %v", err), true)
+	}
+	if len(result.Skipped) > 0 {
+		logrus.Infof("skipped while extracting %s: %v", node.Path(), result.Skipped)
+		return Controllers.Status.showMessage(tr("%d files (%s) extracted to %s, %d skipped", result.Files, humanize.Bytes(uint64(result.Bytes)), result.Target, len(result.Skipped)), false)
+	}
+	return Controllers.Status.showMessage(tr("%d files (%s) extracted to %s", result.Files, humanize.Bytes(uint64(result.Bytes)), result.Target), false)
+}
+
+// toggleMark 将选中的文件或目录标记为删除或取消标记，并保存到配置项 slim.marks-file 指定的文件（"LGM export --remove-marked"从中读取）。
+func (controller *FileTreeController) toggleMark() error {
+	node := controller.getAbsPositionNode()
+	if node == nil {
+		return nil
+	}
+	marked, err := controller.vm.marks.Toggle(node.Path())
+	if err != nil {
+		return Controllers.Status.showMessage(tr("cannot mark the file: %v", err), true)
+	}
+	file := viper.GetString("slim.marks-file")
+	if err := image.SaveRemovalRules(file, controller.vm.marks); err != nil {
+		return Controllers.Status.showMessage(tr("cannot save the marked files: %v", err), true)
+	}
+	if err := controller.Update(); err != nil {
+		return err
+	}
+	if err := controller.Render(); err != nil {
+		return err
+	}
+
+	switch {
+	case marked:
+		return Controllers.Status.showMessage(tr("Marked %s for removal (saved to %s)", node.Path(), file), false)
+	case controller.vm.marks.Matches(node.Path()):
+		return Controllers.Status.showMessage(tr("Unmarked %s, but a marked directory or pattern still removes it", node.Path()), false)
+	default:
+		return Controllers.Status.showMessage(tr("Unmarked %s", node.Path()), false)
+	}
 }
 
 // toggleCollapse 将折叠/展开选定的FileNode。
 func (controller *FileTreeController) toggleCollapse() error {
-	err := controller.vm.toggleCollapse(filterRegex())
+	err := controller.vm.toggleCollapse()
+	if err != nil {
+		return err
+	}
+	controller.Update()
+	return controller.Render()
+}
+
+// setCollapse 将折叠（collapsed为true）或展开选定的目录。
+func (controller *FileTreeController) setCollapse(collapsed bool) error {
+	err := controller.vm.setCollapse(collapsed)
 	if err != nil {
 		return err
 	}
@@ -301,28 +494,98 @@ func (controller *FileTreeController) toggleShowDiffType(diffType filetree.DiffT
 	return nil
 }
 
-// onLayoutChange UI框架调用onLayoutChange以通知视图模型新的屏幕尺寸
-func (controller *FileTreeController) onLayoutChange() error {
-	controller.Update()
-	return controller.Render()
+// cycleSortOrder 切换文件树的排序方式，并保持光标在原来选中的节点上。
+func (controller *FileTreeController) cycleSortOrder() error {
+	selected := controller.getAbsPositionNode()
+
+	err := controller.vm.cycleSortOrder()
+	if err != nil {
+		return err
+	}
+
+	// we need to render the changes to the status pane as well
+	Update()
+	if selected != nil {
+		err = controller.vm.selectNode(selected)
+		if err != nil {
+			logrus.Debug(err)
+		}
+	}
+	Render()
+	return nil
 }
 
-// filterRegex 将返回正则表达式对象以匹配用户的过滤器输入。
-func filterRegex() *regexp.Regexp {
-	if Controllers.Filter == nil || Controllers.Filter.view == nil {
+// searchNext 将光标移动到下一个（forward为false时为上一个）搜索结果，必要时展开其父目录。
+func (controller *FileTreeController) searchNext(forward bool) error {
+	if searchQuery() == nil {
 		return nil
 	}
-	filterString := strings.TrimSpace(Controllers.Filter.view.Buffer())
-	if len(filterString) == 0 {
+
+	var path string
+	if node := controller.getAbsPositionNode(); node != nil {
+		path = node.Path()
+	}
+	match := controller.vm.nextSearchMatch(path, forward, false)
+	if match == "" {
 		return nil
 	}
 
-	regex, err := regexp.Compile(filterString)
+	err := controller.selectPath(match)
 	if err != nil {
-		return nil
+		return err
+	}
+	// the match counter is shown in the status pane
+	return Controllers.Status.Render()
+}
+
+// searchFrom 将光标移动到给定路径处或其后的第一个搜索结果（用于增量搜索），没有搜索结果时光标回到给定路径。
+func (controller *FileTreeController) searchFrom(path string) error {
+	controller.Update()
+
+	var match string
+	if searchQuery() != nil {
+		match = controller.vm.nextSearchMatch(path, true, true)
+	}
+	if match == "" {
+		match = path
+	}
+	if match == "" {
+		return controller.Render()
 	}
 
-	return regex
+	err := controller.selectPath(match)
+	if err != nil {
+		// the path may no longer exist in the current tree
+		logrus.Debug(err)
+		return controller.Render()
+	}
+	return nil
+}
+
+// searchStatus 返回描述搜索结果数量以及光标所在结果序号的文字。
+func (controller *FileTreeController) searchStatus() string {
+	total := len(controller.vm.searchMatches)
+	if total == 0 {
+		return tr("No matches")
+	}
+	if idx := controller.vm.searchMatchIndex(); idx >= 0 {
+		return tr("Match %d of %d", idx+1, total)
+	}
+	return tr("%d matches", total)
+}
+
+// onLayoutChange UI框架调用onLayoutChange以通知视图模型新的屏幕尺寸
+func (controller *FileTreeController) onLayoutChange() error {
+	controller.Update()
+	return controller.Render()
+}
+
+// filterQuery 返回用户在过滤器窗格中输入的（最近一次有效的）查询，没有过滤条件时返回nil。
+func filterQuery() *filetree.Query {
+	if Controllers.Filter == nil {
+		return nil
+	}
+	return Controllers.Filter.query
 }
 
 // Update 刷新状态对象以便将来进行渲染。
@@ -336,28 +599,35 @@ func (controller *FileTreeController) Update() error {
 		width, height = controller.gui.Size()
 	}
 	// height should account for the header
-	return controller.vm.Update(filterRegex(), width, height-1)
+	return controller.vm.Update(filterQuery(), searchQuery(), width, height-1)
 }
 
 // Render 将状态对象（文件树）刷新到窗格。
 func (controller *FileTreeController) Render() error {
-	title := "Current Layer Contents"
-	if Controllers.Layer.CompareMode == CompareAll {
-		title = "Aggregated Layer Contents"
+	title := tr("Current Layer Contents")
+	switch Controllers.Layer.CompareMode {
+	case CompareAll:
+		title = tr("Aggregated Layer Contents")
+	case CompareRange:
+		lower, upper := Controllers.Layer.compareRange(Controllers.Layer.LayerIndex)
+		title = tr("Changes Between Layers %d and %d", lower, upper)
 	}
-
-	// indicate when selected
-	if controller.gui.CurrentView() == controller.view {
-		title = "● " + title
+	if controller.vm.SortOrder != filetree.SortByName {
+		title += tr(" (by %s)", i18n.T(controller.vm.SortOrder.String()))
 	}
 
 	controller.gui.Update(func(g *gocui.Gui) error {
+		// the focus may have changed since Render was called (updates are not run in order), so it is checked here
+		if g.CurrentView() == controller.view {
+			title = theme.Glyphs.Bullet + " " + title
+		}
 		// update the header
 		controller.header.Clear()
 		width, _ := g.Size()
-		headerStr := fmt.Sprintf("[%s]%s\n", title, strings.Repeat("─", width*2))
+		headerStr := fmt.Sprintf("[%s]%s\n", title, strings.Repeat(theme.Glyphs.Rule, width*2))
 		if controller.vm.ShowAttributes {
-			headerStr += fmt.Sprintf(filetree.AttributeFormat+" %s", "P", "ermission", "UID:GID", "Size", "Filetree")
+			// the permission column is 10 characters wide (the type flag and the permission bits)
+			headerStr += fmt.Sprintf(filetree.AttributeFormat+" %s", fmt.Sprintf("%-10s", tr("Permission")), "", tr("UID:GID"), tr("Size"), tr("Filetree"))
 		}
 
 		fmt.Fprintln(controller.header, Formatting.Header(vtclean.Clean(headerStr, false)))
@@ -374,11 +644,22 @@ func (controller *FileTreeController) Render() error {
 
 // KeyHelp 指示用户在选择当前窗格时可以执行的所有操作。
 func (controller *FileTreeController) KeyHelp() string {
-	return renderStatusOption(controller.keybindingToggleCollapse[0].String(), "Collapse dir", false) +
-		renderStatusOption(controller.keybindingToggleCollapseAll[0].String(), "Collapse all dir", false) +
-		renderStatusOption(controller.keybindingToggleAdded[0].String(), "Added", !controller.vm.HiddenDiffTypes[filetree.Added]) +
-		renderStatusOption(controller.keybindingToggleRemoved[0].String(), "Removed", !controller.vm.HiddenDiffTypes[filetree.Removed]) +
-		renderStatusOption(controller.keybindingToggleModified[0].String(), "Modified", !controller.vm.HiddenDiffTypes[filetree.Changed]) +
-		renderStatusOption(controller.keybindingToggleUnchanged[0].String(), "Unmodified", !controller.vm.HiddenDiffTypes[filetree.Unchanged]) +
-		renderStatusOption(controller.keybindingToggleAttributes[0].String(), "Attributes", controller.vm.ShowAttributes)
+	// the match counter comes first so that it is not cut off on narrow screens
+	if searchQuery() != nil {
+		control := controller.keybindingSearchNext[0].String() + "/" + controller.keybindingSearchPrev[0].String()
+		return renderStatusOption(control, controller.searchStatus(), true) + controller.keyHelp()
+	}
+	return controller.keyHelp() + renderStatusOption(controller.keybindingSearch[0].String(), tr("Search"), false)
+}
+
+// keyHelp 返回文件树窗格中除搜索以外的按键帮助。
+func (controller *FileTreeController) keyHelp() string {
+	return renderStatusOption(controller.keybindingToggleCollapse[0].String(), tr("Collapse dir"), false) +
+		renderStatusOption(controller.keybindingToggleCollapseAll[0].String(), tr("Collapse all dir"), false) +
+		renderStatusOption(controller.keybindingToggleAdded[0].String(), tr("Added"), !controller.vm.HiddenDiffTypes[filetree.Added]) +
+		renderStatusOption(controller.keybindingToggleRemoved[0].String(), tr("Removed"), !controller.vm.HiddenDiffTypes[filetree.Removed]) +
+		renderStatusOption(controller.keybindingToggleModified[0].String(), tr("Modified"), !controller.vm.HiddenDiffTypes[filetree.Changed]) +
+		renderStatusOption(controller.keybindingToggleUnchanged[0].String(), tr("Unmodified"), !controller.vm.HiddenDiffTypes[filetree.Unchanged]) +
+		renderStatusOption(controller.keybindingToggleAttributes[0].String(), tr("Attributes"), controller.vm.ShowAttributes) +
+		renderStatusOption(controller.keybindingSort[0].String(), tr("Sort: %s", i18n.T(controller.vm.SortOrder.String())), false)
 }
@@ -2,13 +2,14 @@ package ui
 
 import (
 	"LGM/filetree"
+	"LGM/image"
 	"LGM/utils"
 	"bytes"
 	"fmt"
 	"github.com/lunixbochs/vtclean"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"regexp"
+	"os"
 	"strings"
 )
 
@@ -17,11 +18,12 @@ type FileTreeViewModel struct {
 	ModelTree *filetree.FileTree
 	ViewTree  *filetree.FileTree
 	RefTrees  []*filetree.FileTree
-	cache     filetree.TreeCache
+	cache     *filetree.TreeCache
 
 	CollapseAll           bool
 	ShowAttributes        bool
 	HiddenDiffTypes       []bool
+	SortOrder             filetree.SortOrder
 	TreeIndex             int
 	bufferIndex           int
 	bufferIndexLowerBound int
@@ -29,19 +31,26 @@ type FileTreeViewModel struct {
 	refHeight int
 	refWidth  int
 
+	// searchMatches 是视图树中满足搜索条件的节点路径（按渲染顺序）
+	searchMatches []string
+	// marks 是标记为删除的文件（保存在配置项 slim.marks-file 指定的文件中）
+	marks *filetree.RemovalRules
+
 	mainBuf bytes.Buffer
 }
 
-func NewFileTreeViewModel(tree *filetree.FileTree, refTrees []*filetree.FileTree, cache filetree.TreeCache) (treeViewModel *FileTreeViewModel) {
+func NewFileTreeViewModel(tree *filetree.FileTree, refTrees []*filetree.FileTree, cache *filetree.TreeCache) (treeViewModel *FileTreeViewModel) {
 	treeViewModel = new(FileTreeViewModel)
 
 	// populate main fields
 	treeViewModel.ShowAttributes = viper.GetBool("filetree.show-attributes")
 	treeViewModel.CollapseAll = viper.GetBool("filetree.collapse-dir")
-	treeViewModel.ModelTree = tree
+	// 缓存中的树与图层树共享节点，视图模型的树是写时复制的副本，视图状态通过SetViewInfo修改
+	treeViewModel.ModelTree = tree.Fork()
 	treeViewModel.RefTrees = refTrees
 	treeViewModel.cache = cache
 	treeViewModel.HiddenDiffTypes = make([]bool, 4)
+	treeViewModel.marks = loadMarks()
 
 	hiddenTypes := viper.GetStringSlice("diff.hide")
 	for _, hType := range hiddenTypes {
@@ -59,9 +68,27 @@ func NewFileTreeViewModel(tree *filetree.FileTree, refTrees []*filetree.FileTree
 		}
 	}
 
+	sortOrder, err := filetree.ParseSortOrder(viper.GetString("filetree.sort-order"))
+	if err != nil {
+		utils.PrintAndExit(fmt.Sprintf("unknown filetree.sort-order value: %s", viper.GetString("filetree.sort-order")))
+	}
+	treeViewModel.SortOrder = sortOrder
+
 	return treeViewModel
 }
 
+// loadMarks 读取标记为删除的文件（配置项 slim.marks-file），文件不存在或无法读取时没有标记。
+func loadMarks() *filetree.RemovalRules {
+	marks, err := image.LoadRemovalRules(viper.GetString("slim.marks-file"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Errorf("unable to read the marked files: %+v", err)
+		}
+		marks, _ = filetree.NewRemovalRules(nil)
+	}
+	return marks
+}
+
 // Setup 在全局[gocui]视图对象的上下文中初始化UI关注点。
 func (vm *FileTreeViewModel) Setup(lowerBound, height int) {
 	vm.bufferIndexLowerBound = lowerBound
@@ -101,26 +128,24 @@ func (vm *FileTreeViewModel) setTreeByLayer(bottomTreeStart, bottomTreeStop, top
 	if topTreeStop > len(vm.RefTrees)-1 {
 		return fmt.Errorf("invalid layer index given: %d of %d", topTreeStop, len(vm.RefTrees)-1)
 	}
-	newTree := vm.cache.Get(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop)
+	newTree := vm.cache.Get(bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop).Fork()
 
 	// preserve vm state on copy
-	visitor := func(node *filetree.FileNode) error {
-		newNode, err := newTree.GetNode(node.Path())
-		if err == nil {
-			newNode.Data.ViewInfo = node.Data.ViewInfo
-		}
-		return nil
-	}
-	err := vm.ModelTree.VisitDepthChildFirst(visitor, nil)
-	if err != nil {
-		logrus.Errorf("unable to propagate layer tree: %+v", err)
-		return err
-	}
+	newTree.CopyViewInfo(vm.ModelTree)
 
 	vm.ModelTree = newTree
 	return nil
 }
 
+// setCollapsed 折叠或展开模型树中的给定目录（节点可能与缓存中的树共享，见FileTree.SetViewInfo）。
+func (vm *FileTreeViewModel) setCollapsed(node *filetree.FileNode, collapsed bool) {
+	info := node.Data.ViewInfo
+	info.Collapsed = collapsed
+	if err := vm.ModelTree.SetViewInfo(node, info); err != nil {
+		logrus.Errorf("unable to collapse %s: %+v", node.Path(), err)
+	}
+}
+
 // doCursorUp 在光标上执行内部视图的缓冲区调整。 注意：这与gocui缓冲区无关。
 func (vm *FileTreeViewModel) CursorUp() bool {
 	if vm.TreeIndex <= 0 {
@@ -153,12 +178,11 @@ func (vm *FileTreeViewModel) CursorDown() bool {
 }
 
 // CursorLeft 将光标向上移动，直到我们到达父节点或树的顶部
-func (vm *FileTreeViewModel) CursorLeft(filterRegex *regexp.Regexp) error {
+func (vm *FileTreeViewModel) CursorLeft() error {
 	var visitor func(*filetree.FileNode) error
-	var evaluator func(*filetree.FileNode) bool
 	var dfsCounter, newIndex int
 	oldIndex := vm.TreeIndex
-	currentNode := vm.getAbsPositionNode(filterRegex)
+	currentNode := vm.getAbsPositionNode()
 
 	if currentNode == nil {
 		return nil
@@ -173,16 +197,7 @@ func (vm *FileTreeViewModel) CursorLeft(filterRegex *regexp.Regexp) error {
 		return nil
 	}
 
-	evaluator = func(curNode *filetree.FileNode) bool {
-		regexMatch := true
-		if filterRegex != nil {
-			match := filterRegex.Find([]byte(curNode.Path()))
-			regexMatch = match != nil
-		}
-		return !curNode.Parent.Data.ViewInfo.Collapsed && !curNode.Data.ViewInfo.Hidden && regexMatch
-	}
-
-	err := vm.ModelTree.VisitDepthParentFirst(visitor, evaluator)
+	err := vm.ViewTree.VisitVisible(visitor)
 	if err != nil {
 		logrus.Errorf("could not propagate tree on cursorLeft: %+v", err)
 		return err
@@ -204,8 +219,8 @@ func (vm *FileTreeViewModel) CursorLeft(filterRegex *regexp.Regexp) error {
 }
 
 // CursorRight 如果需要，可以进入扩展目录的目录
-func (vm *FileTreeViewModel) CursorRight(filterRegex *regexp.Regexp) error {
-	node := vm.getAbsPositionNode(filterRegex)
+func (vm *FileTreeViewModel) CursorRight() error {
+	node := vm.getAbsPositionNode()
 	if node == nil {
 		return nil
 	}
@@ -219,7 +234,7 @@ func (vm *FileTreeViewModel) CursorRight(filterRegex *regexp.Regexp) error {
 	}
 
 	if node.Data.ViewInfo.Collapsed {
-		node.Data.ViewInfo.Collapsed = false
+		vm.setCollapsed(node, false)
 	}
 
 	vm.TreeIndex++
@@ -235,6 +250,51 @@ func (vm *FileTreeViewModel) CursorRight(filterRegex *regexp.Regexp) error {
 	return nil
 }
 
+// CursorTop 将光标移动到树的第一行。
+func (vm *FileTreeViewModel) CursorTop() {
+	vm.resetCursor()
+}
+
+// CursorBottom 将光标移动到树的最后一个可见节点，并滚动视图使其位于最后一行。
+func (vm *FileTreeViewModel) CursorBottom() {
+	rows := vm.visibleRows()
+	if rows == 0 {
+		return
+	}
+
+	vm.TreeIndex = rows - 1
+	vm.bufferIndexLowerBound = vm.TreeIndex - vm.height()
+	if vm.bufferIndexLowerBound < 0 {
+		vm.bufferIndexLowerBound = 0
+	}
+	vm.bufferIndex = vm.TreeIndex - vm.bufferIndexLowerBound
+}
+
+// selectRow 将光标移动到视图中给定的行（相对于窗格顶部），该行没有节点时返回false。
+func (vm *FileTreeViewModel) selectRow(row int) bool {
+	index := vm.bufferIndexLowerBound + row
+	if row < 0 || row > vm.height() || index >= vm.visibleRows() {
+		return false
+	}
+	vm.TreeIndex = index
+	vm.bufferIndex = row
+	return true
+}
+
+// visibleRows 返回树中可见（未折叠且未隐藏）的行数。
+func (vm *FileTreeViewModel) visibleRows() int {
+	var rows int
+	err := vm.ViewTree.VisitVisible(func(*filetree.FileNode) error {
+		rows++
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("unable to count visible rows: %+v", err)
+		return 0
+	}
+	return rows
+}
+
 // PageDown 移动到下一页，将光标置于顶部
 func (vm *FileTreeViewModel) PageDown() error {
 	nextBufferIndexLowerBound := vm.bufferIndexLowerBound + vm.height()
@@ -286,42 +346,103 @@ func (vm *FileTreeViewModel) PageUp() error {
 	return nil
 }
 
-// getAbsPositionNode 确定所选屏幕光标在文件树中的位置，返回所选的FileNode。
-func (vm *FileTreeViewModel) getAbsPositionNode(filterRegex *regexp.Regexp) (node *filetree.FileNode) {
+// getAbsPositionNode 确定所选屏幕光标在文件树中的位置，返回所选的FileNode（模型树中的节点）。
+// 注意：光标位置对应的是视图树的渲染顺序（可能按大小等排序），因此需要遍历视图树。
+func (vm *FileTreeViewModel) getAbsPositionNode() (node *filetree.FileNode) {
 	var visitor func(*filetree.FileNode) error
-	var evaluator func(*filetree.FileNode) bool
 	var dfsCounter int
 
 	visitor = func(curNode *filetree.FileNode) error {
 		if dfsCounter == vm.TreeIndex {
-			node = curNode
+			modelNode, err := vm.ModelTree.GetNode(curNode.Path())
+			if err != nil {
+				return err
+			}
+			node = modelNode
 		}
 		dfsCounter++
 		return nil
 	}
 
-	evaluator = func(curNode *filetree.FileNode) bool {
-		regexMatch := true
-		if filterRegex != nil {
-			match := filterRegex.Find([]byte(curNode.Path()))
-			regexMatch = match != nil
+	err := vm.ViewTree.VisitVisible(visitor)
+	if err != nil {
+		logrus.Errorf("unable to get node position: %+v", err)
+	}
+
+	return node
+}
+
+// getNodeIndex 返回给定节点在可见树中的位置（即选中它时的TreeIndex），如果节点不可见则返回-1。
+func (vm *FileTreeViewModel) getNodeIndex(node *filetree.FileNode) int {
+	var dfsCounter int
+	index := -1
+	path := node.Path()
+
+	visitor := func(curNode *filetree.FileNode) error {
+		if curNode.Path() == path {
+			index = dfsCounter
 		}
-		return !curNode.Parent.Data.ViewInfo.Collapsed && !curNode.Data.ViewInfo.Hidden && regexMatch
+		dfsCounter++
+		return nil
 	}
 
-	err := vm.ModelTree.VisitDepthParentFirst(visitor, evaluator)
+	err := vm.ViewTree.VisitVisible(visitor)
 	if err != nil {
-		logrus.Errorf("unable to get node position: %+v", err)
+		logrus.Errorf("unable to get node index: %+v", err)
 	}
 
-	return node
+	return index
+}
+
+// expandPath 展开给定路径的所有父目录，使该路径的节点在树中可见。
+func (vm *FileTreeViewModel) expandPath(path string) (*filetree.FileNode, error) {
+	node, err := vm.ModelTree.GetNode(path)
+	if err != nil {
+		return nil, err
+	}
+	// the parents of a shared node belong to the tree it was shared from, only their paths are used
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		modelParent, err := vm.ModelTree.GetNode(parent.Path())
+		if err != nil {
+			return nil, err
+		}
+		vm.setCollapsed(modelParent, false)
+	}
+	return vm.ModelTree.GetNode(path)
+}
+
+// selectNode 将光标移动到给定节点，并在需要时滚动视图使其位于屏幕中间。 注意：视图模型必须在展开父目录之后更新过。
+func (vm *FileTreeViewModel) selectNode(node *filetree.FileNode) error {
+	index := vm.getNodeIndex(node)
+	if index < 0 {
+		return fmt.Errorf("node is not visible: %s", node.Path())
+	}
+
+	vm.TreeIndex = index
+	if index < vm.bufferIndexLowerBound || index > vm.bufferIndexUpperBound() {
+		vm.bufferIndexLowerBound = index - vm.height()/2
+		if vm.bufferIndexLowerBound < 0 {
+			vm.bufferIndexLowerBound = 0
+		}
+	}
+	vm.bufferIndex = index - vm.bufferIndexLowerBound
+	return nil
 }
 
 // toggleCollapse 将折叠/展开选定的FileNode。
-func (vm *FileTreeViewModel) toggleCollapse(filterRegex *regexp.Regexp) error {
-	node := vm.getAbsPositionNode(filterRegex)
+func (vm *FileTreeViewModel) toggleCollapse() error {
+	node := vm.getAbsPositionNode()
 	if node != nil && node.Data.FileInfo.IsDir {
-		node.Data.ViewInfo.Collapsed = !node.Data.ViewInfo.Collapsed
+		vm.setCollapsed(node, !node.Data.ViewInfo.Collapsed)
+	}
+	return nil
+}
+
+// setCollapse 将折叠或展开选定的目录。
+func (vm *FileTreeViewModel) setCollapse(collapsed bool) error {
+	node := vm.getAbsPositionNode()
+	if node != nil && node.Data.FileInfo.IsDir {
+		vm.setCollapsed(node, collapsed)
 	}
 	return nil
 }
@@ -331,7 +452,7 @@ func (vm *FileTreeViewModel) toggleCollapseAll() error {
 	vm.CollapseAll = !vm.CollapseAll
 
 	visitor := func(curNode *filetree.FileNode) error {
-		curNode.Data.ViewInfo.Collapsed = vm.CollapseAll
+		vm.setCollapsed(curNode, vm.CollapseAll)
 		return nil
 	}
 
@@ -353,6 +474,19 @@ func (vm *FileTreeViewModel) toggleAttributes() error {
 	return nil
 }
 
+// cycleSortOrder 依次切换文件树的排序方式（名称、累计大小、变更类型）。
+func (vm *FileTreeViewModel) cycleSortOrder() error {
+	switch vm.SortOrder {
+	case filetree.SortByName:
+		vm.SortOrder = filetree.SortBySize
+	case filetree.SortBySize:
+		vm.SortOrder = filetree.SortByDiffType
+	default:
+		vm.SortOrder = filetree.SortByName
+	}
+	return nil
+}
+
 // toggleShowDiffType 将在filetree窗格中显示/隐藏选定的DiffType。
 func (vm *FileTreeViewModel) toggleShowDiffType(diffType filetree.DiffType) error {
 	vm.HiddenDiffTypes[diffType] = !vm.HiddenDiffTypes[diffType]
@@ -360,27 +494,105 @@ func (vm *FileTreeViewModel) toggleShowDiffType(diffType filetree.DiffType) erro
 	return nil
 }
 
+// searchMatchIndex 返回光标所在节点在搜索结果中的序号，光标不在匹配项上时返回-1。
+func (vm *FileTreeViewModel) searchMatchIndex() int {
+	if len(vm.searchMatches) == 0 {
+		return -1
+	}
+	node := vm.getAbsPositionNode()
+	if node == nil {
+		return -1
+	}
+	path := node.Path()
+	for idx, match := range vm.searchMatches {
+		if match == path {
+			return idx
+		}
+	}
+	return -1
+}
+
+// nextSearchMatch 按渲染顺序返回给定路径之后（forward）或之前的第一个匹配项的路径，到达末尾时回绕。
+// 与光标位置不同，这里也会查找折叠目录中的节点。inclusive表示给定路径本身匹配时直接返回它。没有匹配项时返回空字符串。
+func (vm *FileTreeViewModel) nextSearchMatch(path string, forward, inclusive bool) string {
+	var first, last, before, after string
+	passed := false
+
+	visitor := func(node *filetree.FileNode) error {
+		curPath := node.Path()
+		if curPath == path {
+			passed = true
+			if inclusive && node.Data.ViewInfo.Matched {
+				after = curPath
+			}
+			return nil
+		}
+		if !node.Data.ViewInfo.Matched {
+			return nil
+		}
+		if first == "" {
+			first = curPath
+		}
+		last = curPath
+		if !passed {
+			before = curPath
+		} else if after == "" {
+			after = curPath
+		}
+		return nil
+	}
+
+	err := vm.ViewTree.VisitDepthParentFirst(visitor, nil)
+	if err != nil {
+		logrus.Errorf("unable to search tree: %+v", err)
+		return ""
+	}
+
+	if forward {
+		if after != "" {
+			return after
+		}
+		return first
+	}
+	if inclusive && after == path {
+		return after
+	}
+	if before != "" {
+		return before
+	}
+	return last
+}
+
 // Update 刷新状态对象以供将来呈现。
-func (vm *FileTreeViewModel) Update(filterRegex *regexp.Regexp, width, height int) error {
+func (vm *FileTreeViewModel) Update(filter, search *filetree.Query, width, height int) error {
 	vm.refWidth = width
 	vm.refHeight = height
 
 	// keep the vm selection in parity with the current DiffType selection
-	err := vm.ModelTree.VisitDepthChildFirst(func(node *filetree.FileNode) error {
-		node.Data.ViewInfo.Hidden = vm.HiddenDiffTypes[node.Data.DiffType]
+	err := vm.ModelTree.VisitDepthChildFirst(func(visited *filetree.FileNode) error {
+		// the visited node is replaced by an owned copy when the view state of one of its children changes, the copy has the updated children
+		node, err := vm.ModelTree.GetNode(visited.Path())
+		if err != nil {
+			return err
+		}
+		info := node.Data.ViewInfo
+		info.Hidden = vm.HiddenDiffTypes[node.Data.DiffType]
 		visibleChild := false
 		for _, child := range node.Children {
 			if !child.Data.ViewInfo.Hidden {
 				visibleChild = true
-				node.Data.ViewInfo.Hidden = false
+				info.Hidden = false
 			}
 		}
-		// hide nodes that do not match the current file filter regex (also don't unhide nodes that are already hidden)
-		if filterRegex != nil && !visibleChild && !node.Data.ViewInfo.Hidden {
-			match := filterRegex.FindString(node.Path())
-			node.Data.ViewInfo.Hidden = len(match) == 0
+		// hide nodes that do not match the current file filter query (also don't unhide nodes that are already hidden)
+		if filter != nil && !visibleChild && !info.Hidden {
+			info.Hidden = !filter.Match(node)
 		}
-		return nil
+		// highlight the nodes that match the current search query (unlike the filter, nothing is hidden)
+		info.Matched = search != nil && search.Match(node)
+		info.Marked = vm.marks.Matches(node.Path())
+		// only the nodes whose view state changes are copied from the shared trees
+		return vm.ModelTree.SetViewInfo(node, info)
 	}, nil)
 
 	if err != nil {
@@ -388,8 +600,9 @@ func (vm *FileTreeViewModel) Update(filterRegex *regexp.Regexp, width, height in
 		return err
 	}
 
-	// make a new tree with only visible nodes
-	vm.ViewTree = vm.ModelTree.Copy()
+	// make a new tree with only visible nodes, only the paths to the removed nodes are copied
+	vm.ViewTree = vm.ModelTree.Fork()
+	vm.ViewTree.SortOrder = vm.SortOrder
 	err = vm.ViewTree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
 		if node.Data.ViewInfo.Hidden {
 			vm.ViewTree.RemovePath(node.Path())
@@ -402,6 +615,20 @@ func (vm *FileTreeViewModel) Update(filterRegex *regexp.Regexp, width, height in
 		return err
 	}
 
+	vm.searchMatches = vm.searchMatches[:0]
+	if search != nil {
+		err = vm.ViewTree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			if node.Data.ViewInfo.Matched {
+				vm.searchMatches = append(vm.searchMatches, node.Path())
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			logrus.Errorf("unable to propagate vm view tree: %+v", err)
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -1,11 +1,14 @@
 package ui
 
 import (
+	"LGM/filetree"
+	"LGM/theme"
 	"fmt"
 	"github.com/jroimartin/gocui"
+	"strings"
 )
 
-// FilterController 包含用于填充底行的UI对象和数据模型。 特别是允许用户按路径过滤文件树的窗格。
+// FilterController 包含用于填充底行的UI对象和数据模型。 特别是允许用户按查询条件（见filetree.Query）过滤文件树的窗格。
 type FilterController struct {
 	Name      string
 	gui       *gocui.Gui
@@ -14,6 +17,10 @@ type FilterController struct {
 	headerStr string
 	maxLength int
 	hidden    bool
+
+	// query 是最近一次有效的查询，err 是当前输入的解析错误（输入有效时为nil）
+	query *filetree.Query
+	err   error
 }
 
 // NewFilterController 创建一个附加全局[gocui]屏幕对象的新视图对象。
@@ -23,7 +30,7 @@ func NewFilterController(name string, gui *gocui.Gui) (controller *FilterControl
 	// populate main fields
 	controller.Name = name
 	controller.gui = gui
-	controller.headerStr = "Path Filter: "
+	controller.headerStr = tr("Filter: ")
 	controller.hidden = true
 
 	return controller
@@ -86,10 +93,21 @@ func (controller *FilterController) Edit(v *gocui.View, key gocui.Key, ch rune,
 	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
 		v.EditDelete(true)
 	}
+
+	// 无效的查询不会清除过滤条件，而是在状态栏中显示错误并继续使用上一次有效的查询
+	query, err := filetree.ParseQuery(strings.TrimSpace(v.Buffer()))
+	controller.err = err
+	if err == nil {
+		controller.query = query
+	}
+
 	if Controllers.Tree != nil {
 		Controllers.Tree.Update()
 		Controllers.Tree.Render()
 	}
+	if Controllers.Status != nil {
+		Controllers.Status.Render()
+	}
 }
 
 // Update 刷新状态对象以供将来渲染（当前不执行任何操作）。
@@ -110,5 +128,8 @@ func (controller *FilterController) Render() error {
 
 // KeyHelp 指示选择当前窗格时用户可以采取的所有可能操作。
 func (controller *FilterController) KeyHelp() string {
-	return Formatting.StatusControlNormal("▏Type to filter the file tree ")
+	if controller.err != nil {
+		return Formatting.StatusError(theme.Glyphs.Separator + controller.err.Error() + " ")
+	}
+	return Formatting.StatusControlNormal(theme.Glyphs.Separator + tr("Type to filter the file tree (e.g. size>10MB type:file path:/usr/lib/**)") + " ")
 }
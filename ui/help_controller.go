@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"LGM/keybinding"
+	"LGM/theme"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"strings"
+)
+
+// HelpController 包含用于显示按键帮助浮层的UI对象和数据模型。浮层按窗格列出所有生效的按键（见keybinding.Registry），包括用户在配置文件中覆盖的按键。
+type HelpController struct {
+	Name    string
+	gui     *gocui.Gui
+	view    *gocui.View
+	visible bool
+
+	// previous 是打开帮助之前选中的窗格，关闭帮助时返回该窗格
+	previous string
+	lines    []string
+}
+
+// NewHelpController 创建一个附加全局[gocui]屏幕对象的新视图对象。
+func NewHelpController(name string, gui *gocui.Gui) (controller *HelpController) {
+	controller = new(HelpController)
+
+	// populate main fields
+	controller.Name = name
+	controller.gui = gui
+
+	return controller
+}
+
+// Setup 在全局[gocui]视图对象的上下文中初始化UI关注点。浮层在每次打开时重新创建。
+func (controller *HelpController) Setup(v *gocui.View, header *gocui.View) error {
+
+	// set controller options
+	controller.view = v
+	controller.view.Editable = false
+	controller.view.Wrap = false
+	controller.view.Frame = true
+	controller.view.Title = tr("Keybindings")
+
+	controller.Update()
+	controller.Render()
+
+	return nil
+}
+
+// IsVisible 指示帮助浮层当前是否显示
+func (controller *HelpController) IsVisible() bool {
+	if controller == nil {
+		return false
+	}
+	return controller.visible
+}
+
+// size 返回浮层在给定屏幕尺寸下的宽度和高度（包括边框）。
+func (controller *HelpController) size(maxX, maxY int) (int, int) {
+	width, height := 90, len(controller.lines)+1
+	if width > maxX-4 {
+		width = maxX - 4
+	}
+	if height > maxY-4 {
+		height = maxY - 4
+	}
+	return width, height
+}
+
+// CursorDown 向下滚动帮助浮层。
+func (controller *HelpController) CursorDown() error {
+	ox, oy := controller.view.Origin()
+	_, height := controller.view.Size()
+	if oy+height >= len(controller.lines) {
+		return nil
+	}
+	return controller.view.SetOrigin(ox, oy+1)
+}
+
+// CursorUp 向上滚动帮助浮层。
+func (controller *HelpController) CursorUp() error {
+	ox, oy := controller.view.Origin()
+	if oy <= 0 {
+		return nil
+	}
+	return controller.view.SetOrigin(ox, oy-1)
+}
+
+// Update 根据按键注册表重新生成帮助内容。
+func (controller *HelpController) Update() error {
+	controller.lines = controller.lines[:0]
+	for _, view := range keybinding.Views() {
+		if len(controller.lines) > 0 {
+			controller.lines = append(controller.lines, "")
+		}
+		controller.lines = append(controller.lines, Formatting.Header(tr(view)))
+		for _, binding := range keybinding.Registry {
+			if binding.View != view {
+				continue
+			}
+			controller.lines = append(controller.lines, fmt.Sprintf("  %-18s %s", binding.Describe(), tr(binding.Help)))
+		}
+	}
+	return nil
+}
+
+// Render 将状态对象刷新到屏幕。
+func (controller *HelpController) Render() error {
+	if !controller.IsVisible() {
+		return nil
+	}
+	controller.gui.Update(func(g *gocui.Gui) error {
+		// the view is created by the next layout after the overlay is opened
+		if controller.view == nil {
+			return nil
+		}
+		controller.view.Clear()
+		fmt.Fprint(controller.view, strings.Join(controller.lines, "\n"))
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp 指示选择当前窗格时用户可以采取的所有可能操作。
+func (controller *HelpController) KeyHelp() string {
+	return renderStatusOption(theme.Glyphs.ArrowUp+"/"+theme.Glyphs.ArrowDown, tr("Scroll"), false) +
+		renderStatusOption(GlobalKeybindings.help[0].String(), tr("Close help"), false)
+}
+
+// toggle 显示/隐藏帮助浮层。浮层视图由layout创建，关闭时删除并返回之前选中的窗格。
+func (controller *HelpController) toggle(g *gocui.Gui, v *gocui.View) error {
+	if !controller.visible {
+		controller.previous = Controllers.Layer.Name
+		if v != nil {
+			controller.previous = v.Name()
+		}
+		controller.visible = true
+		return nil
+	}
+
+	controller.visible = false
+	if err := g.DeleteView(controller.Name); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	controller.view = nil
+	if _, err := g.SetCurrentView(controller.previous); err != nil {
+		return err
+	}
+	Update()
+	Render()
+	return nil
+}
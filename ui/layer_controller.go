@@ -4,11 +4,14 @@ import (
 	"LGM/image"
 	"LGM/keybinding"
 	"LGM/utils"
+	"LGM/theme"
 	"fmt"
+	"github.com/dustin/go-humanize"
 	"github.com/jroimartin/gocui"
 	"github.com/lunixbochs/vtclean"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"os"
 	"strings"
 )
 
@@ -17,6 +20,7 @@ type CompareType int
 const (
 	CompareLayer CompareType = iota
 	CompareAll
+	CompareRange
 )
 
 // LayerController 包含用于填充左下窗格的UI对象和数据模型。 特别是显示图像图层和图层选择器的窗格。
@@ -31,20 +35,38 @@ type LayerController struct {
 	CompareStartIndex int
 	ImageSize         uint64
 
+	// CompareRangeStart 和 CompareRangeStop 是CompareRange模式下用户标记的下界和上界（未标记时为-1）
+	CompareRangeStart   int
+	CompareRangeStop    int
+	previousCompareMode CompareType
+
+	// columns 是显示的列（配置项 layer.columns）
+	columns []image.LayerColumn
+
+	// analysis 用于预测合并图层的结果，squash 是当前显示在详细信息窗格中的预测（未预测时为nil）
+	analysis *image.AnalysisResult
+	squash   *image.SquashEstimate
+
 	keybindingCompareAll   []keybinding.Key
 	keybindingCompareLayer []keybinding.Key
+	keybindingCompareMark  []keybinding.Key
 	keybindingPageDown     []keybinding.Key
 	keybindingPageUp       []keybinding.Key
+	keybindingSquash       []keybinding.Key
+	keybindingSquashWrite  []keybinding.Key
 }
 
 // NewLayerController 创建一个附加全局[gocui]屏幕对象的新视图对象。
-func NewLayerController(name string, gui *gocui.Gui, layers []image.Layer) (controller *LayerController) {
+func NewLayerController(name string, gui *gocui.Gui, layers []image.Layer, analysis *image.AnalysisResult) (controller *LayerController) {
 	controller = new(LayerController)
 
 	// 填充主要字段
 	controller.Name = name
 	controller.gui = gui
 	controller.Layers = layers
+	controller.analysis = analysis
+	controller.CompareRangeStart = -1
+	controller.CompareRangeStop = -1
 
 	// 显示汇总的更改
 	switch mode := viper.GetBool("layer.show-aggregated-changes"); mode {
@@ -57,6 +79,15 @@ func NewLayerController(name string, gui *gocui.Gui, layers []image.Layer) (cont
 	}
 
 	var err error
+	controller.columns, err = image.ParseLayerColumns(viper.GetStringSlice("layer.columns"))
+	if err != nil {
+		utils.PrintAndExit(fmt.Sprintf("invalid layer.columns value: %v", err))
+	}
+	// the source line of each layer is shown whenever a Dockerfile is mapped to the history
+	if analysis.Dockerfile != nil {
+		controller.columns = image.WithLineColumn(controller.columns)
+	}
+
 	controller.keybindingCompareAll, err = keybinding.ParseAll(viper.GetString("keybinding.compare-all"))
 	if err != nil {
 		logrus.Error(err)
@@ -67,6 +98,11 @@ func NewLayerController(name string, gui *gocui.Gui, layers []image.Layer) (cont
 		logrus.Error(err)
 	}
 
+	controller.keybindingCompareMark, err = keybinding.ParseAll(viper.GetString("keybinding.compare-mark"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
 	controller.keybindingPageUp, err = keybinding.ParseAll(viper.GetString("keybinding.page-up"))
 	if err != nil {
 		logrus.Error(err)
@@ -77,6 +113,16 @@ func NewLayerController(name string, gui *gocui.Gui, layers []image.Layer) (cont
 		logrus.Error(err)
 	}
 
+	controller.keybindingSquash, err = keybinding.ParseAll(viper.GetString("keybinding.squash-preview"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingSquashWrite, err = keybinding.ParseAll(viper.GetString("keybinding.squash-write"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
 	return controller
 }
 
@@ -98,42 +144,53 @@ func (controller *LayerController) Setup(v *gocui.View, header *gocui.View) erro
 	controller.header.Frame = false
 
 	// set keybindings
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowDown, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorDown() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.down, controller.CursorDown); err != nil {
 		return err
 	}
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowUp, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorUp() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.up, controller.CursorUp); err != nil {
 		return err
 	}
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowRight, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorDown() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.right, controller.CursorDown); err != nil {
 		return err
 	}
-	if err := controller.gui.SetKeybinding(controller.Name, gocui.KeyArrowLeft, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.CursorUp() }); err != nil {
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.left, controller.CursorUp); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.top, func() error { return controller.selectLayer(0) }); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, CursorKeybindings.bottom, func() error { return controller.selectLayer(len(controller.Layers) - 1) }); err != nil {
 		return err
 	}
 
-	for _, key := range controller.keybindingPageUp {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.PageUp() }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingPageUp, controller.PageUp); err != nil {
+		return err
 	}
-	for _, key := range controller.keybindingPageDown {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.PageDown() }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingPageDown, controller.PageDown); err != nil {
+		return err
 	}
 
-	for _, key := range controller.keybindingCompareLayer {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.setCompareMode(CompareLayer) }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingCompareLayer, func() error { return controller.setCompareMode(CompareLayer) }); err != nil {
+		return err
 	}
 
-	for _, key := range controller.keybindingCompareAll {
-		if err := controller.gui.SetKeybinding(controller.Name, key.Value, key.Modifier, func(*gocui.Gui, *gocui.View) error { return controller.setCompareMode(CompareAll) }); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingCompareAll, func() error { return controller.setCompareMode(CompareAll) }); err != nil {
+		return err
 	}
 
+	if err := dispatcher.Bind(controller.Name, controller.keybindingCompareMark, controller.markCompareBound); err != nil {
+		return err
+	}
+
+	if err := dispatcher.Bind(controller.Name, controller.keybindingSquash, controller.toggleSquash); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(controller.Name, controller.keybindingSquashWrite, controller.writeSquashed); err != nil {
+		return err
+	}
+
+	controller.prefetchNeighbors()
+
 	return controller.Render()
 }
 
@@ -211,16 +268,59 @@ func (controller *LayerController) CursorUp() error {
 	return nil
 }
 
+// selectLayer 将光标移动到给定的图层（同时滚动图层窗格）并更新文件树视图。
+func (controller *LayerController) selectLayer(layerIdx int) error {
+	if layerIdx < 0 || layerIdx >= len(controller.Layers) {
+		return fmt.Errorf("invalid layer index given: %d of %d", layerIdx, len(controller.Layers)-1)
+	}
+	step := layerIdx - controller.LayerIndex
+	if step == 0 {
+		return nil
+	}
+	err := CursorStep(controller.gui, controller.view, step)
+	if err != nil {
+		return err
+	}
+	return controller.SetCursor(layerIdx)
+}
+
+// click 选择鼠标点击的图层（行相对于窗格顶部）。
+func (controller *LayerController) click(row int, double bool) error {
+	_, oy := controller.view.Origin()
+	if oy+row >= len(controller.Layers) {
+		return nil
+	}
+	return controller.selectLayer(oy + row)
+}
+
+// syncCursor 将视图的光标放回选中的图层所在的行（鼠标事件会把光标移动到指针所在的位置）。
+func (controller *LayerController) syncCursor() {
+	_, oy := controller.view.Origin()
+	controller.view.SetCursor(0, controller.LayerIndex-oy)
+}
+
 // SetCursor 重置光标并根据给定的层索引确定文件树视图的方向。
 func (controller *LayerController) SetCursor(layer int) error {
 	controller.LayerIndex = layer
+	controller.dropStaleSquash()
 	Controllers.Tree.setTreeByLayer(controller.getCompareIndexes())
 	Controllers.Details.Render()
 	controller.Render()
+	controller.prefetchNeighbors()
 
 	return nil
 }
 
+// prefetchNeighbors 在后台构建与当前选定图层相邻的图层的比较树，这样移动光标时不需要等待树的构建。
+func (controller *LayerController) prefetchNeighbors() {
+	for _, layerIdx := range []int{controller.LayerIndex + 1, controller.LayerIndex - 1} {
+		if layerIdx < 0 || layerIdx >= len(controller.Layers) {
+			continue
+		}
+		Controllers.Tree.prefetchTreeByLayer(controller.compareIndexes(layerIdx))
+	}
+}
+
 // currentLayer 返回当前选定的图层对象。
 func (controller *LayerController) currentLayer() image.Layer {
 	return controller.Layers[(len(controller.Layers)-1)-controller.LayerIndex]
@@ -229,22 +329,135 @@ func (controller *LayerController) currentLayer() image.Layer {
 // setCompareMode 将单层比较与聚合比较之间的层比较进行切换。
 func (controller *LayerController) setCompareMode(compareMode CompareType) error {
 	controller.CompareMode = compareMode
+	if compareMode != CompareRange {
+		controller.CompareRangeStart = -1
+		controller.CompareRangeStop = -1
+	}
+	controller.dropStaleSquash()
 	Update()
 	Render()
-	return Controllers.Tree.setTreeByLayer(controller.getCompareIndexes())
+	err := Controllers.Tree.setTreeByLayer(controller.getCompareIndexes())
+	controller.prefetchNeighbors()
+	return err
+}
+
+// markCompareBound 在选定的图层上标记比较范围的边界：
+// 第一次标记固定下界（上界跟随光标），第二次标记固定上界，第三次标记清除范围并回到之前的比较模式。
+func (controller *LayerController) markCompareBound() error {
+	switch {
+	case controller.CompareMode != CompareRange:
+		controller.previousCompareMode = controller.CompareMode
+		controller.CompareRangeStart = controller.LayerIndex
+		controller.CompareRangeStop = -1
+		return controller.setCompareMode(CompareRange)
+	case controller.CompareRangeStop < 0:
+		controller.CompareRangeStop = controller.LayerIndex
+		if controller.CompareRangeStart > controller.CompareRangeStop {
+			controller.CompareRangeStart, controller.CompareRangeStop = controller.CompareRangeStop, controller.CompareRangeStart
+		}
+		return controller.setCompareMode(CompareRange)
+	default:
+		return controller.setCompareMode(controller.previousCompareMode)
+	}
+}
+
+// toggleSquash 显示或隐藏将比较范围内的各层合并为一层的预测（合并后的层大小、镜像大小和效率的变化），预测显示在详细信息窗格中。
+func (controller *LayerController) toggleSquash() error {
+	if controller.squash != nil {
+		controller.squash = nil
+		Render()
+		return nil
+	}
+	lower, upper := controller.compareRange(controller.LayerIndex)
+	if controller.CompareMode != CompareRange || lower == upper {
+		return Controllers.Status.showMessage(tr("Mark the layers to merge with %s first", controller.keybindingCompareMark[0].String()), true)
+	}
+	estimate, err := image.EstimateSquash(controller.analysis, lower, upper)
+	if err != nil {
+		return Controllers.Status.showMessage(tr("cannot squash the layers: %v", err), true)
+	}
+	controller.squash = estimate
+	Render()
+	return nil
+}
+
+// writeSquashed 将合并了预测中的各层的镜像写入配置项 squash.output-file 指定的文件。
+func (controller *LayerController) writeSquashed() error {
+	if controller.squash == nil {
+		return Controllers.Status.showMessage(tr("Show the squash projection with %s first", controller.keybindingSquash[0].String()), true)
+	}
+	if controller.analysis.Squash == nil {
+		return Controllers.Status.showMessage(tr("The image layers are not available (enable viewer.spool-layers or raise viewer.spool-limit to keep them)"), true)
+	}
+	file := viper.GetString("squash.output-file")
+	out, err := os.Create(file)
+	if err != nil {
+		return Controllers.Status.showMessage(tr("cannot write the image: %v", err), true)
+	}
+	result, err := controller.analysis.Squash.WriteSquashed(out, controller.squash.Layer, image.SquashTags(controller.analysis.RepoTags))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file)
+		return Controllers.Status.showMessage(tr("cannot write the image: %v", err), true)
+	}
+	squashed := controller.squash.Layer
+	return Controllers.Status.showMessage(tr("Layers %d-%d squashed into %s (layer size %s %s %s)", squashed.Lower, squashed.Upper, file,
+		humanize.Bytes(uint64(result.OriginalSize)), theme.Glyphs.Range, humanize.Bytes(uint64(result.Size))), false)
+}
+
+// dropStaleSquash 在比较范围改变后丢弃不再对应的合并预测。
+func (controller *LayerController) dropStaleSquash() {
+	if controller.squash == nil {
+		return
+	}
+	lower, upper := controller.compareRange(controller.LayerIndex)
+	if controller.CompareMode != CompareRange || lower != controller.squash.Layer.Lower || upper != controller.squash.Layer.Upper {
+		controller.squash = nil
+	}
+}
+
+// compareRange 返回选择给定图层时CompareRange模式的下界和上界（上界未固定时跟随给定图层）。
+func (controller *LayerController) compareRange(layerIdx int) (lower, upper int) {
+	lower, upper = controller.CompareRangeStart, controller.CompareRangeStop
+	if upper < 0 {
+		upper = layerIdx
+	}
+	if lower > upper {
+		lower, upper = upper, lower
+	}
+	return lower, upper
 }
 
 // getCompareIndexes 确定用于比较的层边界（基于当前比较模式）
 func (controller *LayerController) getCompareIndexes() (bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) {
+	return controller.compareIndexes(controller.LayerIndex)
+}
+
+// compareIndexes 确定选择给定图层时用于比较的层边界（基于当前比较模式）
+func (controller *LayerController) compareIndexes(layerIdx int) (bottomTreeStart, bottomTreeStop, topTreeStart, topTreeStop int) {
 	bottomTreeStart = controller.CompareStartIndex
-	topTreeStop = controller.LayerIndex
 
-	if controller.LayerIndex == controller.CompareStartIndex {
-		bottomTreeStop = controller.LayerIndex
-		topTreeStart = controller.LayerIndex
+	if controller.CompareMode == CompareRange {
+		// the lower bound is stacked as the bottom tree, everything above it up to the upper bound is compared against it
+		lower, upper := controller.compareRange(layerIdx)
+		bottomTreeStop = lower
+		topTreeStart = lower + 1
+		if lower == upper {
+			topTreeStart = lower
+		}
+		return bottomTreeStart, bottomTreeStop, topTreeStart, upper
+	}
+
+	topTreeStop = layerIdx
+
+	if layerIdx == controller.CompareStartIndex {
+		bottomTreeStop = layerIdx
+		topTreeStart = layerIdx
 	} else if controller.CompareMode == CompareLayer {
-		bottomTreeStop = controller.LayerIndex - 1
-		topTreeStart = controller.LayerIndex
+		bottomTreeStop = layerIdx - 1
+		topTreeStart = layerIdx
 	} else {
 		bottomTreeStop = controller.CompareStartIndex
 		topTreeStart = controller.CompareStartIndex + 1
@@ -265,6 +478,15 @@ func (controller *LayerController) renderCompareBar(layerIdx int) string {
 		result = Formatting.CompareTop("  ")
 	}
 
+	// mark the fixed bounds of a range comparison
+	if controller.CompareMode == CompareRange && (layerIdx == controller.CompareRangeStart || layerIdx == controller.CompareRangeStop) {
+		if layerIdx <= bottomTreeStop {
+			result = Formatting.CompareBottom(theme.Glyphs.Bullet + " ")
+		} else {
+			result = Formatting.CompareTop(theme.Glyphs.Bullet + " ")
+		}
+	}
+
 	return result
 }
 
@@ -283,18 +505,22 @@ func (controller *LayerController) Update() error {
 func (controller *LayerController) Render() error {
 
 	// indicate when selected
-	title := "Layers"
-	if controller.gui.CurrentView() == controller.view {
-		title = "● " + title
+	title := tr("Layers")
+	if controller.CompareMode == CompareRange {
+		lower, upper := controller.compareRange(controller.LayerIndex)
+		title = tr("Layers (comparing %d %s %d)", lower, theme.Glyphs.Range, upper)
 	}
 
 	controller.gui.Update(func(g *gocui.Gui) error {
+		// the focus may have changed since Render was called (updates are not run in order), so it is checked here
+		if g.CurrentView() == controller.view {
+			title = theme.Glyphs.Bullet + " " + title
+		}
 		// update header
 		controller.header.Clear()
 		width, _ := g.Size()
-		headerStr := fmt.Sprintf("[%s]%s\n", title, strings.Repeat("─", width*2))
-		// headerStr += fmt.Sprintf("Cmp "+image.LayerFormat, "Layer Digest", "Size", "Command")
-		headerStr += fmt.Sprintf("Cmp"+image.LayerFormat, "Size", "Command")
+		headerStr := fmt.Sprintf("[%s]%s\n", title, strings.Repeat(theme.Glyphs.Rule, width*2))
+		headerStr += fmt.Sprintf("%-4s", tr("Cmp")) + image.FormatLayerHeader(controller.columns, func(title string) string { return tr(title) })
 		fmt.Fprintln(controller.header, Formatting.Header(vtclean.Clean(headerStr, false)))
 
 		// update contents
@@ -303,13 +529,13 @@ func (controller *LayerController) Render() error {
 			layer := controller.Layers[revIdx]
 			idx := (len(controller.Layers) - 1) - revIdx
 
-			layerStr := layer.String()
+			layerStr := image.FormatLayerRow(controller.columns, layer, controller.ImageSize)
 			compareBar := controller.renderCompareBar(idx)
 
 			if idx == controller.LayerIndex {
-				fmt.Fprintln(controller.view, compareBar+" "+Formatting.Selected(layerStr))
+				fmt.Fprintln(controller.view, compareBar+"  "+Formatting.Selected(layerStr))
 			} else {
-				fmt.Fprintln(controller.view, compareBar+" "+layerStr)
+				fmt.Fprintln(controller.view, compareBar+"  "+layerStr)
 			}
 
 		}
@@ -320,6 +546,8 @@ func (controller *LayerController) Render() error {
 
 // KeyHelp 指示用户在选择当前窗格时可以执行的所有操作。
 func (controller *LayerController) KeyHelp() string {
-	return renderStatusOption(controller.keybindingCompareLayer[0].String(), "Show layer changes", controller.CompareMode == CompareLayer) +
-		renderStatusOption(controller.keybindingCompareAll[0].String(), "Show aggregated changes", controller.CompareMode == CompareAll)
+	return renderStatusOption(controller.keybindingCompareLayer[0].String(), tr("Show layer changes"), controller.CompareMode == CompareLayer) +
+		renderStatusOption(controller.keybindingCompareAll[0].String(), tr("Show aggregated changes"), controller.CompareMode == CompareAll) +
+		renderStatusOption(controller.keybindingCompareMark[0].String(), tr("Mark compare range"), controller.CompareMode == CompareRange) +
+		renderStatusOption(controller.keybindingSquash[0].String(), tr("Squash (what-if)"), controller.squash != nil)
 }
@@ -0,0 +1,223 @@
+package ui
+
+import (
+	"LGM/keybinding"
+	"github.com/jroimartin/gocui"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"os"
+	"path"
+)
+
+// PaneLayout 描述可以在运行时调整的窗格布局。配置项 layout.persist 启用时，布局在每次调整后保存到 layout.file，下次启动时恢复。
+type PaneLayout struct {
+	// FileTreeWidth 是文件树窗格（右列）占屏幕宽度的比例（配置项 filetree.pane-width 是启动时的默认值）
+	FileTreeWidth float64
+	// LayerHeight 是图层窗格占屏幕高度的比例，为0时根据图层数量自动确定（左列的其余部分是详细信息窗格）
+	LayerHeight float64
+	// Maximized 指示选中的窗格是否占满整个屏幕（切换窗格时最大化的窗格跟随选中的窗格）
+	Maximized bool
+
+	// maximizedPane 是最大化时显示的窗格：选中的窗格，选中输入栏或帮助浮层时为之前选中的窗格
+	maximizedPane string
+	// 最近一次布局时图层窗格的高度及其范围和屏幕的高度（行），用于按行调整 LayerHeight
+	layersHeight    int
+	minLayersHeight int
+	maxLayersHeight int
+	screenHeight    int
+}
+
+const (
+	// 每次调整时列宽变化的比例和详细信息窗格高度变化的行数
+	columnStep = 0.05
+	rowStep    = 2
+	// 文件树窗格的最小和最大宽度比例
+	minFileTreeWidth = 0.1
+	maxFileTreeWidth = 0.9
+)
+
+// paneLayout 是当前的窗格布局
+var paneLayout PaneLayout
+
+// LayoutKeybindings 包含调整布局的按键（在图层、文件树和详细信息窗格中有效）
+var LayoutKeybindings struct {
+	narrowLeft    []keybinding.Key
+	widenLeft     []keybinding.Key
+	shrinkDetails []keybinding.Key
+	growDetails   []keybinding.Key
+	maximize      []keybinding.Key
+	reset         []keybinding.Key
+}
+
+// defaultLayout 返回根据配置确定的初始布局。
+func defaultLayout() PaneLayout {
+	width := viper.GetFloat64("filetree.pane-width")
+	if width >= 1 || width <= 0 {
+		logrus.Errorf("invalid config value: 'filetree.pane-width' should be 0 < value < 1, given '%v'", width)
+		width = 0.5
+	}
+	return PaneLayout{FileTreeWidth: width}
+}
+
+// loadLayout 返回上次保存的布局，没有保存的布局（或保存的值无效）时使用根据配置确定的初始布局。
+func loadLayout() PaneLayout {
+	result := defaultLayout()
+	file := viper.GetString("layout.file")
+	if !viper.GetBool("layout.persist") || file == "" {
+		return result
+	}
+
+	saved := viper.New()
+	saved.SetConfigFile(file)
+	if err := saved.ReadInConfig(); err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Errorf("unable to read the saved layout: %+v", err)
+		}
+		return result
+	}
+	if width := saved.GetFloat64("filetree-width"); width >= minFileTreeWidth && width <= maxFileTreeWidth {
+		result.FileTreeWidth = width
+	}
+	if height := saved.GetFloat64("layer-height"); height > 0 && height < 1 {
+		result.LayerHeight = height
+	}
+	result.Maximized = saved.GetBool("maximized")
+	return result
+}
+
+// save 将布局写入 layout.file（配置项 layout.persist 为false时不保存）。
+func (layout *PaneLayout) save() {
+	file := viper.GetString("layout.file")
+	if !viper.GetBool("layout.persist") || file == "" {
+		return
+	}
+
+	saved := viper.New()
+	saved.Set("filetree-width", layout.FileTreeWidth)
+	saved.Set("layer-height", layout.LayerHeight)
+	saved.Set("maximized", layout.Maximized)
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		logrus.Errorf("unable to save the layout: %+v", err)
+		return
+	}
+	if err := saved.WriteConfigAs(file); err != nil {
+		logrus.Errorf("unable to save the layout: %+v", err)
+	}
+}
+
+// layersRows 返回图层窗格在给定屏幕高度下占用的行数（包括标题）。
+func (layout *PaneLayout) layersRows(layerCount, headerRows, maxY, bottomRows int) int {
+	// leave at least one row for the layers, the details and the config pane
+	layout.minLayersHeight = headerRows + 1
+	layout.maxLayersHeight = maxY - bottomRows - 2*headerRows - 3
+	layout.screenHeight = maxY
+
+	height := layerCount + headerRows + 1 // layers + header + base image layer row
+	maxHeight := int(0.75 * float64(maxY))
+	if layout.LayerHeight > 0 {
+		height = int(layout.LayerHeight * float64(maxY))
+		maxHeight = layout.maxLayersHeight
+	}
+	if height > maxHeight {
+		height = maxHeight
+	}
+	if height < layout.minLayersHeight {
+		height = layout.minLayersHeight
+	}
+	layout.layersHeight = height
+	return height
+}
+
+// resizeColumns 将左列（图层和详细信息窗格）加宽给定的比例（为负时变窄）。
+func (layout *PaneLayout) resizeColumns(step float64) error {
+	layout.FileTreeWidth -= step
+	if layout.FileTreeWidth < minFileTreeWidth {
+		layout.FileTreeWidth = minFileTreeWidth
+	}
+	if layout.FileTreeWidth > maxFileTreeWidth {
+		layout.FileTreeWidth = maxFileTreeWidth
+	}
+	return layout.changed()
+}
+
+// resizeDetails 将详细信息窗格增高给定的行数（为负时变矮），图层窗格相应地变矮或增高。
+func (layout *PaneLayout) resizeDetails(rows int) error {
+	if layout.screenHeight <= 0 {
+		return nil
+	}
+	height := layout.layersHeight - rows
+	if height > layout.maxLayersHeight {
+		height = layout.maxLayersHeight
+	}
+	if height < layout.minLayersHeight {
+		height = layout.minLayersHeight
+	}
+	// the middle of the row, so that the same number of rows is computed again
+	layout.LayerHeight = (float64(height) + 0.5) / float64(layout.screenHeight)
+	return layout.changed()
+}
+
+// toggleMaximize 最大化选中的窗格或恢复所有窗格。
+func (layout *PaneLayout) toggleMaximize() error {
+	layout.Maximized = !layout.Maximized
+	return layout.changed()
+}
+
+// reset 恢复根据配置确定的初始布局。
+func (layout *PaneLayout) reset() error {
+	*layout = defaultLayout()
+	return layout.changed()
+}
+
+// changed 保存布局并重新渲染所有窗格（窗格的大小在下一次layout时改变）。
+func (layout *PaneLayout) changed() error {
+	layout.save()
+	Update()
+	Render()
+	return nil
+}
+
+// layoutBindings 在图层、文件树、详细信息和配置窗格中注册调整布局的按键（这些按键通常是普通字符，因此不在输入栏中绑定）。
+func layoutBindings() error {
+	for _, name := range []string{Controllers.Layer.Name, Controllers.Tree.Name, Controllers.Details.Name, Controllers.Config.Name} {
+		bindings := []struct {
+			keys    []keybinding.Key
+			handler func() error
+		}{
+			{LayoutKeybindings.narrowLeft, func() error { return paneLayout.resizeColumns(-columnStep) }},
+			{LayoutKeybindings.widenLeft, func() error { return paneLayout.resizeColumns(columnStep) }},
+			{LayoutKeybindings.shrinkDetails, func() error { return paneLayout.resizeDetails(-rowStep) }},
+			{LayoutKeybindings.growDetails, func() error { return paneLayout.resizeDetails(rowStep) }},
+			{LayoutKeybindings.maximize, paneLayout.toggleMaximize},
+			{LayoutKeybindings.reset, paneLayout.reset},
+		}
+		for _, binding := range bindings {
+			if err := dispatcher.Bind(name, binding.keys, binding.handler); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// offscreen 将视图的位置移出屏幕（最大化其他窗格时使用），这样视图保持原来的大小，窗格的滚动状态不受影响。
+func offscreen(x0, x1, maxX int) (int, int) {
+	return x0 + maxX + 2, x1 + maxX + 2
+}
+
+// maximizedPaneName 返回最大化时显示的窗格名称，没有最大化时返回空字符串。
+func (layout *PaneLayout) maximizedPaneName(g *gocui.Gui) string {
+	if !layout.Maximized {
+		return ""
+	}
+	if current := g.CurrentView(); current != nil {
+		switch current.Name() {
+		case Controllers.Layer.Name, Controllers.Tree.Name, Controllers.Details.Name, Controllers.Config.Name:
+			layout.maximizedPane = current.Name()
+		}
+	}
+	if layout.maximizedPane == "" {
+		layout.maximizedPane = Controllers.Layer.Name
+	}
+	return layout.maximizedPane
+}
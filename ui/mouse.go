@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"github.com/jroimartin/gocui"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// clickable 是可以用鼠标选择行的窗格。
+type clickable interface {
+	// click 选中鼠标点击的行（相对于窗格顶部），double 指示是否为双击
+	click(row int, double bool) error
+}
+
+// lastClick 记录上一次点击，用于识别双击
+var lastClick struct {
+	view string
+	row  int
+	time time.Time
+}
+
+// doubleClickInterval 是两次点击被视为双击的最长间隔（配置项 mouse.double-click-interval）
+var doubleClickInterval = 500 * time.Millisecond
+
+// mouseBindings 注册鼠标操作：点击窗格将其选中，点击行与按键一样移动光标（双击执行窗格的双击操作），滚轮在指针所在的窗格中移动光标。
+// gocui将鼠标事件交给指针所在的视图，因此这些绑定对所有视图有效。
+func mouseBindings(g *gocui.Gui) error {
+	if err := g.SetKeybinding("", gocui.MouseLeft, gocui.ModNone, onClick); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("", gocui.MouseWheelUp, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error { return onWheel(v, -1) }); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("", gocui.MouseWheelDown, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error { return onWheel(v, 1) }); err != nil {
+		return err
+	}
+	return nil
+}
+
+// paneName 返回视图所属窗格的名称（窗格的标题视图以"header"结尾）。
+func paneName(v *gocui.View) string {
+	return strings.TrimSuffix(v.Name(), "header")
+}
+
+// onClick 选中点击的窗格并将点击交给窗格处理。帮助浮层或查看器显示时点击浮层以外的位置关闭浮层；搜索时点击其他窗格保留搜索结果并结束搜索。
+func onClick(g *gocui.Gui, v *gocui.View) error {
+	// gocui has already moved the cursor of the view to the pointer, the panes expect it where it was
+	_, row := v.Cursor()
+	syncCursors()
+	name := paneName(v)
+
+	if Controllers.Help.IsVisible() {
+		if name == Controllers.Help.Name {
+			return nil
+		}
+		return Controllers.Help.toggle(g, g.CurrentView())
+	}
+	if Controllers.Viewer.IsVisible() {
+		if name == Controllers.Viewer.Name {
+			return nil
+		}
+		return Controllers.Viewer.close()
+	}
+	if Controllers.Search.IsVisible() && name != Controllers.Search.Name {
+		if err := Controllers.Search.close(true); err != nil {
+			return err
+		}
+	}
+
+	if err := focusView(g, name); err != nil {
+		return err
+	}
+
+	pane, ok := Controllers.lookup[name].(clickable)
+	if !ok || v.Name() != name {
+		return nil
+	}
+	now := time.Now()
+	double := lastClick.view == name && lastClick.row == row && now.Sub(lastClick.time) <= doubleClickInterval
+	lastClick.view, lastClick.row, lastClick.time = name, row, now
+	if double {
+		// a third click starts a new double click
+		lastClick.view = ""
+	}
+	return pane.click(row, double)
+}
+
+// onWheel 在指针所在的窗格中移动光标（不改变选中的窗格）。
+func onWheel(v *gocui.View, step int) error {
+	syncCursors()
+	name := paneName(v)
+	if Controllers.Help.IsVisible() && name != Controllers.Help.Name {
+		return nil
+	}
+	if Controllers.Viewer.IsVisible() && !Controllers.Help.IsVisible() && name != Controllers.Viewer.Name {
+		return nil
+	}
+	pane, ok := Controllers.lookup[name]
+	if !ok {
+		return nil
+	}
+	if step < 0 {
+		return pane.CursorUp()
+	}
+	return pane.CursorDown()
+}
+
+// focusView 选中给定的窗格并重新渲染屏幕。状态栏不能被选中。
+func focusView(g *gocui.Gui, name string) error {
+	if _, ok := Controllers.lookup[name]; !ok || name == Controllers.Status.Name {
+		return nil
+	}
+	if current := g.CurrentView(); current != nil && current.Name() == name {
+		return nil
+	}
+	if _, err := g.SetCurrentView(name); err != nil {
+		return err
+	}
+	Update()
+	Render()
+	return nil
+}
+
+// syncCursors 将鼠标事件移动的光标放回窗格使用的位置（gocui在调用处理函数之前把指针所在视图的光标移动到指针处，
+// 拖动等没有绑定的事件也是如此）：图层窗格的光标跟随选中的图层，输入栏的光标不能超过输入的末尾。
+func syncCursors() {
+	Controllers.Layer.syncCursor()
+	for _, v := range []*gocui.View{Controllers.Filter.view, Controllers.Search.view} {
+		if v == nil {
+			continue
+		}
+		cx, _ := v.Cursor()
+		ox, _ := v.Origin()
+		end := utf8.RuneCountInString(strings.TrimSuffix(v.Buffer(), "\n")) - ox
+		if cx > end {
+			cx = end
+		}
+		v.SetCursor(cx, 0)
+	}
+}
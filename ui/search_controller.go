@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"LGM/filetree"
+	"LGM/theme"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"strings"
+)
+
+// SearchController 包含用于填充搜索栏的UI对象和数据模型。 与过滤器不同，搜索不会隐藏任何节点，而是高亮满足查询条件（见filetree.Query）的节点，并将光标移动到匹配项上。
+type SearchController struct {
+	Name      string
+	gui       *gocui.Gui
+	view      *gocui.View
+	header    *gocui.View
+	headerStr string
+	maxLength int
+	hidden    bool
+
+	// query 是最近一次有效的查询，err 是当前输入的解析错误（输入有效时为nil）
+	query *filetree.Query
+	err   error
+	// origin 是开始搜索时光标所在节点的路径，增量搜索从这里开始向下查找
+	origin string
+}
+
+// NewSearchController 创建一个附加全局[gocui]屏幕对象的新视图对象。
+func NewSearchController(name string, gui *gocui.Gui) (controller *SearchController) {
+	controller = new(SearchController)
+
+	// populate main fields
+	controller.Name = name
+	controller.gui = gui
+	controller.headerStr = tr("Search: ")
+	controller.hidden = true
+
+	return controller
+}
+
+// Setup 在全局[gocui]视图对象的上下文中初始化UI关注点。
+func (controller *SearchController) Setup(v *gocui.View, header *gocui.View) error {
+
+	// set controller options
+	controller.view = v
+	controller.maxLength = 200
+	controller.view.Frame = false
+	controller.view.BgColor = gocui.AttrReverse
+	controller.view.Editable = true
+	controller.view.Editor = controller
+
+	controller.header = header
+	controller.header.BgColor = gocui.AttrReverse
+	controller.header.Editable = false
+	controller.header.Wrap = false
+	controller.header.Frame = false
+
+	controller.Render()
+
+	return nil
+}
+
+// IsVisible 指示搜索栏当前是否可见
+func (controller *SearchController) IsVisible() bool {
+	if controller == nil {
+		return false
+	}
+	return !controller.hidden
+}
+
+// CursorDown 在搜索栏中向下移动光标（当前不指示任何内容）。
+func (controller *SearchController) CursorDown() error {
+	return nil
+}
+
+// CursorUp 在搜索栏中向上移动光标（当前不指示任何内容）。
+func (controller *SearchController) CursorUp() error {
+	return nil
+}
+
+// Edit 拦截搜索栏中的按键事件，以实时高亮匹配项并将光标移动到第一个匹配项。Enter 保留搜索结果并返回文件树，Esc 取消搜索。
+func (controller *SearchController) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	if !controller.IsVisible() {
+		return
+	}
+
+	cx, _ := v.Cursor()
+	ox, _ := v.Origin()
+	limit := ox+cx+1 > controller.maxLength
+	switch {
+	case key == gocui.KeyEnter:
+		controller.close(true)
+		return
+	case key == gocui.KeyEsc:
+		controller.close(false)
+		return
+	case ch != 0 && mod == 0 && !limit:
+		v.EditWrite(ch)
+	case key == gocui.KeySpace && !limit:
+		v.EditWrite(' ')
+	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+		v.EditDelete(true)
+	}
+
+	// 与过滤器一样，无效的查询在状态栏中显示错误并继续使用上一次有效的查询
+	query, err := filetree.ParseQuery(strings.TrimSpace(v.Buffer()))
+	controller.err = err
+	if err == nil {
+		controller.query = query
+	}
+
+	if Controllers.Tree != nil {
+		Controllers.Tree.searchFrom(controller.origin)
+	}
+	if Controllers.Status != nil {
+		Controllers.Status.Render()
+	}
+}
+
+// open 清空并显示搜索栏，开始新的搜索。
+func (controller *SearchController) open() error {
+	controller.view.Clear()
+	controller.view.SetCursor(0, 0)
+	controller.query = nil
+	controller.err = nil
+
+	controller.origin = ""
+	if node := Controllers.Tree.getAbsPositionNode(); node != nil {
+		controller.origin = node.Path()
+	}
+
+	controller.hidden = false
+	_, err := controller.gui.SetCurrentView(controller.Name)
+	if err != nil {
+		return err
+	}
+	Update()
+	Render()
+	return nil
+}
+
+// close 隐藏搜索栏并返回文件树。keep为false时清除搜索结果并将光标移回开始搜索时的位置。
+func (controller *SearchController) close(keep bool) error {
+	if !keep {
+		controller.query = nil
+		controller.err = nil
+	}
+	// clear the input while the bar is still visible (the cursor cannot be moved within a hidden view)
+	controller.view.Clear()
+	controller.view.SetCursor(0, 0)
+	controller.hidden = true
+
+	_, err := controller.gui.SetCurrentView(Controllers.Tree.Name)
+	if err != nil {
+		return err
+	}
+	Update()
+	if !keep && controller.origin != "" {
+		Controllers.Tree.searchFrom(controller.origin)
+	}
+	Render()
+	return nil
+}
+
+// Update 刷新状态对象以供将来渲染（当前不执行任何操作）。
+func (controller *SearchController) Update() error {
+	return nil
+}
+
+// Render 将状态对象刷新到屏幕。当前这是用户的搜索输入。
+func (controller *SearchController) Render() error {
+	controller.gui.Update(func(g *gocui.Gui) error {
+		// render the header
+		fmt.Fprintln(controller.header, Formatting.Header(controller.headerStr))
+
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp 指示选择当前窗格时用户可以采取的所有可能操作。
+func (controller *SearchController) KeyHelp() string {
+	if controller.err != nil {
+		return Formatting.StatusError(theme.Glyphs.Separator + controller.err.Error() + " ")
+	}
+	if controller.query == nil {
+		return Formatting.StatusControlNormal(theme.Glyphs.Separator + tr("Type to search the file tree (e.g. name:*.so size>1MB), Enter to confirm, Esc to cancel") + " ")
+	}
+	return Formatting.StatusControlNormal(theme.Glyphs.Separator + Controllers.Tree.searchStatus() + " ")
+}
+
+// searchQuery 返回用户在搜索栏中输入的（最近一次有效的）查询，没有进行搜索时返回nil。
+func searchQuery() *filetree.Query {
+	if Controllers.Search == nil {
+		return nil
+	}
+	return Controllers.Search.query
+}
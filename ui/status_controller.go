@@ -1,16 +1,26 @@
 package ui
 
 import (
+	"LGM/theme"
 	"fmt"
 	"github.com/jroimartin/gocui"
 	"strings"
+	"time"
 )
 
+// statusMessageDuration 是状态栏显示消息的时长
+const statusMessageDuration = 5 * time.Second
+
 // StatusController 保存用于填充最底部窗格的UI对象和数据模型。 具体而言，该面板向用户显示了一组可能在窗口和当前选定窗格中执行的操作。
 type StatusController struct {
 	Name string
 	gui  *gocui.Gui
 	view *gocui.View
+	// message 是暂时代替按键提示显示的消息（例如提取文件的结果），isError 指示它是否是错误
+	message string
+	isError bool
+	// messageTimer 在显示的时长结束后清除消息
+	messageTimer *time.Timer
 }
 
 // NewStatusController 创建附加到全局[gocui]屏幕对象的新视图对象.
@@ -63,7 +73,15 @@ func (controller *StatusController) Update() error {
 func (controller *StatusController) Render() error {
 	controller.gui.Update(func(g *gocui.Gui) error {
 		controller.view.Clear()
-		fmt.Fprintln(controller.view, controller.KeyHelp()+Controllers.lookup[controller.gui.CurrentView().Name()].KeyHelp()+Formatting.StatusNormal("▏"+strings.Repeat(" ", 1000)))
+		if controller.message != "" {
+			if controller.isError {
+				fmt.Fprintln(controller.view, Formatting.StatusError(theme.Glyphs.Separator+controller.message+" ")+Formatting.StatusNormal(theme.Glyphs.Separator+strings.Repeat(" ", 1000)))
+			} else {
+				fmt.Fprintln(controller.view, Formatting.StatusNormal(theme.Glyphs.Separator+controller.message+" "+theme.Glyphs.Separator+strings.Repeat(" ", 1000)))
+			}
+			return nil
+		}
+		fmt.Fprintln(controller.view, controller.KeyHelp()+Controllers.lookup[controller.gui.CurrentView().Name()].KeyHelp()+Formatting.StatusNormal(theme.Glyphs.Separator+strings.Repeat(" ", 1000)))
 
 		return nil
 	})
@@ -71,9 +89,29 @@ func (controller *StatusController) Render() error {
 	return nil
 }
 
+// showMessage 在状态栏中显示给定的消息（代替按键提示），一段时间（statusMessageDuration）后恢复按键提示。
+func (controller *StatusController) showMessage(message string, isError bool) error {
+	controller.message = message
+	controller.isError = isError
+	if controller.messageTimer != nil {
+		controller.messageTimer.Stop()
+	}
+	controller.messageTimer = time.AfterFunc(statusMessageDuration, func() {
+		controller.gui.Update(func(g *gocui.Gui) error {
+			if controller.message == message {
+				controller.message = ""
+				return controller.Render()
+			}
+			return nil
+		})
+	})
+	return controller.Render()
+}
+
 // KeyHelp 指示用户在选择当前窗格时可以执行的所有操作。
 func (controller *StatusController) KeyHelp() string {
-	return renderStatusOption(GlobalKeybindings.quit[0].String(), "Quit", false) +
-		renderStatusOption(GlobalKeybindings.toggleView[0].String(), "Switch view", false) +
-		renderStatusOption(GlobalKeybindings.filterView[0].String(), "Filter", Controllers.Filter.IsVisible())
+	return renderStatusOption(GlobalKeybindings.quit[0].String(), tr("Quit"), false) +
+		renderStatusOption(GlobalKeybindings.toggleView[0].String(), tr("Switch view"), false) +
+		renderStatusOption(GlobalKeybindings.filterView[0].String(), tr("Filter"), Controllers.Filter.IsVisible()) +
+		renderStatusOption(GlobalKeybindings.help[0].String(), tr("Help"), Controllers.Help.IsVisible())
 }
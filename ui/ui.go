@@ -3,14 +3,18 @@ package ui
 import (
 	"errors"
 	"LGM/filetree"
+	"LGM/i18n"
 	"LGM/image"
 	"LGM/keybinding"
 	"LGM/utils"
+	"LGM/theme"
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/jroimartin/gocui"
+	"github.com/mattn/go-runewidth"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"strings"
+	"unicode/utf8"
 )
 
 const debug = false
@@ -39,6 +43,7 @@ var Formatting struct {
 	StatusNormal          func(...interface{}) string
 	StatusControlSelected func(...interface{}) string
 	StatusControlNormal   func(...interface{}) string
+	StatusError           func(...interface{}) string
 	CompareTop            func(...interface{}) string
 	CompareBottom         func(...interface{}) string
 }
@@ -49,15 +54,34 @@ var GlobalKeybindings struct {
 	toggleView []keybinding.Key
 	// 过滤视图
 	filterView []keybinding.Key
+	// 按键帮助
+	help []keybinding.Key
 }
 
+// CursorKeybindings 包含各窗格共用的光标移动按键
+var CursorKeybindings struct {
+	up     []keybinding.Key
+	down   []keybinding.Key
+	left   []keybinding.Key
+	right  []keybinding.Key
+	top    []keybinding.Key
+	bottom []keybinding.Key
+}
+
+// dispatcher 将按键（包括多键序列）分派给各窗格的处理函数
+var dispatcher *keybinding.Dispatcher
+
 // Controllers 包含所有呈现的UI窗格
 var Controllers struct {
 	Tree    *FileTreeController
 	Layer   *LayerController
 	Status  *StatusController
 	Filter  *FilterController
+	Search  *SearchController
 	Details *DetailsController
+	Config  *ConfigController
+	Viewer  *ViewerController
+	Help    *HelpController
 	lookup  map[string]View
 }
 
@@ -72,13 +96,22 @@ type View interface {
 	IsVisible() bool
 }
 
-// toggleView 在file view和layer view之间切换并重新渲染屏幕。
+// toggleView 依次在layer view、file view、details view和config view之间切换并重新渲染屏幕。
 func toggleView(g *gocui.Gui, v *gocui.View) (err error) {
-	if v == nil || v.Name() == Controllers.Layer.Name {
-		_, err = g.SetCurrentView(Controllers.Tree.Name)
-	} else {
-		_, err = g.SetCurrentView(Controllers.Layer.Name)
+	// the overlays keep the focus until they are closed
+	if Controllers.Help.IsVisible() || Controllers.Viewer.IsVisible() {
+		return nil
+	}
+	order := []string{Controllers.Layer.Name, Controllers.Tree.Name, Controllers.Details.Name, Controllers.Config.Name}
+	next := order[0]
+	if v != nil {
+		for idx, name := range order {
+			if name == v.Name() {
+				next = order[(idx+1)%len(order)]
+			}
+		}
 	}
+	_, err = g.SetCurrentView(next)
 	Update()
 	Render()
 	return err
@@ -86,9 +119,16 @@ func toggleView(g *gocui.Gui, v *gocui.View) (err error) {
 
 // toggleFilterView 显示/隐藏文件树筛选器窗格。
 func toggleFilterView(g *gocui.Gui, v *gocui.View) error {
+	// the overlays keep the focus until they are closed
+	if Controllers.Help.IsVisible() || Controllers.Viewer.IsVisible() {
+		return nil
+	}
+
 	// delete all user input from the tree view
 	Controllers.Filter.view.Clear()
 	Controllers.Filter.view.SetCursor(0, 0)
+	Controllers.Filter.query = nil
+	Controllers.Filter.err = nil
 
 	// toggle hiding
 	Controllers.Filter.hidden = !Controllers.Filter.hidden
@@ -128,23 +168,42 @@ func quit(g *gocui.Gui, v *gocui.View) error {
 
 // keyBindings 注册全局按键操作，在任何窗格中有效。
 func keyBindings(g *gocui.Gui) error {
-	for _, key := range GlobalKeybindings.quit {
-		if err := g.SetKeybinding("", key.Value, key.Modifier, quit); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind("", GlobalKeybindings.quit, func() error { return quit(g, g.CurrentView()) }); err != nil {
+		return err
 	}
 
-	for _, key := range GlobalKeybindings.toggleView {
-		if err := g.SetKeybinding("", key.Value, key.Modifier, toggleView); err != nil {
-			return err
-		}
+	if err := dispatcher.Bind("", GlobalKeybindings.toggleView, func() error { return toggleView(g, g.CurrentView()) }); err != nil {
+		return err
+	}
+
+	if err := dispatcher.Bind("", GlobalKeybindings.filterView, func() error { return toggleFilterView(g, g.CurrentView()) }); err != nil {
+		return err
 	}
 
-	for _, key := range GlobalKeybindings.filterView {
-		if err := g.SetKeybinding("", key.Value, key.Modifier, toggleFilterView); err != nil {
+	// the help keys are usually plain characters, so they are not bound in the editable filter and search bars
+	toggleHelp := func() error { return Controllers.Help.toggle(g, g.CurrentView()) }
+	for _, name := range []string{Controllers.Layer.Name, Controllers.Tree.Name, Controllers.Details.Name, Controllers.Config.Name, Controllers.Viewer.Name, Controllers.Help.Name} {
+		if err := dispatcher.Bind(name, GlobalKeybindings.help, toggleHelp); err != nil {
 			return err
 		}
 	}
+	if err := g.SetKeybinding(Controllers.Help.Name, gocui.KeyEsc, gocui.ModNone, Controllers.Help.toggle); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(Controllers.Help.Name, CursorKeybindings.down, Controllers.Help.CursorDown); err != nil {
+		return err
+	}
+	if err := dispatcher.Bind(Controllers.Help.Name, CursorKeybindings.up, Controllers.Help.CursorUp); err != nil {
+		return err
+	}
+
+	if err := Controllers.Viewer.keyBindings(g); err != nil {
+		return err
+	}
+
+	if err := layoutBindings(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -167,12 +226,7 @@ func layout(g *gocui.Gui) error {
 	// TODO: this logic should be refactored into an abstraction that takes care of the math for us
 
 	maxX, maxY := g.Size()
-	fileTreeSplitRatio := viper.GetFloat64("filetree.pane-width")
-	if fileTreeSplitRatio >= 1 || fileTreeSplitRatio <= 0 {
-		logrus.Errorf("invalid config value: 'filetree.pane-width' should be 0 < value < 1, given '%v'", fileTreeSplitRatio)
-		fileTreeSplitRatio = 0.5
-	}
-	splitCols := int(float64(maxX) * (1.0 - fileTreeSplitRatio))
+	splitCols := int(float64(maxX) * (1.0 - paneLayout.FileTreeWidth))
 	debugWidth := 0
 	if debug {
 		debugWidth = maxX / 4
@@ -182,16 +236,12 @@ func layout(g *gocui.Gui) error {
 	headerRows := 2
 
 	filterBarHeight := 1
+	searchBarHeight := 1
 	statusBarHeight := 1
 
 	statusBarIndex := 1
 	filterBarIndex := 2
-
-	layersHeight := len(Controllers.Layer.Layers) + headerRows + 1 // layers + header + base image layer row
-	maxLayerHeight := int(0.75 * float64(maxY))
-	if layersHeight > maxLayerHeight {
-		layersHeight = maxLayerHeight
-	}
+	searchBarIndex := 3
 
 	var view, header *gocui.View
 	var viewErr, headerErr, err error
@@ -199,6 +249,47 @@ func layout(g *gocui.Gui) error {
 	if Controllers.Filter.hidden {
 		bottomRows--
 		filterBarHeight = 0
+		searchBarIndex--
+	}
+
+	// the search bar is stacked above the filter bar when both are shown
+	if Controllers.Search.hidden {
+		searchBarHeight = 0
+	} else {
+		bottomRows++
+	}
+
+	layersHeight := paneLayout.layersRows(len(Controllers.Layer.Layers), headerRows, maxY, bottomRows)
+	configY0 := maxY - bottomRows - Controllers.Config.rows(maxY-bottomRows-layersHeight, headerRows)
+
+	// the columns of the panes and where the details and config panes start
+	layerX0, layerX1, layerY1 := -1, splitCols, layersHeight
+	detailsX0, detailsX1, detailsY0, detailsY1 := -1, splitCols, layersHeight, configY0
+	configX0, configX1 := -1, splitCols
+	treeX0, treeX1 := splitCols, debugCols
+
+	// a maximized pane takes the place of all panes, the others are moved off the screen
+	switch paneLayout.maximizedPaneName(g) {
+	case Controllers.Layer.Name:
+		layerX1, layerY1 = debugCols, maxY-bottomRows
+		detailsX0, detailsX1 = offscreen(detailsX0, detailsX1, maxX)
+		configX0, configX1 = offscreen(configX0, configX1, maxX)
+		treeX0, treeX1 = offscreen(treeX0, treeX1, maxX)
+	case Controllers.Details.Name:
+		detailsX1, detailsY0, detailsY1 = debugCols, 0, maxY-bottomRows
+		layerX0, layerX1 = offscreen(layerX0, layerX1, maxX)
+		configX0, configX1 = offscreen(configX0, configX1, maxX)
+		treeX0, treeX1 = offscreen(treeX0, treeX1, maxX)
+	case Controllers.Config.Name:
+		configX1, configY0 = debugCols, 0
+		layerX0, layerX1 = offscreen(layerX0, layerX1, maxX)
+		detailsX0, detailsX1 = offscreen(detailsX0, detailsX1, maxX)
+		treeX0, treeX1 = offscreen(treeX0, treeX1, maxX)
+	case Controllers.Tree.Name:
+		treeX0 = -1
+		layerX0, layerX1 = offscreen(layerX0, layerX1, maxX)
+		detailsX0, detailsX1 = offscreen(detailsX0, detailsX1, maxX)
+		configX0, configX1 = offscreen(configX0, configX1, maxX)
 	}
 
 	// Debug pane
@@ -211,8 +302,8 @@ func layout(g *gocui.Gui) error {
 	}
 
 	// Layers
-	view, viewErr = g.SetView(Controllers.Layer.Name, -1, -1+headerRows, splitCols, layersHeight)
-	header, headerErr = g.SetView(Controllers.Layer.Name+"header", -1, -1, splitCols, headerRows)
+	view, viewErr = g.SetView(Controllers.Layer.Name, layerX0, -1+headerRows, layerX1, layerY1)
+	header, headerErr = g.SetView(Controllers.Layer.Name+"header", layerX0, -1, layerX1, headerRows)
 	if isNewView(viewErr, headerErr) {
 		Controllers.Layer.Setup(view, header)
 
@@ -224,19 +315,27 @@ func layout(g *gocui.Gui) error {
 	}
 
 	// Details
-	view, viewErr = g.SetView(Controllers.Details.Name, -1, -1+layersHeight+headerRows, splitCols, maxY-bottomRows)
-	header, headerErr = g.SetView(Controllers.Details.Name+"header", -1, -1+layersHeight, splitCols, layersHeight+headerRows)
+	view, viewErr = g.SetView(Controllers.Details.Name, detailsX0, -1+detailsY0+headerRows, detailsX1, detailsY1)
+	header, headerErr = g.SetView(Controllers.Details.Name+"header", detailsX0, -1+detailsY0, detailsX1, detailsY0+headerRows)
 	if isNewView(viewErr, headerErr) {
 		Controllers.Details.Setup(view, header)
 	}
 
+	// Image config
+	// the last row of the panes above the status bar is drawn under it, the config pane ends one row higher so that its last line stays visible
+	view, viewErr = g.SetView(Controllers.Config.Name, configX0, -1+configY0+headerRows, configX1, maxY-bottomRows-1)
+	header, headerErr = g.SetView(Controllers.Config.Name+"header", configX0, -1+configY0, configX1, configY0+headerRows)
+	if isNewView(viewErr, headerErr) {
+		Controllers.Config.Setup(view, header)
+	}
+
 	// Filetree
 	offset := 0
 	if !Controllers.Tree.vm.ShowAttributes {
 		offset = 1
 	}
-	view, viewErr = g.SetView(Controllers.Tree.Name, splitCols, -1+headerRows-offset, debugCols, maxY-bottomRows)
-	header, headerErr = g.SetView(Controllers.Tree.Name+"header", splitCols, -1, debugCols, headerRows-offset)
+	view, viewErr = g.SetView(Controllers.Tree.Name, treeX0, -1+headerRows-offset, treeX1, maxY-bottomRows)
+	header, headerErr = g.SetView(Controllers.Tree.Name+"header", treeX0, -1, treeX1, headerRows-offset)
 	if isNewView(viewErr, headerErr) {
 		Controllers.Tree.Setup(view, header)
 	}
@@ -250,11 +349,49 @@ func layout(g *gocui.Gui) error {
 
 	// Filter Bar
 	view, viewErr = g.SetView(Controllers.Filter.Name, len(Controllers.Filter.headerStr)-1, maxY-filterBarHeight-filterBarIndex, maxX, maxY-(filterBarIndex-1))
-	header, headerErr = g.SetView(Controllers.Filter.Name+"header", -1, maxY-filterBarHeight-filterBarIndex, len(Controllers.Filter.headerStr), maxY-(filterBarIndex-1))
+	header, headerErr = g.SetView(Controllers.Filter.Name+"header", -1, maxY-filterBarHeight-filterBarIndex, utf8.RuneCountInString(Controllers.Filter.headerStr), maxY-(filterBarIndex-1))
 	if isNewView(viewErr, headerErr) {
 		Controllers.Filter.Setup(view, header)
 	}
 
+	// Search Bar
+	view, viewErr = g.SetView(Controllers.Search.Name, len(Controllers.Search.headerStr)-1, maxY-searchBarHeight-searchBarIndex, maxX, maxY-(searchBarIndex-1))
+	header, headerErr = g.SetView(Controllers.Search.Name+"header", -1, maxY-searchBarHeight-searchBarIndex, utf8.RuneCountInString(Controllers.Search.headerStr), maxY-(searchBarIndex-1))
+	if isNewView(viewErr, headerErr) {
+		Controllers.Search.Setup(view, header)
+	}
+
+	// File viewer overlay (covers the panes, the help is drawn on top of it)
+	if Controllers.Viewer.IsVisible() {
+		view, viewErr = g.SetView(Controllers.Viewer.Name, 0, 0, maxX-1, maxY-statusBarHeight-1-bottomRows)
+		if isNewView(viewErr) {
+			Controllers.Viewer.Setup(view, nil)
+			if _, err = g.SetCurrentView(Controllers.Viewer.Name); err != nil {
+				return err
+			}
+			Controllers.Status.Render()
+		}
+	}
+
+	// Help overlay (created last so that it is drawn on top of the other panes)
+	if Controllers.Help.IsVisible() {
+		width, height := Controllers.Help.size(maxX, maxY)
+		x0, y0 := (maxX-width)/2, (maxY-height)/2
+		view, viewErr = g.SetView(Controllers.Help.Name, x0, y0, x0+width, y0+height)
+		if isNewView(viewErr) {
+			Controllers.Help.Setup(view, nil)
+			if _, err = g.SetCurrentView(Controllers.Help.Name); err != nil {
+				return err
+			}
+			Controllers.Status.Render()
+		}
+	}
+
+	// the layout runs after every event, this puts back the cursors moved by mouse events that no handler took care of
+	if g.Mouse {
+		syncCursors()
+	}
+
 	return nil
 }
 
@@ -274,25 +411,45 @@ func Render() {
 	}
 }
 
+// tr 返回界面文字在当前语言中的译文（见i18n.T）。gocui的每个字符只占一个单元格，而终端中的宽字符（例如中文）占两个单元格，
+// 因此在每个宽字符之后补一个占位字符（终端不会显示它），使字符数与显示宽度一致，按字符数计算的对齐也随之正确。
+func tr(message string, args ...interface{}) string {
+	return fitCells(i18n.T(message, args...))
+}
+
+// fitCells 在给定字符串的每个宽字符之后补一个占位字符。
+func fitCells(s string) string {
+	var result strings.Builder
+	for _, r := range s {
+		result.WriteRune(r)
+		// the same rule as termbox uses when drawing the cells
+		if runewidth.RuneWidth(r) == 2 && !runewidth.IsAmbiguousWidth(r) {
+			result.WriteRune(' ')
+		}
+	}
+	return result.String()
+}
+
 // renderStatusOption 将键帮助绑定格式化为标题对。
 func renderStatusOption(control, title string, selected bool) string {
 	if selected {
-		return Formatting.StatusSelected("▏") + Formatting.StatusControlSelected(control) + Formatting.StatusSelected(" "+title+" ")
+		return Formatting.StatusSelected(theme.Glyphs.Separator) + Formatting.StatusControlSelected(control) + Formatting.StatusSelected(" "+title+" ")
 	} else {
-		return Formatting.StatusNormal("▏") + Formatting.StatusControlNormal(control) + Formatting.StatusNormal(" "+title+" ")
+		return Formatting.StatusNormal(theme.Glyphs.Separator) + Formatting.StatusControlNormal(control) + Formatting.StatusNormal(" "+title+" ")
 	}
 }
 
 // Run is the UI entrypoint.
-func Run(analysis *image.AnalysisResult, cache filetree.TreeCache) {
-	Formatting.Selected = color.New(color.ReverseVideo, color.Bold).SprintFunc()
-	Formatting.Header = color.New(color.Bold).SprintFunc()
-	Formatting.StatusSelected = color.New(color.BgMagenta, color.FgWhite).SprintFunc()
-	Formatting.StatusNormal = color.New(color.ReverseVideo).SprintFunc()
-	Formatting.StatusControlSelected = color.New(color.BgMagenta, color.FgWhite, color.Bold).SprintFunc()
-	Formatting.StatusControlNormal = color.New(color.ReverseVideo, color.Bold).SprintFunc()
-	Formatting.CompareTop = color.New(color.BgMagenta).SprintFunc()
-	Formatting.CompareBottom = color.New(color.BgGreen).SprintFunc()
+func Run(analysis *image.AnalysisResult, cache *filetree.TreeCache, minSaving uint64) {
+	Formatting.Selected = theme.Current.SprintFunc(theme.Selected)
+	Formatting.Header = theme.Current.SprintFunc(theme.Header)
+	Formatting.StatusSelected = theme.Current.SprintFunc(theme.StatusSelected)
+	Formatting.StatusNormal = theme.Current.SprintFunc(theme.StatusNormal)
+	Formatting.StatusControlSelected = theme.Current.SprintFunc(theme.StatusControlSelected)
+	Formatting.StatusControlNormal = theme.Current.SprintFunc(theme.StatusControlNormal)
+	Formatting.StatusError = theme.Current.SprintFunc(theme.StatusError)
+	Formatting.CompareTop = theme.Current.SprintFunc(theme.CompareTop)
+	Formatting.CompareBottom = theme.Current.SprintFunc(theme.CompareBottom)
 
 	var err error
 	GlobalKeybindings.quit, err = keybinding.ParseAll(viper.GetString("keybinding.quit"))
@@ -307,6 +464,48 @@ func Run(analysis *image.AnalysisResult, cache filetree.TreeCache) {
 	if err != nil {
 		logrus.Error(err)
 	}
+	GlobalKeybindings.help, err = keybinding.ParseAll(viper.GetString("keybinding.help"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	for _, cursor := range []struct {
+		keys   *[]keybinding.Key
+		config string
+	}{
+		{&CursorKeybindings.up, "keybinding.cursor-up"},
+		{&CursorKeybindings.down, "keybinding.cursor-down"},
+		{&CursorKeybindings.left, "keybinding.cursor-left"},
+		{&CursorKeybindings.right, "keybinding.cursor-right"},
+		{&CursorKeybindings.top, "keybinding.goto-top"},
+		{&CursorKeybindings.bottom, "keybinding.goto-bottom"},
+	} {
+		*cursor.keys, err = keybinding.ParseAll(viper.GetString(cursor.config))
+		if err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	for _, resize := range []struct {
+		keys   *[]keybinding.Key
+		config string
+	}{
+		{&LayoutKeybindings.narrowLeft, "keybinding.narrow-left-column"},
+		{&LayoutKeybindings.widenLeft, "keybinding.widen-left-column"},
+		{&LayoutKeybindings.shrinkDetails, "keybinding.shrink-details-pane"},
+		{&LayoutKeybindings.growDetails, "keybinding.grow-details-pane"},
+		{&LayoutKeybindings.maximize, "keybinding.maximize-pane"},
+		{&LayoutKeybindings.reset, "keybinding.reset-layout"},
+	} {
+		*resize.keys, err = keybinding.ParseAll(viper.GetString(resize.config))
+		if err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	for _, conflict := range keybinding.Validate() {
+		logrus.Warn(conflict)
+	}
 
 	g, err := gocui.NewGui(gocui.OutputNormal)
 	if err != nil {
@@ -314,13 +513,20 @@ func Run(analysis *image.AnalysisResult, cache filetree.TreeCache) {
 	}
 	utils.SetUi(g)
 	defer g.Close()
+	dispatcher = keybinding.NewDispatcher(g, viper.GetDuration("keybinding.sequence-timeout"))
+	// a lone ESC is reported as KeyEsc (used to cancel the search bar) instead of waiting for an Alt combination
+	g.InputEsc = true
+	g.Mouse = viper.GetBool("mouse.enabled")
+	doubleClickInterval = viper.GetDuration("mouse.double-click-interval")
+
+	paneLayout = loadLayout()
 
 	Controllers.lookup = make(map[string]View)
 
-	Controllers.Layer = NewLayerController("side", g, analysis.Layers)
+	Controllers.Layer = NewLayerController("side", g, analysis.Layers, analysis)
 	Controllers.lookup[Controllers.Layer.Name] = Controllers.Layer
 
-	Controllers.Tree = NewFileTreeController("main", g, filetree.StackTreeRange(analysis.RefTrees, 0, 0), analysis.RefTrees, cache)
+	Controllers.Tree = NewFileTreeController("main", g, filetree.StackTreeRange(analysis.RefTrees, 0, 0), analysis.RefTrees, cache, analysis.LayerFiles)
 	Controllers.lookup[Controllers.Tree.Name] = Controllers.Tree
 
 	Controllers.Status = NewStatusController("status", g)
@@ -329,9 +535,23 @@ func Run(analysis *image.AnalysisResult, cache filetree.TreeCache) {
 	Controllers.Filter = NewFilterController("command", g)
 	Controllers.lookup[Controllers.Filter.Name] = Controllers.Filter
 
-	Controllers.Details = NewDetailsController("details", g, analysis.Efficiency, analysis.Inefficiencies)
+	Controllers.Search = NewSearchController("search", g)
+	Controllers.lookup[Controllers.Search.Name] = Controllers.Search
+
+	Controllers.Details = NewDetailsController("details", g, analysis.Efficiency, analysis.Inefficiencies, analysis.RefTrees)
+	// checking every file against the later layers takes a while with large images, the suggestions are shown when they are ready
+	Controllers.Details.advise(analysis, minSaving)
 	Controllers.lookup[Controllers.Details.Name] = Controllers.Details
 
+	Controllers.Config = NewConfigController("config", g, analysis.Config)
+	Controllers.lookup[Controllers.Config.Name] = Controllers.Config
+
+	Controllers.Viewer = NewViewerController("viewer", g, analysis.LayerFiles, analysis.RefTrees)
+	Controllers.lookup[Controllers.Viewer.Name] = Controllers.Viewer
+
+	Controllers.Help = NewHelpController("help", g)
+	Controllers.lookup[Controllers.Help.Name] = Controllers.Help
+
 	g.Cursor = false
 
 	g.SetManagerFunc(layout)
@@ -343,6 +563,11 @@ func Run(analysis *image.AnalysisResult, cache filetree.TreeCache) {
 	if err := keyBindings(g); err != nil {
 		logrus.Error(err)
 	}
+	if g.Mouse {
+		if err := mouseBindings(g); err != nil {
+			logrus.Error(err)
+		}
+	}
 
 	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
 		logrus.Error(err)
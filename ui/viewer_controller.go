@@ -0,0 +1,565 @@
+package ui
+
+import (
+	"LGM/filetree"
+	"LGM/image"
+	"LGM/keybinding"
+	"LGM/textdiff"
+	"LGM/theme"
+	"archive/tar"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/jroimartin/gocui"
+	"github.com/mattn/go-runewidth"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// viewerTabWidth 是查看器中制表符的宽度
+	viewerTabWidth = 8
+	// viewerScrollColumns 是查看器每次水平滚动的列数
+	viewerScrollColumns = 8
+	// viewerDiffContext 是差异中每处修改前后显示的未修改的行数
+	viewerDiffContext = 3
+	// binarySniffLength 是判断文件是否为二进制文件时检查NUL字符的长度（与git相同）
+	binarySniffLength = 8000
+)
+
+// ViewerController 包含用于查看文件内容的浮层（占满窗格区域）：选中的文件在当前比较的图层中的版本（文本或十六进制），
+// 或者与之前版本（比较的下层树中的版本）的统一格式差异。文件内容来自解析时保存的图层（见image.SpoolLayers）。
+type ViewerController struct {
+	Name    string
+	gui     *gocui.Gui
+	view    *gocui.View
+	visible bool
+
+	// previous 是打开查看器之前选中的窗格，关闭查看器时返回该窗格
+	previous string
+	files    image.LayerFiles
+	refTrees []*filetree.FileTree
+	maxSize  int64
+
+	path     string
+	current  fileVersion
+	base     fileVersion
+	showDiff bool
+	showHex  bool
+
+	title string
+	lines []string
+	// width 是最长的一行的宽度，用于限制水平滚动
+	width int
+
+	keybindingViewFile   []keybinding.Key
+	keybindingToggleDiff []keybinding.Key
+	keybindingToggleHex  []keybinding.Key
+	keybindingPageDown   []keybinding.Key
+	keybindingPageUp     []keybinding.Key
+}
+
+// fileVersion 是文件在某一层中的版本
+type fileVersion struct {
+	// layer 是版本所在的图层（RefTrees中的索引），文件不存在时为-1
+	layer int
+	info  filetree.FileInfo
+	data  []byte
+	// truncated 指示内容是否超过viewer.max-file-size而被截断
+	truncated bool
+	err       error
+}
+
+// NewViewerController 创建附加到全局[gocui]屏幕对象的新视图对象。
+func NewViewerController(name string, gui *gocui.Gui, files image.LayerFiles, refTrees []*filetree.FileTree) (controller *ViewerController) {
+	controller = new(ViewerController)
+
+	// populate main fields
+	controller.Name = name
+	controller.gui = gui
+	controller.files = files
+	controller.refTrees = refTrees
+
+	maxSize, err := humanize.ParseBytes(viper.GetString("viewer.max-file-size"))
+	if err != nil {
+		logrus.Errorf("invalid config value: 'viewer.max-file-size': %v", err)
+		maxSize = humanize.MByte
+	}
+	controller.maxSize = int64(maxSize)
+
+	controller.keybindingViewFile, err = keybinding.ParseAll(viper.GetString("keybinding.view-file"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingToggleDiff, err = keybinding.ParseAll(viper.GetString("keybinding.viewer-toggle-diff"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingToggleHex, err = keybinding.ParseAll(viper.GetString("keybinding.viewer-toggle-hex"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingPageUp, err = keybinding.ParseAll(viper.GetString("keybinding.page-up"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	controller.keybindingPageDown, err = keybinding.ParseAll(viper.GetString("keybinding.page-down"))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	return controller
+}
+
+// Setup 在全局[gocui]视图对象的上下文中初始化UI关注点。浮层在每次打开时重新创建。
+func (controller *ViewerController) Setup(v *gocui.View, header *gocui.View) error {
+
+	// set controller options
+	controller.view = v
+	controller.view.Editable = false
+	controller.view.Wrap = false
+	controller.view.Frame = true
+
+	controller.Update()
+	return controller.Render()
+}
+
+// keyBindings 注册查看器中的按键（浮层视图在每次打开时重新创建，而按键只需注册一次）。
+func (controller *ViewerController) keyBindings(g *gocui.Gui) error {
+	bindings := []struct {
+		keys    []keybinding.Key
+		handler func() error
+	}{
+		{CursorKeybindings.down, controller.CursorDown},
+		{CursorKeybindings.up, controller.CursorUp},
+		{CursorKeybindings.left, func() error { return controller.scrollColumns(-viewerScrollColumns) }},
+		{CursorKeybindings.right, func() error { return controller.scrollColumns(viewerScrollColumns) }},
+		{CursorKeybindings.top, func() error { return controller.scrollTo(0) }},
+		{CursorKeybindings.bottom, func() error { return controller.scrollTo(len(controller.lines)) }},
+		{controller.keybindingPageUp, func() error { return controller.scroll(-controller.pageHeight()) }},
+		{controller.keybindingPageDown, func() error { return controller.scroll(controller.pageHeight()) }},
+		{controller.keybindingToggleDiff, controller.toggleDiff},
+		{controller.keybindingToggleHex, controller.toggleHex},
+		{controller.keybindingViewFile, controller.close},
+	}
+	for _, binding := range bindings {
+		if err := dispatcher.Bind(controller.Name, binding.keys, binding.handler); err != nil {
+			return err
+		}
+	}
+	return g.SetKeybinding(controller.Name, gocui.KeyEsc, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return controller.close() })
+}
+
+// IsVisible 指示查看器当前是否显示
+func (controller *ViewerController) IsVisible() bool {
+	if controller == nil {
+		return false
+	}
+	return controller.visible
+}
+
+// open 打开给定路径的文件：读取其在当前比较的上层树中的版本和下层树中的版本（图层边界与setTreeByLayer的参数相同），浮层视图由下一次layout创建。
+func (controller *ViewerController) open(path string, bottomTreeStop, topTreeStart, topTreeStop int) error {
+	if current := controller.gui.CurrentView(); current != nil {
+		controller.previous = current.Name()
+	} else {
+		controller.previous = Controllers.Tree.Name
+	}
+
+	// the previous version is the one the file tree compares against, when nothing is compared it is the one below the layer
+	baseStop := bottomTreeStop
+	if bottomTreeStop >= topTreeStart {
+		baseStop = topTreeStop - 1
+	}
+	controller.path = path
+	controller.current = controller.readVersion(filetree.LayerOf(controller.refTrees, path, topTreeStop))
+	controller.base = controller.readVersion(filetree.LayerOf(controller.refTrees, path, baseStop))
+	controller.showDiff = false
+	controller.showHex = isBinary(controller.shown().data)
+	controller.visible = true
+	return nil
+}
+
+// shown 返回显示内容时使用的版本：当前版本，文件已被删除时为之前的版本。
+func (controller *ViewerController) shown() fileVersion {
+	if controller.current.layer < 0 {
+		return controller.base
+	}
+	return controller.current
+}
+
+// close 关闭查看器并返回之前选中的窗格。
+func (controller *ViewerController) close() error {
+	controller.visible = false
+	if err := controller.gui.DeleteView(controller.Name); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	controller.view = nil
+	if _, err := controller.gui.SetCurrentView(controller.previous); err != nil {
+		return err
+	}
+	Update()
+	Render()
+	return nil
+}
+
+// readVersion 读取给定图层中的文件版本（最多viewer.max-file-size字节），符号链接和其他特殊文件只记录其文件信息。
+func (controller *ViewerController) readVersion(layer int) fileVersion {
+	version := fileVersion{layer: layer}
+	if layer < 0 {
+		return version
+	}
+	if node, err := controller.refTrees[layer].GetNode(controller.path); err == nil {
+		version.info = node.Data.FileInfo
+	}
+	if version.info.TypeFlag != tar.TypeReg && version.info.TypeFlag != tar.TypeRegA && version.info.TypeFlag != tar.TypeLink {
+		return version
+	}
+	if controller.files == nil {
+		return version
+	}
+
+	// one byte more tells whether the file is longer than the limit
+	version.data, version.err = controller.files.ReadFile(layer, controller.path, controller.maxSize+1)
+	if int64(len(version.data)) > controller.maxSize {
+		version.data = version.data[:controller.maxSize]
+		version.truncated = true
+	}
+	return version
+}
+
+// pageHeight 返回查看器一页的行数。
+func (controller *ViewerController) pageHeight() int {
+	if controller.view == nil {
+		return 0
+	}
+	_, height := controller.view.Size()
+	return height
+}
+
+// CursorDown 向下滚动查看器。
+func (controller *ViewerController) CursorDown() error {
+	return controller.scroll(1)
+}
+
+// CursorUp 向上滚动查看器。
+func (controller *ViewerController) CursorUp() error {
+	return controller.scroll(-1)
+}
+
+// scroll 将查看器滚动给定的行数（为负时向上）。
+func (controller *ViewerController) scroll(step int) error {
+	if controller.view == nil {
+		return nil
+	}
+	_, oy := controller.view.Origin()
+	return controller.scrollTo(oy + step)
+}
+
+// scrollTo 将给定的行滚动到查看器顶部（最后一页的内容占满查看器）。
+func (controller *ViewerController) scrollTo(row int) error {
+	if controller.view == nil {
+		return nil
+	}
+	ox, _ := controller.view.Origin()
+	if last := len(controller.lines) - controller.pageHeight(); row > last {
+		row = last
+	}
+	if row < 0 {
+		row = 0
+	}
+	return controller.view.SetOrigin(ox, row)
+}
+
+// scrollColumns 将查看器水平滚动给定的列数（为负时向左）。
+func (controller *ViewerController) scrollColumns(step int) error {
+	if controller.view == nil {
+		return nil
+	}
+	ox, oy := controller.view.Origin()
+	width, _ := controller.view.Size()
+	ox += step
+	if last := controller.width - width; ox > last {
+		ox = last
+	}
+	if ox < 0 {
+		ox = 0
+	}
+	return controller.view.SetOrigin(ox, oy)
+}
+
+// toggleDiff 在文件内容和与之前版本的差异之间切换。
+func (controller *ViewerController) toggleDiff() error {
+	controller.showDiff = !controller.showDiff
+	return controller.changed()
+}
+
+// toggleHex 在文本和十六进制显示之间切换。
+func (controller *ViewerController) toggleHex() error {
+	controller.showHex = !controller.showHex
+	return controller.changed()
+}
+
+// changed 重新生成内容并回到开头。
+func (controller *ViewerController) changed() error {
+	if controller.view != nil {
+		if err := controller.view.SetOrigin(0, 0); err != nil {
+			return err
+		}
+	}
+	controller.Update()
+	controller.Render()
+	return Controllers.Status.Render()
+}
+
+// Update 根据显示模式生成查看器的内容。
+func (controller *ViewerController) Update() error {
+	if !controller.IsVisible() {
+		return nil
+	}
+	controller.lines = controller.lines[:0]
+	controller.width = 0
+
+	mode := tr("Contents")
+	switch shown := controller.shown(); {
+	case controller.showDiff:
+		mode = tr("Diff")
+		controller.updateDiff()
+	case shown.layer < 0:
+		controller.note(tr("The file does not exist in the compared layers"))
+	case shown.layer != controller.current.layer:
+		controller.note(tr("Removed in the selected layers, showing the version from layer %d", shown.layer))
+		controller.updateContents(shown)
+	default:
+		controller.note(tr("Version from layer %d", shown.layer))
+		controller.updateContents(shown)
+	}
+	if controller.showHex {
+		mode += ", " + tr("Hex")
+	}
+	controller.title = fmt.Sprintf(" %s (%s) ", controller.path, mode)
+	return nil
+}
+
+// updateContents 显示给定版本的内容：文本带有行号，二进制内容以十六进制显示。
+func (controller *ViewerController) updateContents(version fileVersion) {
+	if !controller.available(version) {
+		return
+	}
+	if controller.showHex {
+		controller.plain(hexLines(version.data))
+		return
+	}
+
+	lines := textLines(version.data)
+	width := len(fmt.Sprint(len(lines)))
+	for idx, line := range lines {
+		controller.add(fmt.Sprintf("%*d  %s", width, idx+1, displayLine(line)), nil)
+	}
+}
+
+// updateDiff 显示当前版本与之前版本的统一格式差异（十六进制显示时比较两个版本的十六进制转储）。
+func (controller *ViewerController) updateDiff() {
+	base, current := controller.base, controller.current
+	if base.layer < 0 && current.layer < 0 {
+		controller.note(tr("The file does not exist in the compared layers"))
+		return
+	}
+	if base.layer == current.layer {
+		controller.note(tr("The file is unchanged since layer %d", current.layer))
+		return
+	}
+	for _, version := range []fileVersion{base, current} {
+		if version.layer >= 0 && !controller.available(version) {
+			return
+		}
+	}
+	if base.truncated || current.truncated {
+		controller.note(tr("Only the first %s of the file is compared", humanize.Bytes(uint64(controller.maxSize))))
+	}
+
+	var from, to []string
+	if controller.showHex {
+		from, to = hexLines(base.data), hexLines(current.data)
+	} else {
+		if isBinary(base.data) || isBinary(current.data) {
+			if bytes.Equal(base.data, current.data) {
+				controller.note(tr("The contents of the binary files are the same"))
+			} else {
+				controller.note(tr("The binary files differ"))
+			}
+			return
+		}
+		from, to = textLines(base.data), textLines(current.data)
+	}
+
+	lines := textdiff.Unified(from, to, versionName(controller.path, base.layer), versionName(controller.path, current.layer), viewerDiffContext)
+	if len(lines) == 0 && (base.truncated || current.truncated) {
+		controller.note(tr("The compared part of the contents is the same"))
+		return
+	}
+	if len(lines) == 0 {
+		controller.note(tr("The contents are the same, only the file attributes changed"))
+		return
+	}
+	added := theme.Current.Color(theme.Added).SprintFunc()
+	removed := theme.Current.Color(theme.Removed).SprintFunc()
+	for idx, line := range lines {
+		switch {
+		// the file names and the hunk headers
+		case idx < 2 || strings.HasPrefix(line, "@@"):
+			controller.note(fitCells(line))
+		case strings.HasPrefix(line, "+"):
+			controller.add("+"+displayLine(line[1:]), added)
+		case strings.HasPrefix(line, "-"):
+			controller.add("-"+displayLine(line[1:]), removed)
+		default:
+			controller.add(line[:1]+displayLine(line[1:]), nil)
+		}
+	}
+}
+
+// available 检查给定版本的内容是否可以显示，不能显示时添加说明原因的行。
+func (controller *ViewerController) available(version fileVersion) bool {
+	info := version.info
+	switch {
+	case info.TypeFlag == tar.TypeSymlink:
+		controller.note(tr("Symbolic link to %s", info.LinkName))
+		return false
+	case info.TypeFlag != tar.TypeReg && info.TypeFlag != tar.TypeRegA && info.TypeFlag != tar.TypeLink:
+		controller.note(tr("Not a regular file"))
+		return false
+	case controller.files == nil:
+		controller.note(tr("File contents are not available (enable viewer.spool-layers or raise viewer.spool-limit to keep the image layers)"))
+		return false
+	case version.err != nil:
+		controller.note(tr("cannot read the file: %v", version.err))
+		return false
+	}
+	if version.truncated && !controller.showDiff {
+		controller.note(tr("Only the first %s of the file is shown", humanize.Bytes(uint64(controller.maxSize))))
+	}
+	return true
+}
+
+// note 以标题样式添加一行说明。
+func (controller *ViewerController) note(text string) {
+	controller.add(text, Formatting.Header)
+}
+
+// plain 添加不需要转换的行。
+func (controller *ViewerController) plain(lines []string) {
+	for _, line := range lines {
+		controller.add(line, nil)
+	}
+}
+
+// add 以给定的样式（为nil时不设置样式）添加一行，并记录最长的一行的宽度。
+func (controller *ViewerController) add(line string, style func(...interface{}) string) {
+	if width := utf8.RuneCountInString(line); width > controller.width {
+		controller.width = width
+	}
+	if style != nil {
+		line = style(line)
+	}
+	controller.lines = append(controller.lines, line)
+}
+
+// Render 将状态对象刷新到屏幕。
+func (controller *ViewerController) Render() error {
+	if !controller.IsVisible() {
+		return nil
+	}
+	controller.gui.Update(func(g *gocui.Gui) error {
+		// the view is created by the next layout after the viewer is opened
+		if controller.view == nil {
+			return nil
+		}
+		controller.view.Title = controller.title
+		controller.view.Clear()
+		fmt.Fprint(controller.view, strings.Join(controller.lines, "\n"))
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp 指示选择当前窗格时用户可以采取的所有可能操作。
+func (controller *ViewerController) KeyHelp() string {
+	return renderStatusOption(theme.Glyphs.ArrowUp+"/"+theme.Glyphs.ArrowDown, tr("Scroll"), false) +
+		renderStatusOption(controller.keybindingToggleDiff[0].String(), tr("Diff"), controller.showDiff) +
+		renderStatusOption(controller.keybindingToggleHex[0].String(), tr("Hex"), controller.showHex) +
+		renderStatusOption("esc", tr("Close"), false)
+}
+
+// versionName 返回差异标题中的版本名称：路径及其所在的图层，不存在的版本为 /dev/null（与diff相同）。
+func versionName(path string, layer int) string {
+	if layer < 0 {
+		return "/dev/null"
+	}
+	return path + "  " + tr("(layer %d)", layer)
+}
+
+// isBinary 指示内容是否为二进制数据：开头部分包含NUL字符，或者不是有效的UTF-8（截断在末尾的多字节字符除外）。
+func isBinary(data []byte) bool {
+	head := data
+	if len(head) > binarySniffLength {
+		head = head[:binarySniffLength]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			// an incomplete character is only allowed where the contents were cut off
+			return utf8.FullRune(data)
+		}
+		data = data[size:]
+	}
+	return false
+}
+
+// textLines 将文本分为行（最后一行之后的换行不产生空行）。
+func textLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// hexLines 返回内容的十六进制转储（与 "hexdump -C" 相同的格式）。
+func hexLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(hex.Dump(data), "\n"), "\n")
+}
+
+// displayLine 返回一行文本在查看器中的显示形式：展开制表符，控制字符以 ^X 的形式显示，宽字符按tr的方式补齐。
+func displayLine(line string) string {
+	var result strings.Builder
+	column := 0
+	for _, r := range line {
+		switch {
+		case r == '\t':
+			spaces := viewerTabWidth - column%viewerTabWidth
+			result.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+		case r < 0x20 || r == 0x7f:
+			result.WriteRune('^')
+			result.WriteRune(r ^ 0x40)
+			column += 2
+		default:
+			result.WriteRune(r)
+			column += runewidth.RuneWidth(r)
+		}
+	}
+	return fitCells(result.String())
+}
@@ -9,6 +9,9 @@ import (
 
 var ui *gocui.Gui
 
+// cleanUps 是退出时执行的清理函数（见OnCleanUp）
+var cleanUps []func()
+
 func SetUi(g *gocui.Gui) {
 	ui = g
 }
@@ -29,4 +32,13 @@ func CleanUp() {
 	if ui != nil {
 		ui.Close()
 	}
+	for _, cleanUp := range cleanUps {
+		cleanUp()
+	}
+	cleanUps = nil
+}
+
+// OnCleanUp 注册一个在CleanUp（包括Exit和PrintAndExit）时执行的函数，例如删除临时文件。
+func OnCleanUp(cleanUp func()) {
+	cleanUps = append(cleanUps, cleanUp)
 }
\ No newline at end of file